@@ -5,13 +5,23 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"version-backend/internal/agent"
 	"version-backend/internal/api"
 	"version-backend/internal/config"
+	"version-backend/internal/cpe"
 	"version-backend/internal/db"
+	"version-backend/internal/db/models"
+	"version-backend/internal/ingest"
+	"version-backend/internal/live"
 	"version-backend/internal/osquery"
+	"version-backend/internal/packs"
+	"version-backend/internal/retention"
+	"version-backend/internal/vuln"
 	"version-backend/pkg/logger"
 )
 
@@ -25,6 +35,29 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// The first argument selects the mode, defaulting to the server
+	command := "server"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
+	switch command {
+	case "server":
+		runServer(cfg)
+	case "agent":
+		runAgent(cfg)
+	case "migrate":
+		runMigrate(cfg, os.Args[2:])
+	default:
+		log.Fatalf("Unknown command %q, expected server, agent or migrate", command)
+	}
+}
+
+// runServer stores snapshots in the database and serves the REST API. It
+// collects from the local osquery socket unless COLLECT_LOCAL is disabled.
+func runServer(cfg *config.Config) {
+	log := logger.GetLogger()
+
 	// Initialize database connection
 	database, err := db.New(&cfg.Database)
 	if err != nil {
@@ -32,42 +65,90 @@ func main() {
 	}
 	defer database.Close()
 
-	// Initialize osquery client
-	osqueryClient, err := osquery.NewClient(cfg.Osquery.SocketPath)
+	// Bring the schema up to date before anything reads or writes it
+	if err := migrateUp(database); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	// Seed the configured enroll secret so nodes can enroll without an admin
+	if cfg.Server.EnrollSecret != "" {
+		if err := database.EnsureEnrollSecret("default", cfg.Server.EnrollSecret); err != nil {
+			log.Fatalf("Failed to store enroll secret: %v", err)
+		}
+	}
+
+	// Load the mappings the CPE names of apps are inferred with
+	cpes, err := cpe.Load(cfg.Vuln.CPEMappingFile)
 	if err != nil {
-		log.Fatalf("Failed to create osquery client: %v", err)
+		log.Fatalf("Failed to load CPE mappings: %v", err)
 	}
-	defer osqueryClient.Close()
+
+	// Register the data collectors
+	registry := newRegistry()
+	ingestService := ingest.NewService(database, registry, cpes)
+
+	// Load the scheduled query packs
+	queryPacks, err := packs.Load(cfg.Osquery.PacksDir)
+	if err != nil {
+		log.Fatalf("Failed to load query packs: %v", err)
+	}
+	log.Infof("Loaded %d query packs from %s", len(queryPacks), cfg.Osquery.PacksDir)
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Collect initial data at startup
-	log.Info("Collecting initial system information...")
-	if err := collectAndSaveData(ctx, osqueryClient, database); err != nil {
-		log.Errorf("Failed to collect initial data: %v", err)
-	}
+	var osqueryClient *osquery.Client
+	if cfg.Server.CollectLocal {
+		// Initialize osquery client
+		osqueryClient, err = osquery.NewClient(cfg.Osquery.SocketPath)
+		if err != nil {
+			log.Fatalf("Failed to create osquery client: %v", err)
+		}
+		defer osqueryClient.Close()
 
-	// Start data collection in background
-	go func() {
-		ticker := time.NewTicker(time.Duration(cfg.Osquery.QueryInterval) * time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				if err := collectAndSaveData(ctx, osqueryClient, database); err != nil {
-					log.Errorf("Failed to collect and save data: %v", err)
+		// Collect initial data at startup and then in background
+		log.Info("Collecting initial system information...")
+		go runCollectionLoop(ctx, cfg.Osquery.QueryInterval, func() error {
+			return collectAndSaveData(ctx, osqueryClient, registry, ingestService)
+		})
+
+		// Run the pack queries, each on its own interval
+		if len(queryPacks) > 0 {
+			scheduler := packs.NewScheduler(osqueryClient, database, queryPacks)
+			go func() {
+				if err := scheduler.Run(ctx); err != nil {
+					log.Errorf("Failed to schedule pack queries: %v", err)
 				}
-			}
+			}()
 		}
-	}()
+	} else {
+		log.Info("Local collection disabled, waiting for agent snapshots")
+	}
+
+	// Live queries run on the local osquery socket and on enrolled nodes
+	liveService := live.NewService(database, osqueryClient)
+
+	// Prune archived software rows in the background
+	retentionJob := retention.NewJob(database, cfg.Retention)
+	if retentionJob.Enabled() {
+		go retentionJob.Run(ctx)
+	} else {
+		log.Info("Retention disabled, keeping every app version")
+	}
+
+	// Load the local vulnerability feeds
+	var vulnDB *vuln.Database
+	if cfg.Vuln.OSVDir != "" || cfg.Vuln.NVDFeed != "" {
+		vulnDB, err = vuln.Load(cfg.Vuln.OSVDir, cfg.Vuln.NVDFeed)
+		if err != nil {
+			log.Fatalf("Failed to load vulnerability feeds: %v", err)
+		}
+		log.Infof("Loaded %d advisories from %s", vulnDB.Advisories(), strings.Join(vulnDB.Sources(), ", "))
+	}
 
 	// Initialize and start HTTP server
-	router := api.NewRouter(database)
+	router := api.NewRouter(cfg, database, ingestService, liveService, registry, queryPacks, retentionJob, vulnDB)
 	serverAddr := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
 
 	// Handle graceful shutdown
@@ -80,23 +161,199 @@ func main() {
 		cancel()
 	}()
 
-	log.Infof("Server starting on %s", serverAddr)
-	if err := router.Run(serverAddr); err != nil {
+	if cfg.Server.TLSCertFile != "" && cfg.Server.TLSKeyFile != "" {
+		log.Infof("Server starting with TLS on %s", serverAddr)
+		err = router.RunTLS(serverAddr, cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+	} else {
+		log.Infof("Server starting on %s", serverAddr)
+		err = router.Run(serverAddr)
+	}
+	if err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
 }
 
+// runAgent only runs the collection loop, posting every snapshot to the
+// central server configured by AGENT_SERVER_URL. The agent enrolls with
+// AGENT_ENROLL_SECRET.
+func runAgent(cfg *config.Config) {
+	log := logger.GetLogger()
+
+	// Initialize osquery client
+	osqueryClient, err := osquery.NewClient(cfg.Osquery.SocketPath)
+	if err != nil {
+		log.Fatalf("Failed to create osquery client: %v", err)
+	}
+	defer osqueryClient.Close()
+
+	registry := newRegistry()
+	client, err := agent.NewClient(cfg.Agent.ServerURL, cfg.Agent.EnrollSecret, cfg.Agent.NodeKeyFile)
+	if err != nil {
+		log.Fatalf("Failed to create agent client: %v", err)
+	}
+
+	// Create context for graceful shutdown
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	log.Infof("Agent reporting to %s", cfg.Agent.ServerURL)
+
+	// Answer live queries between snapshots
+	go runCollectionLoop(ctx, cfg.Agent.PollInterval, func() error {
+		return client.Poll(ctx, osqueryClient.Query)
+	})
+
+	runCollectionLoop(ctx, cfg.Osquery.QueryInterval, func() error {
+		return collectAndSendData(ctx, osqueryClient, registry, client)
+	})
+	log.Info("Shutting down agent...")
+}
+
+// runMigrate manages the database schema: "up" applies pending migrations,
+// "down [n]" reverts the last n migrations, one by default, and "status"
+// lists every migration
+func runMigrate(cfg *config.Config, args []string) {
+	log := logger.GetLogger()
+
+	database, err := db.New(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "up":
+		err = migrateUp(database)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				log.Fatalf("Invalid number of migrations to revert: %s", args[1])
+			}
+		}
+		err = migrateDown(database, steps)
+	case "status":
+		err = printMigrationStatus(database)
+	default:
+		log.Fatalf("Unknown migrate action %q, expected up, down or status", action)
+	}
+	if err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+}
+
+// migrateUp applies the pending migrations and logs each of them
+func migrateUp(database *db.DB) error {
+	applied, err := database.Migrate(context.Background())
+	for _, m := range applied {
+		logger.GetLogger().Infof("Applied migration %d_%s", m.Version, m.Name)
+	}
+	return err
+}
+
+// migrateDown reverts the last steps migrations and logs each of them
+func migrateDown(database *db.DB, steps int) error {
+	reverted, err := database.MigrateDown(context.Background(), steps)
+	for _, m := range reverted {
+		logger.GetLogger().Infof("Reverted migration %d_%s", m.Version, m.Name)
+	}
+	return err
+}
+
+// printMigrationStatus prints every migration and whether it was applied
+func printMigrationStatus(database *db.DB) error {
+	statuses, err := database.GetMigrationStatus(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		applied := "pending"
+		if status.AppliedAt != nil {
+			applied = "applied " + status.AppliedAt.Format("2006-01-02T15:04:05Z07:00")
+		}
+		fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, applied)
+	}
+	return nil
+}
+
+// newRegistry creates the collector registry and logs its collectors
+func newRegistry() *osquery.Registry {
+	registry := osquery.DefaultRegistry()
+	for _, collector := range registry.Collectors() {
+		logger.GetLogger().Infof("Registered collector %s (table: %s, endpoint: %s)",
+			collector.Name(), collector.Table(), collector.Endpoint())
+	}
+	return registry
+}
+
+// runCollectionLoop runs collect immediately and then every interval seconds
+// until the context is cancelled
+func runCollectionLoop(ctx context.Context, interval int, collect func() error) {
+	log := logger.GetLogger()
+
+	if err := collect(); err != nil {
+		log.Errorf("Failed to collect initial data: %v", err)
+	}
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := collect(); err != nil {
+				log.Errorf("Failed to collect data: %v", err)
+			}
+		}
+	}
+}
+
+// collectData runs every registered collector that applies to the host. It
+// returns both the mapped snapshot and the raw rows of each collector.
+func collectData(ctx context.Context, client *osquery.Client, registry *osquery.Registry) (*models.SystemInfo, osquery.Results, error) {
+	sysInfo := &models.SystemInfo{}
+	results := make(osquery.Results)
+	for _, collector := range registry.Collectors() {
+		if !osquery.Supports(collector, sysInfo) {
+			continue
+		}
+		rows, err := client.Collect(ctx, collector, sysInfo)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to run %s collector: %w", collector.Name(), err)
+		}
+		results[collector.Name()] = rows
+	}
+	return sysInfo, results, nil
+}
+
 // collectAndSaveData collects system information and saves it to the database
-func collectAndSaveData(ctx context.Context, client *osquery.Client, db *db.DB) error {
-	// Collect system information
-	sysInfo, err := client.GetSystemInfo(ctx)
+func collectAndSaveData(ctx context.Context, client *osquery.Client, registry *osquery.Registry, service *ingest.Service) error {
+	sysInfo, results, err := collectData(ctx, client, registry)
+	if err != nil {
+		return err
+	}
+
+	_, err = service.Save(sysInfo, results)
+	return err
+}
+
+// collectAndSendData collects system information and sends it to the server
+func collectAndSendData(ctx context.Context, client *osquery.Client, registry *osquery.Registry, server *agent.Client) error {
+	_, results, err := collectData(ctx, client, registry)
 	if err != nil {
-		return fmt.Errorf("failed to get system info: %w", err)
+		return err
 	}
 
-	// Save to database
-	if err := db.SaveSystemInfo(sysInfo); err != nil {
-		return fmt.Errorf("failed to save system info: %w", err)
+	if err := server.Send(ctx, results); err != nil {
+		return fmt.Errorf("failed to send system info: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,271 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"version-backend/internal/ingest"
+	"version-backend/internal/live"
+	"version-backend/internal/osquery"
+)
+
+// errUnauthorized is returned when the server rejects the node key
+var errUnauthorized = errors.New("node key rejected by server")
+
+// Client sends collected results to a central server
+type Client struct {
+	serverURL    string
+	enrollSecret string
+	nodeKeyFile  string
+	httpClient   *http.Client
+
+	mu      sync.Mutex
+	nodeKey string
+}
+
+// NewClient creates a new agent client for the given server. The client
+// enrolls with the enroll secret before sending its first snapshot. The
+// node key is kept in nodeKeyFile when set, since the server refuses to
+// enroll a host again while its node is active, and reused on restart.
+func NewClient(serverURL, enrollSecret, nodeKeyFile string) (*Client, error) {
+	c := &Client{
+		serverURL:    strings.TrimRight(serverURL, "/"),
+		enrollSecret: enrollSecret,
+		nodeKeyFile:  nodeKeyFile,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+
+	if nodeKeyFile != "" {
+		data, err := os.ReadFile(nodeKeyFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("error reading node key: %w", err)
+		}
+		c.nodeKey = strings.TrimSpace(string(data))
+	}
+	return c, nil
+}
+
+// enrollRequest is the body sent to the server's enroll endpoint
+type enrollRequest struct {
+	EnrollSecret   string `json:"enroll_secret"`
+	HostIdentifier string `json:"host_identifier"`
+	HostDetails    struct {
+		OSVersion  map[string]string `json:"os_version"`
+		SystemInfo map[string]string `json:"system_info"`
+	} `json:"host_details"`
+}
+
+// enrollResponse is the server's answer to an enrollment
+type enrollResponse struct {
+	NodeKey     string `json:"node_key"`
+	NodeInvalid bool   `json:"node_invalid"`
+}
+
+// Send posts the collector results to the server's /api/ingest endpoint. The
+// agent enrolls first when it has no node key, and enrolls again once if the
+// server rejects its node key.
+func (c *Client) Send(ctx context.Context, results osquery.Results) error {
+	if c.getNodeKey() == "" {
+		if err := c.enroll(ctx, results); err != nil {
+			return err
+		}
+	}
+
+	err := c.send(ctx, results)
+	if errors.Is(err, errUnauthorized) {
+		c.setNodeKey("")
+		if err := c.enroll(ctx, results); err != nil {
+			return err
+		}
+		err = c.send(ctx, results)
+	}
+	return err
+}
+
+// enroll obtains a node key from the server's /api/osquery/enroll endpoint,
+// describing the host with the os_version and system_info results
+func (c *Client) enroll(ctx context.Context, results osquery.Results) error {
+	request := enrollRequest{EnrollSecret: c.enrollSecret}
+	if rows := results["os_version"]; len(rows) > 0 {
+		request.HostDetails.OSVersion = rows[0]
+	}
+	if rows := results["system_info"]; len(rows) > 0 {
+		request.HostDetails.SystemInfo = rows[0]
+		request.HostIdentifier = rows[0]["hostname"]
+	}
+
+	resp, err := c.post(ctx, "/api/osquery/enroll", request, "")
+	if err != nil {
+		return fmt.Errorf("error enrolling: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server rejected enrollment: %s", readError(resp))
+	}
+
+	var enrolled enrollResponse
+	if err := json.NewDecoder(resp.Body).Decode(&enrolled); err != nil {
+		return fmt.Errorf("error decoding enroll response: %w", err)
+	}
+	if enrolled.NodeInvalid || enrolled.NodeKey == "" {
+		return errors.New("server rejected enrollment: invalid enroll secret or host already enrolled")
+	}
+
+	c.setNodeKey(enrolled.NodeKey)
+	if c.nodeKeyFile != "" {
+		if err := os.WriteFile(c.nodeKeyFile, []byte(enrolled.NodeKey+"\n"), 0600); err != nil {
+			return fmt.Errorf("error saving node key: %w", err)
+		}
+	}
+	return nil
+}
+
+// distributedReadResponse is the server's answer to a distributed read
+type distributedReadResponse struct {
+	Queries     map[string]string `json:"queries"`
+	NodeInvalid bool              `json:"node_invalid"`
+}
+
+// distributedWriteRequest is the body posted with live query results
+type distributedWriteRequest struct {
+	NodeKey  string                         `json:"node_key"`
+	Queries  map[string][]map[string]string `json:"queries"`
+	Statuses map[string]int                 `json:"statuses"`
+	Messages map[string]string              `json:"messages"`
+}
+
+// Poll fetches the live queries targeting this host through the server's
+// distributed read endpoint, runs them with run and posts the results back.
+// Collection queries are ignored since the agent sends snapshots itself.
+// Polling waits until the agent has enrolled with its first snapshot.
+func (c *Client) Poll(ctx context.Context, run func(ctx context.Context, sql string) ([]map[string]string, error)) error {
+	nodeKey := c.getNodeKey()
+	if nodeKey == "" {
+		return nil
+	}
+
+	resp, err := c.post(ctx, "/api/osquery/distributed/read", map[string]string{"node_key": nodeKey}, "")
+	if err != nil {
+		return fmt.Errorf("error polling live queries: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		// Enroll again with the next snapshot
+		c.setNodeKey("")
+		return errUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server rejected live query poll: %s", readError(resp))
+	}
+
+	var read distributedReadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&read); err != nil {
+		return fmt.Errorf("error decoding live queries: %w", err)
+	}
+
+	write := distributedWriteRequest{
+		NodeKey:  nodeKey,
+		Queries:  make(map[string][]map[string]string),
+		Statuses: make(map[string]int),
+		Messages: make(map[string]string),
+	}
+	for name, sql := range read.Queries {
+		if _, ok := live.ParseQueryName(name); !ok {
+			continue
+		}
+		rows, err := run(ctx, sql)
+		if err != nil {
+			write.Statuses[name] = 1
+			write.Messages[name] = err.Error()
+			continue
+		}
+		write.Queries[name] = rows
+		write.Statuses[name] = 0
+	}
+	if len(write.Statuses) == 0 {
+		return nil
+	}
+
+	resp, err = c.post(ctx, "/api/osquery/distributed/write", write, "")
+	if err != nil {
+		return fmt.Errorf("error sending live query results: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server rejected live query results: %s", readError(resp))
+	}
+
+	return nil
+}
+
+// send posts the results with the current node key
+func (c *Client) send(ctx context.Context, results osquery.Results) error {
+	resp, err := c.post(ctx, "/api/ingest", ingest.Payload{Results: results}, c.getNodeKey())
+	if err != nil {
+		return fmt.Errorf("error sending snapshot: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("server rejected snapshot: %s", readError(resp))
+	}
+
+	return nil
+}
+
+// post sends the body as JSON to the server path, authenticated with the
+// node key when one is given
+func (c *Client) post(ctx context.Context, path string, body interface{}, nodeKey string) (*http.Response, error) {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.serverURL+path, bytes.NewReader(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if nodeKey != "" {
+		req.Header.Set("Authorization", "Bearer "+nodeKey)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// getNodeKey returns the current node key, empty when not enrolled
+func (c *Client) getNodeKey() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nodeKey
+}
+
+// setNodeKey replaces the current node key
+func (c *Client) setNodeKey(nodeKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nodeKey = nodeKey
+}
+
+// readError formats the status and start of the body of a failed response
+func readError(resp *http.Response) string {
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Sprintf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+}
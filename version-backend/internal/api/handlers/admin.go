@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"version-backend/internal/db"
+	"version-backend/internal/db/models"
+	"version-backend/pkg/logger"
+)
+
+// EnrollSecretsResponse represents the structure of the response from the /admin/enroll-secrets endpoint
+type EnrollSecretsResponse struct {
+	EnrollSecrets []EnrollSecretInfo `json:"enroll_secrets"`
+}
+
+// EnrollSecretInfo represents an enroll secret in the API response
+type EnrollSecretInfo struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Secret    string `json:"secret"`
+	CreatedAt string `json:"created_at"`
+	RotatedAt string `json:"rotated_at,omitempty"`
+	RevokedAt string `json:"revoked_at,omitempty"`
+}
+
+// CreateEnrollSecretRequest is the body of POST /admin/enroll-secrets
+type CreateEnrollSecretRequest struct {
+	Name string `json:"name"`
+}
+
+// NodesResponse represents the structure of the response from the /admin/nodes endpoint
+type NodesResponse struct {
+	Nodes []NodeInfo `json:"nodes"`
+}
+
+// NodeInfo represents an enrolled node in the API response. The node key
+// itself is never returned.
+type NodeInfo struct {
+	ID              int64  `json:"id"`
+	EnrollSecretID  *int64 `json:"enroll_secret_id"`
+	HostIdentifier  string `json:"host_identifier"`
+	HostUUID        string `json:"host_uuid"`
+	Platform        string `json:"platform"`
+	LastCollectedAt string `json:"last_collected_at,omitempty"`
+	CreatedAt       string `json:"created_at"`
+	LastSeen        string `json:"last_seen"`
+	RevokedAt       string `json:"revoked_at,omitempty"`
+}
+
+// ListEnrollSecrets handles the GET /admin/enroll-secrets endpoint
+func ListEnrollSecrets(w http.ResponseWriter, r *http.Request) {
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	secrets, err := dbInstance.ListEnrollSecrets()
+	if err != nil {
+		http.Error(w, "Error retrieving enroll secrets: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := EnrollSecretsResponse{
+		EnrollSecrets: make([]EnrollSecretInfo, len(secrets)),
+	}
+	for i := range secrets {
+		response.EnrollSecrets[i] = newEnrollSecretInfo(&secrets[i])
+	}
+
+	writeJSON(w, response)
+}
+
+// CreateEnrollSecret handles the POST /admin/enroll-secrets endpoint
+// It generates a new random enroll secret
+func CreateEnrollSecret(w http.ResponseWriter, r *http.Request) {
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	var request CreateEnrollSecretRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Name == "" {
+		http.Error(w, "Invalid request: a name is required", http.StatusBadRequest)
+		return
+	}
+
+	secret, err := dbInstance.CreateEnrollSecret(request.Name)
+	if err != nil {
+		http.Error(w, "Error creating enroll secret: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Created enroll secret", map[string]interface{}{
+		"enroll_secret_id": secret.ID,
+		"name":             secret.Name,
+	})
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, newEnrollSecretInfo(secret))
+}
+
+// RotateEnrollSecret handles the POST /admin/enroll-secrets/{id}/rotate endpoint
+// It replaces the secret value; nodes already enrolled keep their node keys
+func RotateEnrollSecret(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r, "enroll secret")
+	if !ok {
+		return
+	}
+
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	secret, err := dbInstance.RotateEnrollSecret(id)
+	if err != nil {
+		if errors.Is(err, db.ErrEnrollSecretNotFound) {
+			http.Error(w, "Enroll secret not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error rotating enroll secret: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Rotated enroll secret", map[string]interface{}{
+		"enroll_secret_id": secret.ID,
+		"name":             secret.Name,
+	})
+
+	writeJSON(w, newEnrollSecretInfo(secret))
+}
+
+// RevokeEnrollSecret handles the DELETE /admin/enroll-secrets/{id} endpoint
+// With ?revoke_nodes=true the node keys issued with the secret are revoked too
+func RevokeEnrollSecret(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r, "enroll secret")
+	if !ok {
+		return
+	}
+
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	revokeNodes, _ := strconv.ParseBool(r.URL.Query().Get("revoke_nodes"))
+	if err := dbInstance.RevokeEnrollSecret(id, revokeNodes); err != nil {
+		if errors.Is(err, db.ErrEnrollSecretNotFound) {
+			http.Error(w, "Enroll secret not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error revoking enroll secret: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Revoked enroll secret", map[string]interface{}{
+		"enroll_secret_id": id,
+		"revoke_nodes":     revokeNodes,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListNodes handles the GET /admin/nodes endpoint
+func ListNodes(w http.ResponseWriter, r *http.Request) {
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	nodes, err := dbInstance.ListNodes()
+	if err != nil {
+		http.Error(w, "Error retrieving nodes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := NodesResponse{
+		Nodes: make([]NodeInfo, len(nodes)),
+	}
+	for i, node := range nodes {
+		response.Nodes[i] = NodeInfo{
+			ID:              node.ID,
+			EnrollSecretID:  node.EnrollSecretID,
+			HostIdentifier:  node.HostIdentifier,
+			HostUUID:        node.HostUUID,
+			Platform:        node.Platform,
+			LastCollectedAt: formatOptionalTime(node.LastCollectedAt),
+			CreatedAt:       node.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			LastSeen:        node.LastSeenAt.Format("2006-01-02T15:04:05Z07:00"),
+			RevokedAt:       formatOptionalTime(node.RevokedAt),
+		}
+	}
+
+	writeJSON(w, response)
+}
+
+// RevokeNode handles the DELETE /admin/nodes/{id} endpoint
+// The node key is rejected from then on, forcing the node to re-enroll
+func RevokeNode(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r, "node")
+	if !ok {
+		return
+	}
+
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	if err := dbInstance.RevokeNode(id); err != nil {
+		if errors.Is(err, db.ErrNodeNotFound) {
+			http.Error(w, "Node not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error revoking node: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Revoked node key", map[string]interface{}{
+		"node_id": id,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newEnrollSecretInfo converts an enroll secret to its API representation
+func newEnrollSecretInfo(secret *models.EnrollSecret) EnrollSecretInfo {
+	return EnrollSecretInfo{
+		ID:        secret.ID,
+		Name:      secret.Name,
+		Secret:    secret.Secret,
+		CreatedAt: secret.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		RotatedAt: formatOptionalTime(secret.RotatedAt),
+		RevokedAt: formatOptionalTime(secret.RevokedAt),
+	}
+}
+
+// formatOptionalTime formats a nullable timestamp, returning an empty string
+// when it is not set
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02T15:04:05Z07:00")
+}
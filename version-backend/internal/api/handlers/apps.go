@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"version-backend/internal/db/models"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultEventsWindow is how far back /events looks when no since time is given
+const defaultEventsWindow = 24 * time.Hour
+
+// AppHistoryResponse represents the structure of the response from the /apps/{bundle_id}/history endpoint
+type AppHistoryResponse struct {
+	BundleIdentifier string         `json:"bundle_identifier"`
+	Events           []AppEventInfo `json:"events"`
+}
+
+// EventsResponse represents the structure of the response from the /events endpoint
+type EventsResponse struct {
+	Since  string         `json:"since"`
+	Events []AppEventInfo `json:"events"`
+}
+
+// AppEventInfo represents an app change in the API response
+type AppEventInfo struct {
+	HostID           int64  `json:"host_id"`
+	HostUUID         string `json:"host_uuid"`
+	Hostname         string `json:"hostname"`
+	BundleIdentifier string `json:"bundle_identifier"`
+	Name             string `json:"name"`
+	Path             string `json:"path"`
+	Event            string `json:"event"`
+	FromVersion      string `json:"from_version,omitempty"`
+	ToVersion        string `json:"to_version,omitempty"`
+	Timestamp        string `json:"timestamp"`
+}
+
+// GetAppHistory handles the GET /apps/{bundle_id}/history endpoint
+// It returns every install, removal, upgrade and downgrade of an app, oldest
+// first, optionally restricted to one host with the host_id query parameter
+func GetAppHistory(w http.ResponseWriter, r *http.Request) {
+	bundleID := mux.Vars(r)["bundle_id"]
+
+	hostID, ok := hostIDQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// Get database instance from context
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	events, err := dbInstance.GetAppHistory(bundleID, hostID)
+	if err != nil {
+		http.Error(w, "Error retrieving app history: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := AppHistoryResponse{
+		BundleIdentifier: bundleID,
+		Events:           make([]AppEventInfo, len(events)),
+	}
+	for i := range events {
+		response.Events[i] = newAppEventInfo(&events[i])
+	}
+
+	writeJSON(w, response)
+}
+
+// GetEvents handles the GET /events endpoint
+// It returns the app events recorded since the since query parameter, which
+// defaults to the last 24 hours, optionally restricted to one host
+func GetEvents(w http.ResponseWriter, r *http.Request) {
+	since := time.Now().Add(-defaultEventsWindow)
+	if value := r.URL.Query().Get("since"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "Invalid since time, expected RFC 3339", http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+
+	limit := defaultQueryResultsLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		if n > maxQueryResultsLimit {
+			n = maxQueryResultsLimit
+		}
+		limit = n
+	}
+
+	hostID, ok := hostIDQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// Get database instance from context
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	events, err := dbInstance.GetAppEvents(since, hostID, limit)
+	if err != nil {
+		http.Error(w, "Error retrieving events: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := EventsResponse{
+		Since:  since.Format("2006-01-02T15:04:05Z07:00"),
+		Events: make([]AppEventInfo, len(events)),
+	}
+	for i := range events {
+		response.Events[i] = newAppEventInfo(&events[i])
+	}
+
+	writeJSON(w, response)
+}
+
+// newAppEventInfo converts an app event to its API representation
+func newAppEventInfo(event *models.AppEvent) AppEventInfo {
+	return AppEventInfo{
+		HostID:           event.HostID,
+		HostUUID:         event.HostUUID,
+		Hostname:         event.Hostname,
+		BundleIdentifier: event.BundleIdentifier,
+		Name:             event.Name,
+		Path:             event.Path,
+		Event:            event.EventType,
+		FromVersion:      event.FromVersion,
+		ToVersion:        event.ToVersion,
+		Timestamp:        event.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// hostIDQuery parses the optional host_id query parameter, returning zero
+// when it is absent. It writes an error response and returns false when the
+// ID is invalid.
+func hostIDQuery(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	value := r.URL.Query().Get("host_id")
+	if value == "" {
+		return 0, true
+	}
+
+	hostID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || hostID <= 0 {
+		http.Error(w, "Invalid host ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return hostID, true
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"version-backend/internal/api/middleware"
+	"version-backend/internal/db"
+
+	"github.com/gorilla/mux"
+)
+
+// getDB retrieves the database instance injected by middleware.WithDB. It
+// writes an error response and returns false when none is available.
+func getDB(w http.ResponseWriter, r *http.Request) (db.Store, bool) {
+	dbInstance, ok := r.Context().Value(middleware.DBKey{}).(db.Store)
+	if !ok {
+		http.Error(w, "Database connection not found", http.StatusInternalServerError)
+		return nil, false
+	}
+	return dbInstance, true
+}
+
+// writeInitializing tells the client that no data has been collected yet
+func writeInitializing(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "initializing",
+		"message": "System information is being collected. Please try again in a few seconds.",
+	})
+}
+
+// writeJSON writes the response as uncached JSON
+func writeJSON(w http.ResponseWriter, response interface{}) {
+	// Set response headers
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	// Write response
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Error encoding response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// idParam parses the {id} route variable. It writes an error response and
+// returns false when the ID is invalid.
+func idParam(w http.ResponseWriter, r *http.Request, resource string) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "Invalid "+resource+" ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
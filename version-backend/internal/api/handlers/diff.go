@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"version-backend/internal/db"
+	"version-backend/internal/db/models"
+)
+
+// DiffResponse represents the structure of the response from the /diff endpoint
+type DiffResponse struct {
+	From           DiffSnapshotInfo `json:"from"`
+	To             DiffSnapshotInfo `json:"to"`
+	OSVersion      *VersionChange   `json:"os_version,omitempty"`
+	OsqueryVersion *VersionChange   `json:"osquery_version,omitempty"`
+	Added          []AppInfo        `json:"added"`
+	Removed        []AppInfo        `json:"removed"`
+	Changed        []AppChangeInfo  `json:"changed"`
+}
+
+// DiffSnapshotInfo represents one side of a snapshot diff in the API response
+type DiffSnapshotInfo struct {
+	SnapshotID int64  `json:"snapshot_id"`
+	HostUUID   string `json:"host_uuid"`
+	Hostname   string `json:"hostname"`
+	OSVersion  struct {
+		Name     string `json:"name"`
+		Version  string `json:"version"`
+		Platform string `json:"platform"`
+	} `json:"os_version"`
+	OsqueryVersion string `json:"osquery_version"`
+	AsOf           string `json:"as_of"`
+}
+
+// VersionChange represents a version that differs between two snapshots in the API response
+type VersionChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// AppChangeInfo represents an app whose version changed in the API response
+type AppChangeInfo struct {
+	BundleIdentifier string `json:"bundle_identifier"`
+	Name             string `json:"name"`
+	Path             string `json:"path"`
+	Change           string `json:"change"`
+	FromVersion      string `json:"from_version"`
+	ToVersion        string `json:"to_version"`
+}
+
+// snapshotRef points at a snapshot either by ID or by a point in time. The
+// time of a snapshot referenced by ID is its created_at once loaded.
+type snapshotRef struct {
+	id int64
+	at time.Time
+}
+
+// GetDiff handles the GET /diff endpoint
+// It compares the installed apps, OS and osquery version between two points
+// in a host's history, given by the from and to query parameters as either
+// a snapshot ID or an RFC 3339 time. Times resolve on the host_id query
+// parameter, defaulting to the host of the other snapshot or the most
+// recently updated host. Both snapshots must belong to the same host.
+func GetDiff(w http.ResponseWriter, r *http.Request) {
+	refs := make([]snapshotRef, 2)
+	for i, name := range []string{"from", "to"} {
+		ref, err := parseSnapshotRef(r.URL.Query().Get(name))
+		if err != nil {
+			http.Error(w, "Invalid "+name+": "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		refs[i] = ref
+	}
+
+	hostID, ok := hostIDQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// Get database instance from context
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	// Resolve snapshot IDs first so times default to the host of the snapshot
+	order := []int{1, 0}
+	if refs[0].id != 0 {
+		order = []int{0, 1}
+	}
+
+	snapshots := make([]*models.SystemInfo, 2)
+	for _, i := range order {
+		snapshot, err := loadSnapshot(dbInstance, &refs[i], hostID)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrSnapshotNotFound), errors.Is(err, db.ErrHostNotFound):
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case errors.Is(err, db.ErrNoSystemInfo):
+				http.Error(w, "No snapshot recorded at "+refs[i].at.Format("2006-01-02T15:04:05Z07:00"), http.StatusNotFound)
+			default:
+				http.Error(w, "Error retrieving snapshot: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		if hostID != 0 && snapshot.HostID != hostID {
+			http.Error(w, fmt.Sprintf("Snapshot %d belongs to another host", snapshot.ID), http.StatusBadRequest)
+			return
+		}
+		hostID = snapshot.HostID
+		snapshots[i] = snapshot
+	}
+
+	diff := db.DiffSnapshots(snapshots[0], snapshots[1])
+
+	// Convert to response format
+	response := DiffResponse{
+		From:    newDiffSnapshotInfo(diff.From, refs[0]),
+		To:      newDiffSnapshotInfo(diff.To, refs[1]),
+		Added:   make([]AppInfo, len(diff.Added)),
+		Removed: make([]AppInfo, len(diff.Removed)),
+		Changed: make([]AppChangeInfo, len(diff.Changed)),
+	}
+
+	fromOS := osDescription(diff.From)
+	toOS := osDescription(diff.To)
+	if fromOS != toOS {
+		response.OSVersion = &VersionChange{From: fromOS, To: toOS}
+	}
+	if diff.From.OsqueryVersion != diff.To.OsqueryVersion {
+		response.OsqueryVersion = &VersionChange{
+			From: diff.From.OsqueryVersion,
+			To:   diff.To.OsqueryVersion,
+		}
+	}
+
+	for i := range diff.Added {
+		response.Added[i] = newAppInfo(&diff.Added[i])
+	}
+	for i := range diff.Removed {
+		response.Removed[i] = newAppInfo(&diff.Removed[i])
+	}
+	for i, change := range diff.Changed {
+		response.Changed[i] = AppChangeInfo{
+			BundleIdentifier: change.BundleIdentifier,
+			Name:             change.To.Name,
+			Path:             change.To.Path,
+			Change:           change.Change,
+			FromVersion:      change.From.BundleShortVersion,
+			ToVersion:        change.To.BundleShortVersion,
+		}
+	}
+
+	writeJSON(w, response)
+}
+
+// parseSnapshotRef parses one side of a diff: an integer is a snapshot ID,
+// anything else an RFC 3339 time. The two cannot be confused, as an RFC
+// 3339 time is never an integer.
+func parseSnapshotRef(value string) (snapshotRef, error) {
+	if value == "" {
+		return snapshotRef{}, fmt.Errorf("expected a snapshot ID or an RFC 3339 time")
+	}
+	if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if id <= 0 {
+			return snapshotRef{}, fmt.Errorf("invalid snapshot ID %d", id)
+		}
+		return snapshotRef{id: id}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return snapshotRef{}, fmt.Errorf("expected a snapshot ID or an RFC 3339 time, got %q", value)
+	}
+	return snapshotRef{at: t}, nil
+}
+
+// loadSnapshot retrieves the snapshot a reference points at. A snapshot
+// referenced by ID is rebuilt as it was at its created_at, which becomes
+// the time of the reference. A zero hostID resolves times on any host.
+func loadSnapshot(dbInstance db.Store, ref *snapshotRef, hostID int64) (*models.SystemInfo, error) {
+	if ref.id == 0 {
+		return dbInstance.GetHostSystemInfoAt(hostID, ref.at)
+	}
+
+	snapshot, err := dbInstance.GetSnapshot(ref.id)
+	if err != nil {
+		return nil, err
+	}
+	ref.at = snapshot.CreatedAt
+	return snapshot, nil
+}
+
+// newDiffSnapshotInfo converts one side of a snapshot diff to its API
+// representation
+func newDiffSnapshotInfo(info *models.SystemInfo, ref snapshotRef) DiffSnapshotInfo {
+	snapshot := DiffSnapshotInfo{
+		SnapshotID:     info.ID,
+		OsqueryVersion: info.OsqueryVersion,
+		AsOf:           ref.at.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if info.Host != nil {
+		snapshot.HostUUID = info.Host.UUID
+		snapshot.Hostname = info.Host.Hostname
+	}
+	snapshot.OSVersion.Name = info.OSName
+	snapshot.OSVersion.Version = info.OSVersion
+	snapshot.OSVersion.Platform = info.OSPlatform
+	return snapshot
+}
+
+// osDescription describes the OS of a snapshot, such as "macOS 14.4"
+func osDescription(info *models.SystemInfo) string {
+	return info.OSName + " " + info.OSVersion
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"version-backend/internal/db"
+)
+
+// ExtensionsResponse represents the structure of the response from the /extensions endpoint
+type ExtensionsResponse struct {
+	Extensions  []ExtensionInfo `json:"extensions"`
+	LastUpdated string          `json:"last_updated"`
+}
+
+// ExtensionInfo represents a browser extension in the API response
+type ExtensionInfo struct {
+	Browser     string `json:"browser"`
+	Username    string `json:"username"`
+	Identifier  string `json:"identifier"`
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Permissions string `json:"permissions,omitempty"`
+	ProfilePath string `json:"profile_path,omitempty"`
+	Path        string `json:"path,omitempty"`
+	AddedAt     string `json:"added_at"`
+	RemovedAt   string `json:"removed_at,omitempty"`
+}
+
+// GetExtensions handles the GET /extensions endpoint
+// It retrieves the browser extensions of the most recent system information.
+// With include_removed=true, removed and superseded versions are returned too,
+// showing when each extension was added and removed.
+func GetExtensions(w http.ResponseWriter, r *http.Request) {
+	var includeRemoved bool
+	if value := r.URL.Query().Get("include_removed"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid include_removed value: "+value, http.StatusBadRequest)
+			return
+		}
+		includeRemoved = parsed
+	}
+
+	// Get database instance from context
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	// Get latest browser extensions from database
+	sysInfo, err := dbInstance.GetLatestExtensions(includeRemoved)
+	if err != nil {
+		if errors.Is(err, db.ErrNoSystemInfo) {
+			writeInitializing(w)
+			return
+		}
+		http.Error(w, "Error retrieving browser extensions: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Convert to response format
+	response := ExtensionsResponse{
+		Extensions:  make([]ExtensionInfo, len(sysInfo.Extensions)),
+		LastUpdated: sysInfo.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	for i, ext := range sysInfo.Extensions {
+		var removedAt string
+		if ext.EndTime != nil {
+			removedAt = ext.EndTime.Format("2006-01-02T15:04:05Z07:00")
+		}
+		response.Extensions[i] = ExtensionInfo{
+			Browser:     ext.Browser,
+			Username:    ext.Username,
+			Identifier:  ext.Identifier,
+			Name:        ext.Name,
+			Version:     ext.Version,
+			Permissions: ext.Permissions,
+			ProfilePath: ext.ProfilePath,
+			Path:        ext.Path,
+			AddedAt:     ext.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			RemovedAt:   removedAt,
+		}
+	}
+
+	writeJSON(w, response)
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"version-backend/internal/db"
+)
+
+// HostsResponse represents the structure of the response from the /hosts endpoint
+type HostsResponse struct {
+	Hosts []HostSummary `json:"hosts"`
+}
+
+// HostSummary represents a host of the fleet in the API response. The OS
+// and osquery versions are null for a host that has not saved a snapshot.
+type HostSummary struct {
+	ID int64 `json:"id"`
+	HostInfo
+	OSVersion      *HostOSVersion `json:"os_version"`
+	OsqueryVersion *string        `json:"osquery_version"`
+	LastSeen       string         `json:"last_seen"`
+}
+
+// HostOSVersion represents the OS of the latest snapshot of a host in the API response
+type HostOSVersion struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Platform string `json:"platform"`
+}
+
+// QueryEventsResponse represents the structure of the response from the /hosts/{id}/query_events endpoint
+type QueryEventsResponse struct {
+	HostUUID string           `json:"host_uuid"`
+	Events   []QueryEventInfo `json:"events"`
+}
+
+// QueryEventInfo represents a row added to or removed from a collector query in the API response
+type QueryEventInfo struct {
+	Query     string            `json:"query"`
+	Action    string            `json:"action"`
+	Columns   map[string]string `json:"columns"`
+	Timestamp string            `json:"timestamp"`
+}
+
+// ListHosts handles the GET /hosts endpoint
+// It returns every inventoried host with the OS of its latest snapshot
+func ListHosts(w http.ResponseWriter, r *http.Request) {
+	// Get database instance from context
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	hosts, err := dbInstance.ListHosts()
+	if err != nil {
+		http.Error(w, "Error retrieving hosts: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Convert to response format
+	response := HostsResponse{
+		Hosts: make([]HostSummary, len(hosts)),
+	}
+	for i, host := range hosts {
+		summary := HostSummary{
+			ID:             host.ID,
+			HostInfo:       *newHostInfo(&host.Host),
+			OsqueryVersion: host.OsqueryVersion,
+			LastSeen:       host.LastSeenAt.Format("2006-01-02T15:04:05Z07:00"),
+		}
+		if host.OSName != nil {
+			summary.OSVersion = &HostOSVersion{
+				Name:     *host.OSName,
+				Version:  *host.OSVersion,
+				Platform: *host.OSPlatform,
+			}
+		}
+		response.Hosts[i] = summary
+	}
+
+	writeJSON(w, response)
+}
+
+// GetHostLatestData handles the GET /hosts/{id}/latest_data endpoint
+// It returns the most recent system information of a single host in the
+// same format as /latest_data, including the at query parameter and the
+// ETag
+func GetHostLatestData(w http.ResponseWriter, r *http.Request) {
+	hostID, ok := idParam(w, r, "host")
+	if !ok {
+		return
+	}
+
+	at, ok := atQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// Get database instance from context
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	if !at.IsZero() {
+		sysInfo, err := dbInstance.GetHostSystemInfoAt(hostID, at)
+		writeLatestData(w, sysInfo, at, err)
+		return
+	}
+
+	sysInfo, err := dbInstance.GetHostLatestSystemInfo(hostID)
+	if err == nil && notModified(w, r, sysInfo) {
+		return
+	}
+	writeLatestData(w, sysInfo, at, err)
+}
+
+// GetHostQueryEvents handles the GET /hosts/{id}/query_events endpoint
+// It returns the rows added to or removed from the collector queries of a
+// host, optionally filtered by the query, since and limit query parameters
+func GetHostQueryEvents(w http.ResponseWriter, r *http.Request) {
+	hostID, ok := idParam(w, r, "host")
+	if !ok {
+		return
+	}
+
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "Invalid since time, expected RFC 3339", http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+
+	limit := defaultQueryResultsLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		if n > maxQueryResultsLimit {
+			n = maxQueryResultsLimit
+		}
+		limit = n
+	}
+
+	// Get database instance from context
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	host, err := dbInstance.GetHost(hostID)
+	if err != nil {
+		if errors.Is(err, db.ErrHostNotFound) {
+			http.Error(w, "Host not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error retrieving host: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	events, err := dbInstance.GetQueryEvents(host.UUID, r.URL.Query().Get("query"), since, limit)
+	if err != nil {
+		http.Error(w, "Error retrieving query events: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := QueryEventsResponse{
+		HostUUID: host.UUID,
+		Events:   make([]QueryEventInfo, len(events)),
+	}
+	for i, event := range events {
+		info := QueryEventInfo{
+			Query:     event.QueryName,
+			Action:    event.Action,
+			Timestamp: event.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		}
+		json.Unmarshal([]byte(event.Columns), &info.Columns)
+		response.Events[i] = info
+	}
+
+	writeJSON(w, response)
+}
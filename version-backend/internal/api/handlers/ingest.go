@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"version-backend/internal/db"
+	"version-backend/internal/ingest"
+)
+
+// maxIngestBodySize bounds the size of an ingested snapshot
+const maxIngestBodySize = 32 << 20
+
+// IngestResponse represents the structure of the response from the /ingest endpoint
+// Status is "unchanged" when the snapshot was identical to the latest of
+// the host and was not written
+type IngestResponse struct {
+	Status      string `json:"status"`
+	HostUUID    string `json:"host_uuid"`
+	ContentHash string `json:"content_hash"`
+}
+
+// Ingest returns the handler for the POST /ingest endpoint
+// It authenticates the agent by the node key it obtained from
+// /osquery/enroll, validates the collector results and saves the resulting
+// snapshot
+func Ingest(service *ingest.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dbInstance, ok := getDB(w, r)
+		if !ok {
+			return
+		}
+
+		nodeKey := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		node, err := dbInstance.AuthenticateNode(nodeKey)
+		if err != nil {
+			if errors.Is(err, db.ErrNodeNotFound) {
+				http.Error(w, "Unknown or revoked node key", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "Error authenticating node: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var payload ingest.Payload
+		r.Body = http.MaxBytesReader(w, r.Body, maxIngestBodySize)
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid payload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		snapshot, saved, err := service.IngestNode(node, payload.Results)
+		if err != nil {
+			switch {
+			case errors.Is(err, ingest.ErrInvalidPayload):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, ingest.ErrHostMismatch):
+				http.Error(w, err.Error(), http.StatusForbidden)
+			default:
+				http.Error(w, "Error ingesting snapshot: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		status := "accepted"
+		if !saved {
+			status = "unchanged"
+		}
+		writeJSON(w, IngestResponse{
+			Status:      status,
+			HostUUID:    snapshot.Host.UUID,
+			ContentHash: snapshot.ContentHash,
+		})
+	}
+}
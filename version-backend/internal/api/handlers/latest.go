@@ -1,26 +1,54 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
-	"version-backend/internal/api/middleware"
 	"version-backend/internal/db"
+	"version-backend/internal/db/models"
 )
 
 // LatestDataResponse represents the structure of the response from the /latest_data endpoint
 type LatestDataResponse struct {
-	OSVersion struct {
+	SnapshotID int64     `json:"snapshot_id"`
+	Host       *HostInfo `json:"host,omitempty"`
+	OSVersion  struct {
 		Name     string `json:"name"`
 		Version  string `json:"version"`
 		Platform string `json:"platform"`
 	} `json:"os_version"`
-	OsqueryVersion string    `json:"osquery_version"`
-	InstalledApps  []AppInfo `json:"installed_apps"`
-	LastUpdated    string    `json:"last_updated"`
+	OsqueryVersion    string        `json:"osquery_version"`
+	InstalledApps     []AppInfo     `json:"installed_apps"`
+	InstalledPackages []PackageInfo `json:"installed_packages"`
+	LastUpdated       string        `json:"last_updated"`
+	AsOf              string        `json:"as_of,omitempty"`
 }
 
-// AppInfo represents an installed application in the API response
+// HostInfo represents the identity and hardware of a host in the API response
+type HostInfo struct {
+	UUID             string `json:"uuid"`
+	Hostname         string `json:"hostname"`
+	ComputerName     string `json:"computer_name,omitempty"`
+	CPUBrand         string `json:"cpu_brand,omitempty"`
+	CPUType          string `json:"cpu_type,omitempty"`
+	CPUPhysicalCores int    `json:"cpu_physical_cores,omitempty"`
+	CPULogicalCores  int    `json:"cpu_logical_cores,omitempty"`
+	PhysicalMemory   int64  `json:"physical_memory,omitempty"`
+	HardwareVendor   string `json:"hardware_vendor,omitempty"`
+	HardwareModel    string `json:"hardware_model,omitempty"`
+	HardwareSerial   string `json:"hardware_serial,omitempty"`
+	Platform         struct {
+		Vendor   string `json:"vendor,omitempty"`
+		Version  string `json:"version,omitempty"`
+		Date     string `json:"date,omitempty"`
+		Revision string `json:"revision,omitempty"`
+	} `json:"platform"`
+}
+
+// AppInfo represents an installed application in the API response. An app
+// is outdated when a newer version of it is installed on any host.
 type AppInfo struct {
 	Name                 string  `json:"name"`
 	Path                 string  `json:"path"`
@@ -30,30 +58,110 @@ type AppInfo struct {
 	DisplayName          string  `json:"display_name,omitempty"`
 	MinimumSystemVersion string  `json:"minimum_system_version,omitempty"`
 	LastOpenedTime       float64 `json:"last_opened_time,omitempty"`
+	CPE                  string  `json:"cpe,omitempty"`
 	EndTime              float64 `json:"end_time,omitempty"`
+	Outdated             bool    `json:"outdated,omitempty"`
+	NewestVersion        string  `json:"newest_version,omitempty"`
+}
+
+// PackageInfo represents a package installed through an OS package manager
+// in the API response
+type PackageInfo struct {
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	Arch          string `json:"arch,omitempty"`
+	Source        string `json:"source"`
+	Revision      string `json:"revision,omitempty"`
+	SourcePackage string `json:"source_package,omitempty"`
+	InstallTime   int64  `json:"install_time,omitempty"`
 }
 
 // GetLatestData handles the GET /latest_data endpoint
 // It retrieves the most recent system information from the database
-// and returns it in JSON format
+// and returns it in JSON format. The at query parameter returns the system
+// information as it was at a past time instead. The content hash of the
+// latest system information is sent as its ETag, so clients polling with
+// If-None-Match get 304 Not Modified until the data changes.
 func GetLatestData(w http.ResponseWriter, r *http.Request) {
-	// Get database instance from context using the correct key
-	dbInstance, ok := r.Context().Value(middleware.DBKey{}).(*db.DB)
+	at, ok := atQuery(w, r)
 	if !ok {
-		http.Error(w, "Database connection not found", http.StatusInternalServerError)
+		return
+	}
+
+	// Get database instance from context
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	if !at.IsZero() {
+		sysInfo, err := dbInstance.GetSystemInfoAt(at)
+		writeLatestData(w, sysInfo, at, err)
 		return
 	}
 
 	// Get latest system info from database
 	sysInfo, err := dbInstance.GetLatestSystemInfo()
+	if err == nil && notModified(w, r, sysInfo) {
+		return
+	}
+	writeLatestData(w, sysInfo, at, err)
+}
+
+// notModified sets the ETag header to the content hash of a system
+// information snapshot and writes a 304 Not Modified response when it
+// matches the If-None-Match header of the request. Snapshots saved before
+// content hashes were recorded have no ETag.
+func notModified(w http.ResponseWriter, r *http.Request, sysInfo *models.SystemInfo) bool {
+	if sysInfo.ContentHash == "" {
+		return false
+	}
+
+	etag := `"` + sysInfo.ContentHash + `"`
+	w.Header().Set("ETag", etag)
+
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// atQuery parses the optional at query parameter, returning the zero time
+// when it is absent. It writes an error response and returns false when the
+// time is invalid.
+func atQuery(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	value := r.URL.Query().Get("at")
+	if value == "" {
+		return time.Time{}, true
+	}
+
+	at, err := time.Parse(time.RFC3339, value)
 	if err != nil {
-		if err.Error() == "no system information available yet - waiting for first osquery data collection" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusServiceUnavailable)
-			json.NewEncoder(w).Encode(map[string]string{
-				"status":  "initializing",
-				"message": "System information is being collected. Please try again in a few seconds.",
-			})
+		http.Error(w, "Invalid at time, expected RFC 3339", http.StatusBadRequest)
+		return time.Time{}, false
+	}
+	return at, true
+}
+
+// writeLatestData writes a system information snapshot in the
+// LatestDataResponse format, or the error that occurred loading it. A
+// non-zero at is the point in time the snapshot was reconstructed for.
+func writeLatestData(w http.ResponseWriter, sysInfo *models.SystemInfo, at time.Time, err error) {
+	if err != nil {
+		if errors.Is(err, db.ErrNoSystemInfo) {
+			if !at.IsZero() {
+				http.Error(w, "No system information recorded at "+at.Format("2006-01-02T15:04:05Z07:00"), http.StatusNotFound)
+				return
+			}
+			writeInitializing(w)
+			return
+		}
+		if errors.Is(err, db.ErrHostNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
 		http.Error(w, "Error retrieving system information: "+err.Error(), http.StatusInternalServerError)
@@ -62,9 +170,18 @@ func GetLatestData(w http.ResponseWriter, r *http.Request) {
 
 	// Convert to response format
 	response := LatestDataResponse{
+		SnapshotID:     sysInfo.ID,
 		OsqueryVersion: sysInfo.OsqueryVersion,
 		LastUpdated:    sysInfo.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
+	if !at.IsZero() {
+		response.AsOf = at.Format("2006-01-02T15:04:05Z07:00")
+	}
+
+	// Set host identity
+	if sysInfo.Host != nil {
+		response.Host = newHostInfo(sysInfo.Host)
+	}
 
 	// Set OS version info
 	response.OSVersion.Name = sysInfo.OSName
@@ -73,31 +190,67 @@ func GetLatestData(w http.ResponseWriter, r *http.Request) {
 
 	// Convert installed apps
 	response.InstalledApps = make([]AppInfo, len(sysInfo.InstalledApps))
-	for i, app := range sysInfo.InstalledApps {
-		var endTime float64
-		if app.EndTime != nil {
-			endTime = float64(app.EndTime.Unix())
-		}
-		response.InstalledApps[i] = AppInfo{
-			Name:                 app.Name,
-			Path:                 app.Path,
-			BundleIdentifier:     app.BundleIdentifier,
-			BundleName:           app.BundleName,
-			BundleShortVersion:   app.BundleShortVersion,
-			DisplayName:          app.DisplayName,
-			MinimumSystemVersion: app.MinimumSystemVersion,
-			LastOpenedTime:       app.LastOpenedTime,
-			EndTime:              endTime,
+	for i := range sysInfo.InstalledApps {
+		response.InstalledApps[i] = newAppInfo(&sysInfo.InstalledApps[i])
+	}
+
+	// Convert installed packages
+	response.InstalledPackages = make([]PackageInfo, len(sysInfo.Packages))
+	for i, pkg := range sysInfo.Packages {
+		response.InstalledPackages[i] = PackageInfo{
+			Name:          pkg.Name,
+			Version:       pkg.Version,
+			Arch:          pkg.Arch,
+			Source:        pkg.Source,
+			Revision:      pkg.Revision,
+			SourcePackage: pkg.SourcePackage,
+			InstallTime:   pkg.InstallTime,
 		}
 	}
 
-	// Set response headers
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Cache-Control", "no-cache")
+	writeJSON(w, response)
+}
+
+// newAppInfo converts an installed app model to its API representation
+func newAppInfo(app *models.InstalledApp) AppInfo {
+	var endTime float64
+	if app.EndTime != nil {
+		endTime = float64(app.EndTime.Unix())
+	}
+	return AppInfo{
+		Name:                 app.Name,
+		Path:                 app.Path,
+		BundleIdentifier:     app.BundleIdentifier,
+		BundleName:           app.BundleName,
+		BundleShortVersion:   app.BundleShortVersion,
+		DisplayName:          app.DisplayName,
+		MinimumSystemVersion: app.MinimumSystemVersion,
+		LastOpenedTime:       app.LastOpenedTime,
+		CPE:                  app.CPE,
+		EndTime:              endTime,
+		Outdated:             app.NewestVersion != "",
+		NewestVersion:        app.NewestVersion,
+	}
+}
 
-	// Write response
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, "Error encoding response: "+err.Error(), http.StatusInternalServerError)
-		return
+// newHostInfo converts a host model to its API representation
+func newHostInfo(host *models.Host) *HostInfo {
+	info := &HostInfo{
+		UUID:             host.UUID,
+		Hostname:         host.Hostname,
+		ComputerName:     host.ComputerName,
+		CPUBrand:         host.CPUBrand,
+		CPUType:          host.CPUType,
+		CPUPhysicalCores: host.CPUPhysicalCores,
+		CPULogicalCores:  host.CPULogicalCores,
+		PhysicalMemory:   host.PhysicalMemory,
+		HardwareVendor:   host.HardwareVendor,
+		HardwareModel:    host.HardwareModel,
+		HardwareSerial:   host.HardwareSerial,
 	}
+	info.Platform.Vendor = host.PlatformVendor
+	info.Platform.Version = host.PlatformVersion
+	info.Platform.Date = host.PlatformDate
+	info.Platform.Revision = host.PlatformRevision
+	return info
 }
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"version-backend/internal/db"
+	"version-backend/internal/db/models"
+	"version-backend/internal/live"
+)
+
+const (
+	// defaultLiveQueryTimeout leaves remote nodes one distributed interval
+	// to pick up the query and another to answer
+	defaultLiveQueryTimeout = 120 * time.Second
+
+	// maxLiveQueryTimeout bounds how long a live query request may stream
+	maxLiveQueryTimeout = 10 * time.Minute
+)
+
+// LiveQueryRequest is the body of POST /queries/live
+type LiveQueryRequest struct {
+	Query    string        `json:"query"`
+	Selector live.Selector `json:"selector"`
+	Timeout  int           `json:"timeout"`
+}
+
+// LiveQueryEvent is a line of the streamed POST /queries/live response
+type LiveQueryEvent struct {
+	Type       string            `json:"type"`
+	CampaignID int64             `json:"campaign_id"`
+	Targets    []LiveQueryResult `json:"targets,omitempty"`
+	Result     *LiveQueryResult  `json:"result,omitempty"`
+	Counts     map[string]int    `json:"counts,omitempty"`
+}
+
+// LiveQueryResponse represents the structure of the response from the /queries/live/{id} endpoint
+type LiveQueryResponse struct {
+	CampaignID int64             `json:"campaign_id"`
+	Query      string            `json:"query"`
+	Selector   json.RawMessage   `json:"selector"`
+	CreatedAt  string            `json:"created_at"`
+	Results    []LiveQueryResult `json:"results"`
+}
+
+// LiveQueryResult represents the result of a live query on a single host
+type LiveQueryResult struct {
+	HostUUID       string              `json:"host_uuid"`
+	HostIdentifier string              `json:"host_identifier"`
+	Status         string              `json:"status"`
+	RowCount       int                 `json:"row_count"`
+	Rows           []map[string]string `json:"rows,omitempty"`
+	Error          string              `json:"error,omitempty"`
+	CompletedAt    string              `json:"completed_at,omitempty"`
+}
+
+// RunLiveQuery returns the handler for the POST /queries/live endpoint
+// It starts a campaign running the query on the selected hosts and streams
+// one JSON line per event: the campaign and its targets, each host result as
+// it arrives, and the final status counts
+func RunLiveQuery(service *live.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request LiveQueryRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		timeout := defaultLiveQueryTimeout
+		if request.Timeout > 0 {
+			timeout = time.Duration(request.Timeout) * time.Second
+		}
+		if timeout > maxLiveQueryTimeout {
+			timeout = maxLiveQueryTimeout
+		}
+
+		campaign, targets, results, err := service.Run(r.Context(), request.Query, request.Selector, timeout)
+		if err != nil {
+			switch {
+			case errors.Is(err, live.ErrInvalidQuery), errors.Is(err, live.ErrInvalidSelector):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, live.ErrNoTargets), errors.Is(err, db.ErrHostNotFound):
+				http.Error(w, err.Error(), http.StatusNotFound)
+			default:
+				http.Error(w, "Error starting live query: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Cache-Control", "no-cache")
+		flusher, _ := w.(http.Flusher)
+		encoder := json.NewEncoder(w)
+		send := func(event LiveQueryEvent) bool {
+			if err := encoder.Encode(event); err != nil {
+				return false
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return true
+		}
+
+		started := LiveQueryEvent{
+			Type:       "campaign",
+			CampaignID: campaign.ID,
+			Targets:    make([]LiveQueryResult, len(targets)),
+		}
+		for i := range targets {
+			targets[i].Status = models.CampaignStatusPending
+			started.Targets[i] = newLiveQueryResult(&targets[i])
+		}
+		if !send(started) {
+			return
+		}
+
+		counts := make(map[string]int)
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case result, ok := <-results:
+				if !ok {
+					send(LiveQueryEvent{Type: "done", CampaignID: campaign.ID, Counts: counts})
+					return
+				}
+				counts[result.Status]++
+				hostResult := newLiveQueryResult(&result)
+				if !send(LiveQueryEvent{Type: "result", CampaignID: campaign.ID, Result: &hostResult}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// GetLiveQuery handles the GET /queries/live/{id} endpoint
+// It re-opens a stored campaign with the results of every targeted host
+func GetLiveQuery(w http.ResponseWriter, r *http.Request) {
+	campaignID, ok := idParam(w, r, "campaign")
+	if !ok {
+		return
+	}
+
+	// Get database instance from context
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	campaign, err := dbInstance.GetCampaign(campaignID)
+	if err != nil {
+		if errors.Is(err, db.ErrCampaignNotFound) {
+			http.Error(w, "Campaign not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error retrieving campaign: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	results, err := dbInstance.GetCampaignResults(campaignID)
+	if err != nil {
+		http.Error(w, "Error retrieving campaign results: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := LiveQueryResponse{
+		CampaignID: campaign.ID,
+		Query:      campaign.Query,
+		Selector:   json.RawMessage(campaign.Selector),
+		CreatedAt:  campaign.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		Results:    make([]LiveQueryResult, len(results)),
+	}
+	for i := range results {
+		response.Results[i] = newLiveQueryResult(&results[i])
+	}
+
+	writeJSON(w, response)
+}
+
+// newLiveQueryResult converts a stored host result to its API representation
+func newLiveQueryResult(result *models.CampaignResult) LiveQueryResult {
+	hostResult := LiveQueryResult{
+		HostUUID:       result.HostUUID,
+		HostIdentifier: result.HostIdentifier,
+		Status:         result.Status,
+		RowCount:       result.RowCount,
+		CompletedAt:    formatOptionalTime(result.CompletedAt),
+	}
+	if result.Results != nil {
+		json.Unmarshal([]byte(*result.Results), &hostResult.Rows)
+	}
+	if result.Error != nil {
+		hostResult.Error = *result.Error
+	}
+	return hostResult
+}
@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"version-backend/internal/db"
+	"version-backend/internal/db/models"
+	"version-backend/internal/ingest"
+	"version-backend/internal/live"
+	"version-backend/internal/osquery"
+	"version-backend/internal/packs"
+	"version-backend/pkg/logger"
+)
+
+// collectQueryPrefix prefixes the distributed query names used to run the
+// collectors on a remote node
+const collectQueryPrefix = "collect:"
+
+// maxRemoteBodySize bounds the size of a request from an osqueryd node
+const maxRemoteBodySize = 32 << 20
+
+// OsqueryRemote implements the osquery TLS remote API, letting osqueryd
+// instances enroll, pull their configuration, ship logs and answer
+// distributed queries
+type OsqueryRemote struct {
+	interval time.Duration
+	registry *osquery.Registry
+	ingest   *ingest.Service
+	live     *live.Service
+	packs    []packs.Pack
+}
+
+// NewOsqueryRemote creates the TLS remote API handlers. Nodes run the
+// registry collectors through distributed queries every interval, along
+// with the live queries they are targeted by. The query packs are part of
+// the node configuration.
+func NewOsqueryRemote(interval time.Duration, registry *osquery.Registry, ingestService *ingest.Service, liveService *live.Service, queryPacks []packs.Pack) *OsqueryRemote {
+	return &OsqueryRemote{
+		interval: interval,
+		registry: registry,
+		ingest:   ingestService,
+		live:     liveService,
+		packs:    queryPacks,
+	}
+}
+
+// EnrollRequest is the body osqueryd and agents send to the enroll endpoint
+type EnrollRequest struct {
+	EnrollSecret   string `json:"enroll_secret"`
+	HostIdentifier string `json:"host_identifier"`
+	HostDetails    struct {
+		OSVersion  map[string]string `json:"os_version"`
+		SystemInfo map[string]string `json:"system_info"`
+	} `json:"host_details"`
+}
+
+// EnrollResponse is the answer to a successful enrollment
+type EnrollResponse struct {
+	NodeKey     string `json:"node_key"`
+	NodeInvalid bool   `json:"node_invalid"`
+}
+
+// nodeRequest is the common part of every authenticated request
+type nodeRequest struct {
+	NodeKey string `json:"node_key"`
+}
+
+// logRequest is the body osqueryd sends to the logger endpoint
+type logRequest struct {
+	NodeKey string            `json:"node_key"`
+	LogType string            `json:"log_type"`
+	Data    []json.RawMessage `json:"data"`
+}
+
+// distributedWriteRequest is the body osqueryd sends with distributed query results
+type distributedWriteRequest struct {
+	NodeKey  string                     `json:"node_key"`
+	Queries  map[string]json.RawMessage `json:"queries"`
+	Statuses map[string]int             `json:"statuses"`
+	Messages map[string]string          `json:"messages"`
+}
+
+// Enroll handles POST /osquery/enroll
+// It checks the enroll secret and issues a new node key. Agents enroll
+// through the same endpoint before posting to /ingest.
+func (o *OsqueryRemote) Enroll(w http.ResponseWriter, r *http.Request) {
+	var req EnrollRequest
+	if !decodeRemoteRequest(w, r, &req) {
+		return
+	}
+
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	node := &models.Node{
+		HostIdentifier: req.HostIdentifier,
+		HostUUID:       req.HostDetails.SystemInfo["uuid"],
+		Platform:       req.HostDetails.OSVersion["platform"],
+		PlatformLike:   req.HostDetails.OSVersion["platform_like"],
+	}
+	replaced, err := dbInstance.EnrollNode(req.EnrollSecret, node)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidEnrollSecret) {
+			logger.Info("Rejected enrollment with invalid secret", map[string]interface{}{
+				"host_identifier": req.HostIdentifier,
+				"host_uuid":       node.HostUUID,
+				"remote_addr":     r.RemoteAddr,
+			})
+			writeNodeInvalid(w)
+			return
+		}
+		http.Error(w, "Error enrolling node: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Enrolled node", map[string]interface{}{
+		"node_id":          node.ID,
+		"enroll_secret_id": *node.EnrollSecretID,
+		"host_identifier":  node.HostIdentifier,
+		"host_uuid":        node.HostUUID,
+		"platform":         node.Platform,
+		"remote_addr":      r.RemoteAddr,
+	})
+	for _, id := range replaced {
+		logger.Warn("Revoked the node key of a re-enrolled host", map[string]interface{}{
+			"node_id":     id,
+			"new_node_id": node.ID,
+			"host_uuid":   node.HostUUID,
+			"remote_addr": r.RemoteAddr,
+		})
+	}
+
+	writeJSON(w, EnrollResponse{NodeKey: node.NodeKey})
+}
+
+// Config handles POST /osquery/config
+// It returns the osquery configuration for the node, including the query
+// packs
+func (o *OsqueryRemote) Config(w http.ResponseWriter, r *http.Request) {
+	var req nodeRequest
+	if !decodeRemoteRequest(w, r, &req) {
+		return
+	}
+
+	if _, ok := o.authenticate(w, r, req.NodeKey); !ok {
+		return
+	}
+
+	// osqueryd filters the packs by platform and version itself
+	nodePacks := make(map[string]packs.Pack, len(o.packs))
+	for _, pack := range o.packs {
+		nodePacks[pack.Name] = pack
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"options": map[string]interface{}{
+			"disable_distributed":  false,
+			"distributed_interval": 60,
+			"pack_delimiter":       packs.Delimiter,
+		},
+		"schedule":     map[string]interface{}{},
+		"packs":        nodePacks,
+		"node_invalid": false,
+	})
+}
+
+// Log handles POST /osquery/log
+// Status logs are forwarded to the application logger and the results of
+// pack queries are stored
+func (o *OsqueryRemote) Log(w http.ResponseWriter, r *http.Request) {
+	var req logRequest
+	if !decodeRemoteRequest(w, r, &req) {
+		return
+	}
+
+	node, ok := o.authenticate(w, r, req.NodeKey)
+	if !ok {
+		return
+	}
+
+	switch req.LogType {
+	case "status":
+		for _, raw := range req.Data {
+			// Depending on the osquery version, fields are strings or numbers
+			var line map[string]interface{}
+			if err := json.Unmarshal(raw, &line); err != nil {
+				continue
+			}
+			severity := fmt.Sprint(line["severity"])
+			message := fmt.Sprint("osqueryd: ", line["message"])
+			fields := map[string]interface{}{
+				"node_id":  node.ID,
+				"filename": line["filename"],
+				"line":     line["line"],
+			}
+			// osquery severities: 0 info, 1 warning, 2 error, 3 fatal
+			if severity == "0" {
+				logger.Debug(message, fields)
+			} else {
+				logger.Error(message, errors.New("severity "+severity), fields)
+			}
+		}
+	case "result":
+		var results []models.QueryResult
+		for _, raw := range req.Data {
+			lineResults, err := packs.ResultsFromLog(node.HostUUID, raw)
+			if err != nil {
+				logger.Error("Failed to decode result log", err, map[string]interface{}{
+					"node_id": node.ID,
+				})
+				continue
+			}
+			results = append(results, lineResults...)
+		}
+
+		dbInstance, ok := getDB(w, r)
+		if !ok {
+			return
+		}
+		if err := dbInstance.SaveQueryResults(results); err != nil {
+			http.Error(w, "Error storing query results: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		logger.Debug("Received osquery logs", map[string]interface{}{
+			"node_id":  node.ID,
+			"log_type": req.LogType,
+			"count":    len(req.Data),
+		})
+	}
+
+	writeJSON(w, map[string]interface{}{"node_invalid": false})
+}
+
+// DistributedRead handles POST /osquery/distributed/read
+// Once per collection interval it hands the node the queries of every
+// collector that applies to its platform, and hands out pending live queries
+// on every read
+func (o *OsqueryRemote) DistributedRead(w http.ResponseWriter, r *http.Request) {
+	var req nodeRequest
+	if !decodeRemoteRequest(w, r, &req) {
+		return
+	}
+
+	node, ok := o.authenticate(w, r, req.NodeKey)
+	if !ok {
+		return
+	}
+
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	queries, err := o.live.PendingQueries(node)
+	if err != nil {
+		http.Error(w, "Error getting live queries: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	claimed, err := dbInstance.ClaimCollection(node.ID, o.interval)
+	if err != nil {
+		http.Error(w, "Error scheduling collection: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if claimed {
+		platform := &models.SystemInfo{
+			OSPlatform:     node.Platform,
+			OSPlatformLike: node.PlatformLike,
+		}
+		for _, collector := range o.registry.Collectors() {
+			if osquery.Supports(collector, platform) {
+				queries[collectQueryPrefix+collector.Name()] = collector.Query()
+			}
+		}
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"queries":      queries,
+		"node_invalid": false,
+	})
+}
+
+// DistributedWrite handles POST /osquery/distributed/write
+// Collector results are mapped through the registry and saved as a snapshot,
+// and live query results are stored with their campaign
+func (o *OsqueryRemote) DistributedWrite(w http.ResponseWriter, r *http.Request) {
+	var req distributedWriteRequest
+	if !decodeRemoteRequest(w, r, &req) {
+		return
+	}
+
+	node, ok := o.authenticate(w, r, req.NodeKey)
+	if !ok {
+		return
+	}
+
+	results := make(osquery.Results)
+	o.recordLiveResults(node, &req)
+
+	for name, raw := range req.Queries {
+		collector, ok := strings.CutPrefix(name, collectQueryPrefix)
+		if !ok {
+			continue
+		}
+		if status, ok := req.Statuses[name]; ok && status != 0 {
+			logger.Error("Distributed collector query failed", errors.New(req.Messages[name]), map[string]interface{}{
+				"node_id":   node.ID,
+				"collector": collector,
+			})
+			continue
+		}
+		results[collector] = decodeRows(raw)
+	}
+
+	if len(results) > 0 {
+		if _, _, err := o.ingest.IngestNode(node, results); err != nil {
+			logger.Error("Failed to ingest distributed results", err, map[string]interface{}{
+				"node_id": node.ID,
+			})
+		}
+	}
+
+	writeJSON(w, map[string]interface{}{"node_invalid": false})
+}
+
+// recordLiveResults stores the live query results of a distributed write.
+// Failed queries may only be reported in the statuses.
+func (o *OsqueryRemote) recordLiveResults(node *models.Node, req *distributedWriteRequest) {
+	names := make(map[string]struct{})
+	for name := range req.Queries {
+		names[name] = struct{}{}
+	}
+	for name := range req.Statuses {
+		names[name] = struct{}{}
+	}
+
+	for name := range names {
+		campaignID, ok := live.ParseQueryName(name)
+		if !ok {
+			continue
+		}
+
+		var queryErr error
+		if status := req.Statuses[name]; status != 0 {
+			queryErr = errors.New(req.Messages[name])
+		}
+		if err := o.live.RecordNodeResult(node, campaignID, decodeRows(req.Queries[name]), queryErr); err != nil {
+			logger.Error("Failed to store live query result", err, map[string]interface{}{
+				"node_id":     node.ID,
+				"campaign_id": campaignID,
+			})
+		}
+	}
+}
+
+// authenticate resolves the node owning a node key. Unknown keys are
+// answered with node_invalid so osqueryd enrolls again.
+func (o *OsqueryRemote) authenticate(w http.ResponseWriter, r *http.Request, nodeKey string) (*models.Node, bool) {
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	node, err := dbInstance.AuthenticateNode(nodeKey)
+	if err != nil {
+		if errors.Is(err, db.ErrNodeNotFound) {
+			writeNodeInvalid(w)
+			return nil, false
+		}
+		http.Error(w, "Error authenticating node: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return node, true
+}
+
+// decodeRemoteRequest decodes a JSON request body from osqueryd. It writes
+// an error response and returns false when the body is invalid.
+func decodeRemoteRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRemoteBodySize)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// decodeRows decodes the rows of a distributed query result. osqueryd sends
+// an empty string instead of an array for queries without results.
+func decodeRows(raw json.RawMessage) []map[string]string {
+	var rows []map[string]string
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil
+	}
+	return rows
+}
+
+// writeNodeInvalid tells osqueryd its enrollment is invalid
+func writeNodeInvalid(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"node_invalid": true,
+	})
+}
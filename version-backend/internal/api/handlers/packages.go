@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"version-backend/internal/db"
+)
+
+// ecosystems lists the language package ecosystems accepted by /api/packages
+var ecosystems = map[string]bool{
+	"pip": true,
+	"npm": true,
+	"gem": true,
+	"go":  true,
+}
+
+// PackagesResponse represents the structure of the response from the /packages endpoint
+type PackagesResponse struct {
+	Ecosystem   string                `json:"ecosystem,omitempty"`
+	Packages    []LanguagePackageInfo `json:"packages"`
+	LastUpdated string                `json:"last_updated"`
+}
+
+// LanguagePackageInfo represents a language package in the API response
+type LanguagePackageInfo struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Path      string `json:"path"`
+	Username  string `json:"username,omitempty"`
+}
+
+// GetPackages handles the GET /packages endpoint
+// It retrieves the language packages of the most recent system information,
+// optionally filtered by the ecosystem query parameter
+func GetPackages(w http.ResponseWriter, r *http.Request) {
+	ecosystem := r.URL.Query().Get("ecosystem")
+	if ecosystem != "" && !ecosystems[ecosystem] {
+		http.Error(w, "Unknown ecosystem: "+ecosystem, http.StatusBadRequest)
+		return
+	}
+
+	// Get database instance from context
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	// Get latest language packages from database
+	sysInfo, err := dbInstance.GetLatestLanguagePackages(ecosystem)
+	if err != nil {
+		if errors.Is(err, db.ErrNoSystemInfo) {
+			writeInitializing(w)
+			return
+		}
+		http.Error(w, "Error retrieving packages: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Convert to response format
+	response := PackagesResponse{
+		Ecosystem:   ecosystem,
+		Packages:    make([]LanguagePackageInfo, len(sysInfo.LangPackages)),
+		LastUpdated: sysInfo.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	for i, pkg := range sysInfo.LangPackages {
+		response.Packages[i] = LanguagePackageInfo{
+			Ecosystem: pkg.Ecosystem,
+			Name:      pkg.Name,
+			Version:   pkg.Version,
+			Path:      pkg.Path,
+			Username:  pkg.Username,
+		}
+	}
+
+	writeJSON(w, response)
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"version-backend/internal/db"
+	"version-backend/internal/packs"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// defaultQueryResultsLimit is the number of query results returned when no limit is given
+	defaultQueryResultsLimit = 100
+
+	// maxQueryResultsLimit bounds the number of query results returned at once
+	maxQueryResultsLimit = 1000
+)
+
+// PacksResponse represents the structure of the response from the /packs endpoint
+type PacksResponse struct {
+	Packs []PackInfo `json:"packs"`
+}
+
+// PackInfo represents a query pack in the API response
+type PackInfo struct {
+	Name     string      `json:"name"`
+	Platform string      `json:"platform,omitempty"`
+	Version  string      `json:"version,omitempty"`
+	Queries  []QueryInfo `json:"queries"`
+}
+
+// QueryInfo represents a scheduled query of a pack in the API response
+type QueryInfo struct {
+	Name        string `json:"name"`
+	Query       string `json:"query"`
+	Interval    int    `json:"interval"`
+	Platform    string `json:"platform,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Snapshot    bool   `json:"snapshot"`
+	Description string `json:"description,omitempty"`
+}
+
+// QueryResultsResponse represents the structure of the response from the /packs/{pack}/{query}/results endpoint
+type QueryResultsResponse struct {
+	Pack    string            `json:"pack"`
+	Query   string            `json:"query"`
+	Results []QueryResultInfo `json:"results"`
+}
+
+// QueryResultInfo represents a row returned by a scheduled query in the API response
+type QueryResultInfo struct {
+	HostUUID    string            `json:"host_uuid"`
+	Action      string            `json:"action"`
+	Columns     map[string]string `json:"columns"`
+	CollectedAt string            `json:"collected_at"`
+}
+
+// ListPacks returns the handler for the GET /packs endpoint
+// It lists the query packs loaded at startup
+func ListPacks(queryPacks []packs.Pack) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := PacksResponse{
+			Packs: make([]PackInfo, len(queryPacks)),
+		}
+		for i, pack := range queryPacks {
+			info := PackInfo{
+				Name:     pack.Name,
+				Platform: pack.Platform,
+				Version:  pack.Version,
+			}
+			for _, name := range pack.QueryNames() {
+				query := pack.Queries[name]
+				info.Queries = append(info.Queries, QueryInfo{
+					Name:        name,
+					Query:       query.Query,
+					Interval:    query.Interval,
+					Platform:    query.Platform,
+					Version:     query.Version,
+					Snapshot:    query.Snapshot,
+					Description: query.Description,
+				})
+			}
+			response.Packs[i] = info
+		}
+
+		writeJSON(w, response)
+	}
+}
+
+// GetQueryResults handles the GET /packs/{pack}/{query}/results endpoint
+// It returns the most recent results of a scheduled query, optionally
+// filtered by the host_uuid and since query parameters
+func GetQueryResults(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	filter := db.QueryResultFilter{
+		Pack:      vars["pack"],
+		QueryName: vars["query"],
+		HostUUID:  r.URL.Query().Get("host_uuid"),
+		Limit:     defaultQueryResultsLimit,
+	}
+
+	if since := r.URL.Query().Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			http.Error(w, "Invalid since time, expected RFC 3339", http.StatusBadRequest)
+			return
+		}
+		filter.Since = t
+	}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		if n > maxQueryResultsLimit {
+			n = maxQueryResultsLimit
+		}
+		filter.Limit = n
+	}
+
+	// Get database instance from context
+	dbInstance, ok := getDB(w, r)
+	if !ok {
+		return
+	}
+
+	results, err := dbInstance.GetQueryResults(filter)
+	if err != nil {
+		http.Error(w, "Error retrieving query results: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := QueryResultsResponse{
+		Pack:    filter.Pack,
+		Query:   filter.QueryName,
+		Results: make([]QueryResultInfo, len(results)),
+	}
+	for i, result := range results {
+		info := QueryResultInfo{
+			HostUUID:    result.HostUUID,
+			Action:      result.Action,
+			CollectedAt: result.CollectedAt.Format("2006-01-02T15:04:05Z07:00"),
+		}
+		json.Unmarshal([]byte(result.Columns), &info.Columns)
+		response.Results[i] = info
+	}
+
+	writeJSON(w, response)
+}
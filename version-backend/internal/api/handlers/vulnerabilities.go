@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"version-backend/internal/db"
+	"version-backend/internal/vuln"
+)
+
+// VulnerabilitiesResponse represents the structure of the response from the /vulnerabilities endpoint
+type VulnerabilitiesResponse struct {
+	Advisories      int                 `json:"advisories"`
+	Sources         []string            `json:"sources"`
+	LoadedAt        string              `json:"loaded_at"`
+	Vulnerabilities []VulnerabilityInfo `json:"vulnerabilities"`
+}
+
+// VulnerabilityInfo represents an advisory affecting software installed on
+// a host in the API response
+type VulnerabilityInfo struct {
+	ID           string       `json:"id"`
+	Aliases      []string     `json:"aliases,omitempty"`
+	Summary      string       `json:"summary,omitempty"`
+	Severity     string       `json:"severity,omitempty"`
+	Score        float64      `json:"score,omitempty"`
+	Source       string       `json:"source"`
+	HostID       int64        `json:"host_id"`
+	HostUUID     string       `json:"host_uuid"`
+	Hostname     string       `json:"hostname"`
+	Affected     AffectedInfo `json:"affected"`
+	FixedVersion string       `json:"fixed_version,omitempty"`
+}
+
+// AffectedInfo represents the app or package affected by an advisory in
+// the API response
+type AffectedInfo struct {
+	Kind             string `json:"kind"`
+	Ecosystem        string `json:"ecosystem,omitempty"`
+	Name             string `json:"name"`
+	BundleIdentifier string `json:"bundle_identifier,omitempty"`
+	Path             string `json:"path,omitempty"`
+	Version          string `json:"version"`
+}
+
+// vulnerabilityKinds lists the kinds accepted by /api/vulnerabilities
+var vulnerabilityKinds = map[string]bool{
+	vuln.KindApp:             true,
+	vuln.KindPackage:         true,
+	vuln.KindLanguagePackage: true,
+}
+
+// GetVulnerabilities returns the handler for the GET /vulnerabilities endpoint
+// It matches the current installed software of every host against the
+// local vulnerability feeds, most severe first. The host_id, kind and
+// severity query parameters restrict the results to a host, a kind of
+// software and a minimum severity.
+func GetVulnerabilities(database *vuln.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hostID, ok := hostIDQuery(w, r)
+		if !ok {
+			return
+		}
+
+		kind := r.URL.Query().Get("kind")
+		if kind != "" && !vulnerabilityKinds[kind] {
+			http.Error(w, "Unknown kind: "+kind, http.StatusBadRequest)
+			return
+		}
+
+		minSeverity := -1
+		if severity := r.URL.Query().Get("severity"); severity != "" {
+			minSeverity = vuln.SeverityRank(severity)
+			if minSeverity < 0 {
+				http.Error(w, "Invalid severity, expected low, medium, high or critical", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Get database instance from context
+		dbInstance, ok := getDB(w, r)
+		if !ok {
+			return
+		}
+
+		inventories, err := dbInstance.GetHostInventories(hostID)
+		if err != nil {
+			if errors.Is(err, db.ErrHostNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Error retrieving installed software: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var findings []vuln.Finding
+		for _, info := range inventories {
+			for _, finding := range database.Match(info) {
+				if kind != "" && finding.Kind != kind {
+					continue
+				}
+				if vuln.SeverityRank(finding.Advisory.Severity) < minSeverity {
+					continue
+				}
+				findings = append(findings, finding)
+			}
+		}
+		vuln.SortFindings(findings)
+
+		response := VulnerabilitiesResponse{
+			Advisories:      database.Advisories(),
+			Sources:         database.Sources(),
+			LoadedAt:        database.LoadedAt().Format("2006-01-02T15:04:05Z07:00"),
+			Vulnerabilities: make([]VulnerabilityInfo, len(findings)),
+		}
+		for i, finding := range findings {
+			response.Vulnerabilities[i] = VulnerabilityInfo{
+				ID:           finding.Advisory.ID,
+				Aliases:      finding.Advisory.Aliases,
+				Summary:      finding.Advisory.Summary,
+				Severity:     strings.ToLower(finding.Advisory.Severity),
+				Score:        finding.Advisory.Score,
+				Source:       finding.Advisory.Source,
+				HostID:       finding.Host.ID,
+				HostUUID:     finding.Host.UUID,
+				Hostname:     finding.Host.Hostname,
+				FixedVersion: finding.FixedVersion,
+				Affected: AffectedInfo{
+					Kind:             finding.Kind,
+					Ecosystem:        finding.Ecosystem,
+					Name:             finding.Name,
+					BundleIdentifier: finding.Identifier,
+					Path:             finding.Path,
+					Version:          finding.Version,
+				},
+			}
+		}
+
+		writeJSON(w, response)
+	}
+}
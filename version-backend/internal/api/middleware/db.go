@@ -11,7 +11,7 @@ import (
 type DBKey struct{}
 
 // WithDB middleware injects the database connection into the request context
-func WithDB(db *db.DB) func(http.Handler) http.Handler {
+func WithDB(db db.Store) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := context.WithValue(r.Context(), DBKey{}, db)
@@ -9,7 +9,15 @@ import (
 
 	"version-backend/internal/api/handlers"
 	"version-backend/internal/api/middleware"
+	"version-backend/internal/config"
 	"version-backend/internal/db"
+	"version-backend/internal/ingest"
+	"version-backend/internal/live"
+	"version-backend/internal/osquery"
+	"version-backend/internal/packs"
+	"version-backend/internal/retention"
+	"version-backend/internal/vuln"
+	"version-backend/pkg/logger"
 
 	"github.com/gorilla/mux"
 )
@@ -18,7 +26,10 @@ import (
 type Router struct {
 	*mux.Router
 	startTime time.Time
-	db        *db.DB
+	db        db.Store
+	ingest    *ingest.Service
+	retention *retention.Job
+	vuln      *vuln.Database
 }
 
 // enableCORS adds CORS middleware to allow frontend requests
@@ -26,8 +37,8 @@ func (r *Router) enableCORS() {
 	r.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept, Authorization")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 			if req.Method == "OPTIONS" {
@@ -41,12 +52,15 @@ func (r *Router) enableCORS() {
 }
 
 // NewRouter creates a new HTTP router with all routes configured
-func NewRouter(db *db.DB) *Router {
+func NewRouter(cfg *config.Config, db db.Store, ingestService *ingest.Service, liveService *live.Service, registry *osquery.Registry, queryPacks []packs.Pack, retentionJob *retention.Job, vulnDB *vuln.Database) *Router {
 	r := mux.NewRouter()
 	router := &Router{
 		Router:    r,
 		startTime: time.Now(),
 		db:        db,
+		ingest:    ingestService,
+		retention: retentionJob,
+		vuln:      vulnDB,
 	}
 
 	// Enable CORS for all routes
@@ -79,10 +93,46 @@ func NewRouter(db *db.DB) *Router {
 
  Available Endpoints:
  -------------------
- GET /api/latest_data  -> Returns system information
+ GET /api/latest_data  -> Returns system information of the latest host
+                         • Host Identity
                          • OS Version
                          • Osquery Version
                          • Installed Applications
+                         • Installed Packages
+                         • ?at= for a past point in time
+ GET /api/packages     -> Returns language packages
+                         • ?ecosystem=pip|npm|gem|go
+ GET /api/extensions   -> Returns browser extensions
+                         • ?include_removed=true
+ GET /api/hosts        -> Returns the inventoried hosts
+ GET /api/hosts/{id}/latest_data
+                       -> Returns system information of one host
+ GET /api/hosts/{id}/query_events
+                       -> Returns rows added or removed per query
+                         • ?query=&since=&limit=
+ GET /api/apps/{bundle_id}/history
+                       -> Returns the install and update history of an app
+                         • ?host_id=
+ GET /api/events       -> Returns app changes across hosts
+                         • ?since=&host_id=&limit=
+ GET /api/diff         -> Compares two snapshots of a host
+                         • ?from=&to=&host_id=
+ GET /api/vulnerabilities
+                       -> Returns advisories affecting installed software
+                         • ?host_id=&kind=&severity=
+ GET /api/packs        -> Returns the scheduled query packs
+ GET /api/packs/{pack}/{query}/results
+                       -> Returns scheduled query results
+                         • ?host_uuid=&since=&limit=
+ POST /api/ingest      -> Accepts snapshots from enrolled agents
+ POST /api/osquery/... -> osquery TLS remote API
+                         • enroll, config, log
+                         • distributed/read, distributed/write
+ /api/admin/...        -> Enroll secret and node key management
+                         • enroll-secrets, nodes
+ POST /api/queries/live -> Runs a live query across hosts
+ GET /api/queries/live/{id}
+                       -> Returns the results of a live query
 
  System Status:
  -------------
@@ -99,6 +149,57 @@ func NewRouter(db *db.DB) *Router {
 	// API routes
 	api := r.PathPrefix("/api").Subrouter()
 	api.HandleFunc("/latest_data", handlers.GetLatestData).Methods(http.MethodGet)
+	api.HandleFunc("/packages", handlers.GetPackages).Methods(http.MethodGet)
+	api.HandleFunc("/extensions", handlers.GetExtensions).Methods(http.MethodGet)
+	api.HandleFunc("/hosts", handlers.ListHosts).Methods(http.MethodGet)
+	api.HandleFunc("/hosts/{id:[0-9]+}/latest_data", handlers.GetHostLatestData).Methods(http.MethodGet)
+	api.HandleFunc("/hosts/{id:[0-9]+}/query_events", handlers.GetHostQueryEvents).Methods(http.MethodGet)
+	api.HandleFunc("/apps/{bundle_id}/history", handlers.GetAppHistory).Methods(http.MethodGet)
+	api.HandleFunc("/events", handlers.GetEvents).Methods(http.MethodGet)
+	api.HandleFunc("/diff", handlers.GetDiff).Methods(http.MethodGet)
+	api.HandleFunc("/packs", handlers.ListPacks(queryPacks)).Methods(http.MethodGet)
+	api.HandleFunc("/packs/{pack}/{query}/results", handlers.GetQueryResults).Methods(http.MethodGet)
+
+	// Vulnerabilities are matched against the feeds loaded at startup
+	if vulnDB != nil {
+		api.HandleFunc("/vulnerabilities", handlers.GetVulnerabilities(vulnDB)).Methods(http.MethodGet)
+	} else {
+		logger.Info("VULN_OSV_DIR and VULN_NVD_FEED not set, vulnerability matching is disabled", nil)
+	}
+
+	// Agents and osqueryd nodes authenticate with the node key they
+	// obtained by enrolling with an enroll secret
+	api.HandleFunc("/ingest", handlers.Ingest(ingestService)).Methods(http.MethodPost)
+
+	interval := time.Duration(cfg.Osquery.QueryInterval) * time.Second
+	remote := handlers.NewOsqueryRemote(interval, registry, ingestService, liveService, queryPacks)
+
+	osqueryAPI := api.PathPrefix("/osquery").Subrouter()
+	osqueryAPI.HandleFunc("/enroll", remote.Enroll).Methods(http.MethodPost)
+	osqueryAPI.HandleFunc("/config", remote.Config).Methods(http.MethodPost)
+	osqueryAPI.HandleFunc("/log", remote.Log).Methods(http.MethodPost)
+	osqueryAPI.HandleFunc("/distributed/read", remote.DistributedRead).Methods(http.MethodPost)
+	osqueryAPI.HandleFunc("/distributed/write", remote.DistributedWrite).Methods(http.MethodPost)
+
+	// Enroll secret and node key management and live queries are only
+	// enabled with an admin token
+	if cfg.Server.AdminToken != "" {
+		admin := api.PathPrefix("/admin").Subrouter()
+		admin.Use(middleware.RequireToken(cfg.Server.AdminToken))
+		admin.HandleFunc("/enroll-secrets", handlers.ListEnrollSecrets).Methods(http.MethodGet)
+		admin.HandleFunc("/enroll-secrets", handlers.CreateEnrollSecret).Methods(http.MethodPost)
+		admin.HandleFunc("/enroll-secrets/{id:[0-9]+}/rotate", handlers.RotateEnrollSecret).Methods(http.MethodPost)
+		admin.HandleFunc("/enroll-secrets/{id:[0-9]+}", handlers.RevokeEnrollSecret).Methods(http.MethodDelete)
+		admin.HandleFunc("/nodes", handlers.ListNodes).Methods(http.MethodGet)
+		admin.HandleFunc("/nodes/{id:[0-9]+}", handlers.RevokeNode).Methods(http.MethodDelete)
+
+		queries := api.PathPrefix("/queries").Subrouter()
+		queries.Use(middleware.RequireToken(cfg.Server.AdminToken))
+		queries.HandleFunc("/live", handlers.RunLiveQuery(liveService)).Methods(http.MethodPost)
+		queries.HandleFunc("/live/{id:[0-9]+}", handlers.GetLiveQuery).Methods(http.MethodGet)
+	} else {
+		logger.Info("ADMIN_TOKEN not set, admin endpoints and live queries are disabled", nil)
+	}
 
 	// Health check - simple endpoint for load balancers
 	r.HandleFunc("/health", router.handleHealth).Methods(http.MethodGet)
@@ -114,6 +215,11 @@ func (r *Router) Run(addr string) error {
 	return http.ListenAndServe(addr, r)
 }
 
+// RunTLS starts the HTTPS server, as required by osqueryd's TLS plugins
+func (r *Router) RunTLS(addr, certFile, keyFile string) error {
+	return http.ListenAndServeTLS(addr, certFile, keyFile, r)
+}
+
 // handleHealth handles the basic health check endpoint
 func (r *Router) handleHealth(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -149,10 +255,9 @@ func (r *Router) handleStatus(w http.ResponseWriter, req *http.Request) {
 	}
 
 	// Get latest system info timestamp
-	var lastUpdate string
-	err := r.db.Get(&lastUpdate, "SELECT updated_at FROM system_info ORDER BY updated_at DESC LIMIT 1")
-	if err != nil {
-		lastUpdate = "no data collected yet"
+	lastUpdate := "no data collected yet"
+	if updatedAt, err := r.db.GetLastDataUpdate(); err == nil {
+		lastUpdate = updatedAt.Format(time.RFC3339)
 	}
 
 	status := map[string]interface{}{
@@ -175,6 +280,9 @@ func (r *Router) handleStatus(w http.ResponseWriter, req *http.Request) {
 			"inUseConnections": r.db.Stats().InUse,
 			"idleConnections":  r.db.Stats().Idle,
 		},
+		"ingestion":       r.ingestionStatus(),
+		"retention":       r.retentionStatus(),
+		"vulnerabilities": r.vulnerabilityStatus(),
 		"build": map[string]interface{}{
 			"goVersion": runtime.Version(),
 			"os":        runtime.GOOS,
@@ -185,3 +293,59 @@ func (r *Router) handleStatus(w http.ResponseWriter, req *http.Request) {
 
 	json.NewEncoder(w).Encode(status)
 }
+
+// ingestionStatus reports the time taken to save snapshots against the
+// latency target
+func (r *Router) ingestionStatus() map[string]interface{} {
+	stats := r.ingest.Stats()
+	var average time.Duration
+	if stats.Snapshots > 0 {
+		average = stats.Total / time.Duration(stats.Snapshots)
+	}
+	return map[string]interface{}{
+		"snapshots":     stats.Snapshots,
+		"unchanged":     stats.Unchanged,
+		"latencyTarget": ingest.LatencyTarget.String(),
+		"slowSnapshots": stats.Slow,
+		"lastLatency":   stats.Last.String(),
+		"avgLatency":    average.String(),
+		"maxLatency":    stats.Max.String(),
+	}
+}
+
+// retentionStatus reports the metrics of the retention job
+func (r *Router) retentionStatus() map[string]interface{} {
+	if r.retention == nil || !r.retention.Enabled() {
+		return map[string]interface{}{"enabled": false}
+	}
+
+	metrics := r.retention.Metrics()
+	lastRun := "not run yet"
+	if !metrics.LastRunAt.IsZero() {
+		lastRun = metrics.LastRunAt.Format(time.RFC3339)
+	}
+	return map[string]interface{}{
+		"enabled":       true,
+		"runs":          metrics.Runs,
+		"failures":      metrics.Failures,
+		"rowsPruned":    metrics.RowsPruned,
+		"rowsCompacted": metrics.RowsCompacted,
+		"lastRun":       lastRun,
+		"lastDuration":  metrics.LastDuration.String(),
+		"lastError":     metrics.LastError,
+	}
+}
+
+// vulnerabilityStatus reports the advisories loaded from the local feeds
+func (r *Router) vulnerabilityStatus() map[string]interface{} {
+	if r.vuln == nil {
+		return map[string]interface{}{"enabled": false}
+	}
+
+	return map[string]interface{}{
+		"enabled":    true,
+		"advisories": r.vuln.Advisories(),
+		"sources":    r.vuln.Sources(),
+		"loadedAt":   r.vuln.LoadedAt().Format(time.RFC3339),
+	}
+}
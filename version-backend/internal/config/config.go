@@ -9,30 +9,67 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Osquery  OsqueryConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Osquery   OsqueryConfig
+	Agent     AgentConfig
+	Retention RetentionConfig
+	Vuln      VulnConfig
 }
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
-	Host string
-	Port string
+	Host         string
+	Port         string
+	AdminToken   string
+	CollectLocal bool
+	EnrollSecret string
+	TLSCertFile  string
+	TLSKeyFile   string
 }
 
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
+	Driver   string
+	Path     string
 	Host     string
 	Port     string
 	User     string
 	Password string
 	DBName   string
+	SSLMode  string
 }
 
 // OsqueryConfig holds osquery configuration
 type OsqueryConfig struct {
 	SocketPath    string
 	QueryInterval int
+	PacksDir      string
+}
+
+// AgentConfig holds configuration for running as a remote collector
+type AgentConfig struct {
+	ServerURL    string
+	EnrollSecret string
+	NodeKeyFile  string
+	PollInterval int
+}
+
+// RetentionConfig holds the retention policy of archived software rows
+type RetentionConfig struct {
+	Days         int
+	MinSnapshots int
+	Compact      bool
+	Interval     int
+}
+
+// VulnConfig holds the local vulnerability feeds matched against the
+// installed software and the mappings the CPE names of apps are inferred
+// with
+type VulnConfig struct {
+	OSVDir         string
+	NVDFeed        string
+	CPEMappingFile string
 }
 
 // Load loads configuration from environment variables
@@ -41,21 +78,54 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	// The default port follows the database driver
+	driver := getEnv("DB_DRIVER", "mysql")
+	defaultPort := "3306"
+	if driver == "postgres" {
+		defaultPort = "5432"
+	}
+
 	return &Config{
 		Server: ServerConfig{
-			Host: getEnv("SERVER_HOST", "localhost"),
-			Port: getEnv("SERVER_PORT", "7070"),
+			Host:         getEnv("SERVER_HOST", "localhost"),
+			Port:         getEnv("SERVER_PORT", "7070"),
+			AdminToken:   getEnv("ADMIN_TOKEN", ""),
+			CollectLocal: getEnvAsBool("COLLECT_LOCAL", true),
+			EnrollSecret: getEnv("OSQUERY_ENROLL_SECRET", ""),
+			TLSCertFile:  getEnv("TLS_CERT_FILE", ""),
+			TLSKeyFile:   getEnv("TLS_KEY_FILE", ""),
 		},
 		Database: DatabaseConfig{
+			Driver:   driver,
+			Path:     getEnv("DB_PATH", "version.db"),
 			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "3306"),
+			Port:     getEnv("DB_PORT", defaultPort),
 			User:     getEnv("DB_USER", "osquery"),
 			Password: getEnv("DB_PASSWORD", "osquery_password"),
 			DBName:   getEnv("DB_NAME", "osquery_data"),
+			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
 		Osquery: OsqueryConfig{
 			SocketPath:    getEnv("OSQUERY_SOCKET", "/var/osquery/osquery.em"),
 			QueryInterval: getEnvAsInt("QUERY_INTERVAL", 300),
+			PacksDir:      getEnv("OSQUERY_PACKS_DIR", "packs"),
+		},
+		Agent: AgentConfig{
+			ServerURL:    getEnv("AGENT_SERVER_URL", "http://localhost:7070"),
+			EnrollSecret: getEnv("AGENT_ENROLL_SECRET", ""),
+			NodeKeyFile:  getEnv("AGENT_NODE_KEY_FILE", "node_key"),
+			PollInterval: getEnvAsInt("AGENT_POLL_INTERVAL", 60),
+		},
+		Retention: RetentionConfig{
+			Days:         getEnvAsInt("RETENTION_DAYS", 0),
+			MinSnapshots: getEnvAsInt("RETENTION_MIN_SNAPSHOTS", 10),
+			Compact:      getEnvAsBool("RETENTION_COMPACT", true),
+			Interval:     getEnvAsInt("RETENTION_INTERVAL", 3600),
+		},
+		Vuln: VulnConfig{
+			OSVDir:         getEnv("VULN_OSV_DIR", ""),
+			NVDFeed:        getEnv("VULN_NVD_FEED", ""),
+			CPEMappingFile: getEnv("CPE_MAPPING_FILE", "cpe_mappings.yaml"),
 		},
 	}, nil
 }
@@ -77,3 +147,13 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvAsBool gets an environment variable as boolean with a default value
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
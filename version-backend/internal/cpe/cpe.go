@@ -0,0 +1,89 @@
+package cpe
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Name holds the fields of a CPE 2.3 name used to identify software. Unset
+// fields are the ANY wildcard when formatted.
+type Name struct {
+	Part     string
+	Vendor   string
+	Product  string
+	Version  string
+	TargetSW string
+}
+
+// Parse parses a CPE 2.3 formatted string such as
+// cpe:2.3:a:google:chrome:*:*:*:*:*:*:*:*, unescaping its fields
+func Parse(s string) (Name, bool) {
+	var fields []string
+	var field strings.Builder
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			field.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ':':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteRune(r)
+		}
+	}
+	fields = append(fields, field.String())
+
+	if len(fields) != 13 || fields[0] != "cpe" || fields[1] != "2.3" {
+		return Name{}, false
+	}
+	return Name{
+		Part:     fields[2],
+		Vendor:   fields[3],
+		Product:  fields[4],
+		Version:  fields[5],
+		TargetSW: fields[10],
+	}, true
+}
+
+// String formats the name as a CPE 2.3 formatted string
+func (n Name) String() string {
+	fields := []string{
+		"cpe", "2.3",
+		formatField(n.Part), formatField(n.Vendor), formatField(n.Product),
+		formatField(n.Version), "*", "*", "*", "*",
+		formatField(n.TargetSW), "*", "*",
+	}
+	return strings.Join(fields, ":")
+}
+
+// formatField escapes a field of a formatted string. Empty fields are the
+// ANY wildcard and a lone hyphen the NA value, both left unescaped.
+func formatField(value string) string {
+	if value == "" || value == "*" {
+		return "*"
+	}
+	if value == "-" {
+		return value
+	}
+
+	var b strings.Builder
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' && r != '.' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Normalize turns a name into the form of CPE vendors and products: lower
+// case, with words joined by underscores
+func Normalize(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '_' || r == '™' || r == '®'
+	}), "_")
+}
@@ -0,0 +1,112 @@
+package cpe
+
+import (
+	"testing"
+)
+
+func TestNameString(t *testing.T) {
+	tests := []struct {
+		name Name
+		want string
+	}{
+		{
+			name: Name{Part: "a", Vendor: "google", Product: "chrome", Version: "120.0.6099.129", TargetSW: "macos"},
+			want: "cpe:2.3:a:google:chrome:120.0.6099.129:*:*:*:*:macos:*:*",
+		},
+		{
+			name: Name{Part: "a", Vendor: "google", Product: "chrome"},
+			want: "cpe:2.3:a:google:chrome:*:*:*:*:*:*:*:*",
+		},
+		{
+			name: Name{Part: "a", Vendor: "example", Product: "app", Version: "-"},
+			want: "cpe:2.3:a:example:app:-:*:*:*:*:*:*:*",
+		},
+		{
+			name: Name{Part: "a", Vendor: "example", Product: "app", Version: "1.0:beta"},
+			want: `cpe:2.3:a:example:app:1.0\:beta:*:*:*:*:*:*:*`,
+		},
+		{
+			name: Name{Part: "a", Vendor: "example", Product: "app", Version: "1.*"},
+			want: `cpe:2.3:a:example:app:1.\*:*:*:*:*:*:*:*`,
+		},
+		{
+			name: Name{Part: "a", Vendor: "example", Product: `back\slash`, Version: "2.0 beta"},
+			want: `cpe:2.3:a:example:back\\slash:2.0\ beta:*:*:*:*:*:*:*`,
+		},
+		{
+			name: Name{Part: "a", Vendor: "example", Product: "c++", Version: "1.0-rc_1"},
+			want: `cpe:2.3:a:example:c\+\+:1.0-rc_1:*:*:*:*:*:*:*`,
+		},
+	}
+
+	for _, tt := range tests {
+		got := tt.name.String()
+		if got != tt.want {
+			t.Errorf("%+v.String() = %s, want %s", tt.name, got, tt.want)
+		}
+
+		// Formatted names parse back to the same fields, with the ANY
+		// wildcard for unset ones
+		parsed, ok := Parse(got)
+		want := tt.name
+		for _, field := range []*string{&want.Part, &want.Vendor, &want.Product, &want.Version, &want.TargetSW} {
+			if *field == "" {
+				*field = "*"
+			}
+		}
+		if !ok || parsed != want {
+			t.Errorf("Parse(%s) = %+v, %v, want %+v", got, parsed, ok, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Name
+		ok   bool
+	}{
+		{
+			s:    "cpe:2.3:a:videolan:vlc_media_player:3.0.20:*:*:*:*:macos:*:*",
+			want: Name{Part: "a", Vendor: "videolan", Product: "vlc_media_player", Version: "3.0.20", TargetSW: "macos"},
+			ok:   true,
+		},
+		{
+			s:    `cpe:2.3:a:example:app:1.0\:beta:*:*:*:*:*:*:*`,
+			want: Name{Part: "a", Vendor: "example", Product: "app", Version: "1.0:beta", TargetSW: "*"},
+			ok:   true,
+		},
+		{s: "cpe:2.3:a:google:chrome", ok: false},
+		{s: "cpe:/a:google:chrome:120.0", ok: false},
+		{s: "cpe:2.2:a:google:chrome:*:*:*:*:*:*:*:*", ok: false},
+		{s: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Parse(tt.s)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v, %v", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Visual Studio Code", "visual_studio_code"},
+		{"VLC", "vlc"},
+		{"  Google   Chrome ", "google_chrome"},
+		{"Foo__Bar", "foo_bar"},
+		{"Acme™ Widget®", "acme_widget"},
+		{"signal-desktop", "signal-desktop"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.name); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
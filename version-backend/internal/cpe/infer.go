@@ -0,0 +1,206 @@
+package cpe
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"version-backend/internal/db/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed mappings.yaml
+var builtinMappings []byte
+
+// Mapping sets the CPE vendor and product of an app. An empty field is
+// inferred like for an unmapped app.
+type Mapping struct {
+	Vendor  string `json:"vendor,omitempty" yaml:"vendor,omitempty"`
+	Product string `json:"product,omitempty" yaml:"product,omitempty"`
+}
+
+// Mappings is the content of a mapping file. Bundles are keyed by bundle
+// identifier, or by a prefix ending in .*, and names by app name.
+type Mappings struct {
+	Bundles map[string]Mapping `json:"bundles" yaml:"bundles"`
+	Names   map[string]Mapping `json:"names" yaml:"names"`
+}
+
+// prefixMapping is a bundle mapping matching every bundle identifier that
+// starts with prefix
+type prefixMapping struct {
+	prefix  string
+	mapping Mapping
+}
+
+// Inferrer derives the CPE names of installed apps
+type Inferrer struct {
+	bundles  map[string]Mapping
+	prefixes []prefixMapping
+	names    map[string]Mapping
+}
+
+// Load creates an inferrer with the built-in mappings, overridden by the
+// mappings of a .json, .yaml or .yml file when path is set. A missing file
+// holds no mappings.
+func Load(path string) (*Inferrer, error) {
+	var builtin Mappings
+	if err := yaml.Unmarshal(builtinMappings, &builtin); err != nil {
+		return nil, fmt.Errorf("error parsing built-in CPE mappings: %w", err)
+	}
+	inferrer := New(builtin)
+
+	if path == "" {
+		return inferrer, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return inferrer, nil
+		}
+		return nil, fmt.Errorf("error reading CPE mappings %s: %w", path, err)
+	}
+
+	var mappings Mappings
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &mappings)
+	default:
+		err = json.Unmarshal(data, &mappings)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CPE mappings %s: %w", path, err)
+	}
+	inferrer.add(mappings)
+
+	return inferrer, nil
+}
+
+// New creates an inferrer with the given mappings only
+func New(mappings Mappings) *Inferrer {
+	inferrer := &Inferrer{
+		bundles: make(map[string]Mapping),
+		names:   make(map[string]Mapping),
+	}
+	inferrer.add(mappings)
+	return inferrer
+}
+
+// add adds mappings, replacing the ones with the same key. Keys are
+// matched case-insensitively.
+func (i *Inferrer) add(mappings Mappings) {
+	for key, mapping := range mappings.Bundles {
+		key = strings.ToLower(key)
+		if prefix, ok := strings.CutSuffix(key, "*"); ok {
+			i.prefixes = append(removePrefix(i.prefixes, prefix), prefixMapping{prefix, mapping})
+			continue
+		}
+		i.bundles[key] = mapping
+	}
+	for key, mapping := range mappings.Names {
+		i.names[strings.ToLower(key)] = mapping
+	}
+
+	// The longest prefix is the most specific one
+	sort.Slice(i.prefixes, func(a, b int) bool {
+		return len(i.prefixes[a].prefix) > len(i.prefixes[b].prefix)
+	})
+}
+
+// removePrefix removes the mapping of a prefix from a list
+func removePrefix(prefixes []prefixMapping, prefix string) []prefixMapping {
+	kept := prefixes[:0]
+	for _, p := range prefixes {
+		if p.prefix != prefix {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// Infer returns the CPE 2.3 name of an app version, or an empty string when
+// the app has no name to derive a product from. Mapped apps take their
+// vendor and product from the mapping. Otherwise the vendor is the
+// organization of the reverse-DNS bundle identifier, such as google for
+// com.google.Chrome, and the product the bundle name, else the name of the
+// .app in the path, without a leading vendor name.
+func (i *Inferrer) Infer(app *models.InstalledApp) string {
+	name := appName(app)
+	mapping := i.lookup(app.BundleIdentifier, name)
+
+	organization := bundleVendor(app.BundleIdentifier)
+	vendor := mapping.Vendor
+	if vendor == "" {
+		vendor = organization
+	}
+
+	product := mapping.Product
+	if product == "" {
+		product = Normalize(name)
+		for _, prefix := range []string{vendor, organization} {
+			if prefix != "" {
+				product = strings.TrimPrefix(product, prefix+"_")
+			}
+		}
+	}
+	if product == "" {
+		return ""
+	}
+	if vendor == "" {
+		vendor = product
+	}
+
+	return Name{
+		Part:     "a",
+		Vendor:   vendor,
+		Product:  product,
+		Version:  app.BundleShortVersion,
+		TargetSW: "macos",
+	}.String()
+}
+
+// lookup returns the mapping of an app: the one of its bundle identifier,
+// else of the longest prefix of it, else of its name
+func (i *Inferrer) lookup(bundleIdentifier, name string) Mapping {
+	if bundleIdentifier != "" {
+		id := strings.ToLower(bundleIdentifier)
+		if mapping, ok := i.bundles[id]; ok {
+			return mapping
+		}
+		for _, p := range i.prefixes {
+			if strings.HasPrefix(id, p.prefix) {
+				return p.mapping
+			}
+		}
+	}
+	return i.names[strings.ToLower(name)]
+}
+
+// appName returns the name of an app: its bundle name, else the name of
+// the .app in its path, else the name osquery reported
+func appName(app *models.InstalledApp) string {
+	if name := strings.TrimSpace(app.BundleName); name != "" {
+		return name
+	}
+	if app.Path != "" {
+		if name := strings.TrimSuffix(filepath.Base(app.Path), ".app"); name != "" && name != "." && name != "/" {
+			return name
+		}
+	}
+	return strings.TrimSuffix(app.Name, ".app")
+}
+
+// bundleVendor returns the organization of a reverse-DNS bundle identifier,
+// the part after the top-level domain
+func bundleVendor(bundleIdentifier string) string {
+	parts := strings.Split(bundleIdentifier, ".")
+	if len(parts) < 3 {
+		return ""
+	}
+	return Normalize(parts[1])
+}
@@ -0,0 +1,193 @@
+package cpe
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"version-backend/internal/db/models"
+)
+
+func TestInfer(t *testing.T) {
+	inferrer := New(Mappings{
+		Bundles: map[string]Mapping{
+			"com.example.Editor":  {Vendor: "exact", Product: "editor_pro"},
+			"com.example.*":       {Vendor: "example_corp"},
+			"com.*":               {Vendor: "generic"},
+			"COM.Acme.Rocket":     {Product: "rocket_launcher"},
+			"org.example.Partial": {Product: "partial_product"},
+		},
+		Names: map[string]Mapping{
+			"Widget": {Vendor: "widgetco", Product: "widget"},
+		},
+	})
+
+	tests := []struct {
+		name string
+		app  models.InstalledApp
+		want string
+	}{
+		{
+			name: "exact bundle identifier before prefixes",
+			app:  models.InstalledApp{BundleIdentifier: "com.example.Editor", BundleName: "Editor", BundleShortVersion: "2.1"},
+			want: "cpe:2.3:a:exact:editor_pro:2.1:*:*:*:*:macos:*:*",
+		},
+		{
+			name: "longest prefix",
+			app:  models.InstalledApp{BundleIdentifier: "com.example.Viewer", BundleName: "Viewer", BundleShortVersion: "1.0"},
+			want: "cpe:2.3:a:example_corp:viewer:1.0:*:*:*:*:macos:*:*",
+		},
+		{
+			name: "shorter prefix",
+			app:  models.InstalledApp{BundleIdentifier: "com.other.Tool", BundleName: "Tool", BundleShortVersion: "1.0"},
+			want: "cpe:2.3:a:generic:tool:1.0:*:*:*:*:macos:*:*",
+		},
+		{
+			name: "case-insensitive bundle identifier",
+			app:  models.InstalledApp{BundleIdentifier: "com.acme.rocket", BundleName: "Rocket", BundleShortVersion: "3.0"},
+			want: "cpe:2.3:a:acme:rocket_launcher:3.0:*:*:*:*:macos:*:*",
+		},
+		{
+			name: "mapping without vendor keeps the inferred one",
+			app:  models.InstalledApp{BundleIdentifier: "org.example.Partial", BundleName: "Partial", BundleShortVersion: "1.0"},
+			want: "cpe:2.3:a:example:partial_product:1.0:*:*:*:*:macos:*:*",
+		},
+		{
+			name: "name mapping for an app without bundle identifier",
+			app:  models.InstalledApp{Name: "Widget.app", Path: "/Applications/Widget.app", BundleShortVersion: "4.2"},
+			want: "cpe:2.3:a:widgetco:widget:4.2:*:*:*:*:macos:*:*",
+		},
+		{
+			name: "name mapping is case-insensitive",
+			app:  models.InstalledApp{BundleName: "WIDGET", BundleShortVersion: "4.2"},
+			want: "cpe:2.3:a:widgetco:widget:4.2:*:*:*:*:macos:*:*",
+		},
+		{
+			name: "unmapped app without bundle identifier",
+			app:  models.InstalledApp{Name: "Some Tool.app", Path: "/Applications/Some Tool.app", BundleShortVersion: "1.0"},
+			want: "cpe:2.3:a:some_tool:some_tool:1.0:*:*:*:*:macos:*:*",
+		},
+		{
+			name: "vendor prefix stripped from the product",
+			app:  models.InstalledApp{BundleIdentifier: "org.mozilla.firefox", BundleName: "Mozilla Firefox", BundleShortVersion: "121.0"},
+			want: "cpe:2.3:a:mozilla:firefox:121.0:*:*:*:*:macos:*:*",
+		},
+		{
+			name: "product from the path without bundle name",
+			app:  models.InstalledApp{BundleIdentifier: "net.vendor.thing", Path: "/Applications/Vendor Thing.app", BundleShortVersion: "5"},
+			want: "cpe:2.3:a:vendor:thing:5:*:*:*:*:macos:*:*",
+		},
+		{
+			name: "version escaped",
+			app:  models.InstalledApp{BundleIdentifier: "net.vendor.thing", BundleName: "Thing", BundleShortVersion: "2.0 (beta:1)"},
+			want: `cpe:2.3:a:vendor:thing:2.0\ \(beta\:1\):*:*:*:*:macos:*:*`,
+		},
+		{
+			name: "no version",
+			app:  models.InstalledApp{BundleIdentifier: "net.vendor.thing", BundleName: "Thing"},
+			want: "cpe:2.3:a:vendor:thing:*:*:*:*:*:macos:*:*",
+		},
+		{
+			name: "no name",
+			app:  models.InstalledApp{BundleIdentifier: "net.vendor.thing"},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inferrer.Infer(&tt.app); got != tt.want {
+				t.Errorf("Infer() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBundleVendor(t *testing.T) {
+	tests := []struct {
+		bundleIdentifier string
+		want             string
+	}{
+		{"com.google.Chrome", "google"},
+		{"com.Apple.Safari", "apple"},
+		{"org.mozilla", ""},
+		{"Chrome", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := bundleVendor(tt.bundleIdentifier); got != tt.want {
+			t.Errorf("bundleVendor(%q) = %q, want %q", tt.bundleIdentifier, got, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"mappings.yaml": `
+bundles:
+  com.google.Chrome:
+    vendor: custom
+    product: browser
+  com.agilebits.*:
+    product: password_manager
+names:
+  Widget:
+    vendor: widgetco
+`,
+		"mappings.json": `{"bundles": {"com.google.Chrome": {"vendor": "custom", "product": "browser"}, "com.agilebits.*": {"product": "password_manager"}}, "names": {"Widget": {"vendor": "widgetco"}}}`,
+	}
+
+	chrome := models.InstalledApp{BundleIdentifier: "com.google.Chrome", BundleName: "Google Chrome", BundleShortVersion: "120.0"}
+	onePassword := models.InstalledApp{BundleIdentifier: "com.agilebits.onepassword7", BundleName: "1Password 7", BundleShortVersion: "7.9"}
+	vscode := models.InstalledApp{BundleIdentifier: "com.microsoft.VSCode", BundleName: "Code", BundleShortVersion: "1.85.0"}
+	widget := models.InstalledApp{Path: "/Applications/Widget.app", BundleShortVersion: "1.0"}
+
+	type loadTest struct {
+		name string
+		path string
+		app  models.InstalledApp
+		want string
+	}
+	tests := []loadTest{
+		{"built-in mapping", "", chrome, "cpe:2.3:a:google:chrome:120.0:*:*:*:*:macos:*:*"},
+		{"built-in prefix", "", onePassword, "cpe:2.3:a:1password:1password:7.9:*:*:*:*:macos:*:*"},
+		{"missing file keeps built-in mappings", filepath.Join(dir, "missing.yaml"), chrome, "cpe:2.3:a:google:chrome:120.0:*:*:*:*:macos:*:*"},
+	}
+	for _, file := range []string{"mappings.yaml", "mappings.json"} {
+		path := filepath.Join(dir, file)
+		tests = append(tests, []loadTest{
+			{file + " overrides a bundle", path, chrome, "cpe:2.3:a:custom:browser:120.0:*:*:*:*:macos:*:*"},
+			{file + " overrides a prefix", path, onePassword, "cpe:2.3:a:agilebits:password_manager:7.9:*:*:*:*:macos:*:*"},
+			{file + " keeps other built-in mappings", path, vscode, "cpe:2.3:a:microsoft:visual_studio_code:1.85.0:*:*:*:*:macos:*:*"},
+			{file + " adds a name", path, widget, "cpe:2.3:a:widgetco:widget:1.0:*:*:*:*:macos:*:*"},
+		}...)
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inferrer, err := Load(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := inferrer.Infer(&tt.app); got != tt.want {
+				t.Errorf("Infer() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(invalid); err == nil {
+		t.Error("Load accepted an invalid mapping file")
+	}
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"version-backend/internal/db/models"
+	"version-backend/pkg/version"
+)
+
+// appEventColumns lists the columns selected for app events
+const appEventColumns = `
+	e.id, e.host_id, h.uuid AS host_uuid, COALESCE(h.hostname, '') AS hostname,
+	e.bundle_identifier, e.name, e.path, e.event_type,
+	COALESCE(e.from_version, '') AS from_version,
+	COALESCE(e.to_version, '') AS to_version, e.created_at
+`
+
+// latestSystemInfoID returns the ID of the latest system information of a
+// host, or zero when the host has none yet
+func latestSystemInfoID(tx *Tx, hostID int64) (int64, error) {
+	var id int64
+	query := `
+		SELECT id FROM system_info
+		WHERE host_id = ?
+		ORDER BY updated_at DESC, id DESC LIMIT 1
+	`
+	if err := tx.Get(&id, query, hostID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("error getting latest system info: %w", err)
+	}
+	return id, nil
+}
+
+// recordAppEvents compares the apps of a new snapshot with the current apps
+// of the previous snapshot and records an event for every app installed,
+// removed, upgraded or downgraded
+func recordAppEvents(tx *Tx, hostID, previousSystemInfoID int64, apps []models.InstalledApp) error {
+	var previousApps []models.InstalledApp
+	query := `
+		SELECT
+			name, path, COALESCE(bundle_identifier, '') AS bundle_identifier,
+			COALESCE(bundle_short_version, '') AS bundle_short_version
+		FROM installed_apps
+		WHERE system_info_id = ? AND end_time IS NULL
+	`
+	if err := tx.Select(&previousApps, query, previousSystemInfoID); err != nil {
+		return fmt.Errorf("error getting previous apps: %w", err)
+	}
+
+	previous := indexApps(previousApps)
+	current := indexApps(apps)
+
+	for key, app := range current {
+		old, existed := previous[key]
+		switch {
+		case !existed:
+			err := insertAppEvent(tx, hostID, key, app, models.AppEventInstalled, "", app.BundleShortVersion)
+			if err != nil {
+				return err
+			}
+		case version.Compare(app.BundleShortVersion, old.BundleShortVersion) > 0:
+			err := insertAppEvent(tx, hostID, key, app, models.AppEventUpgraded, old.BundleShortVersion, app.BundleShortVersion)
+			if err != nil {
+				return err
+			}
+		case version.Compare(app.BundleShortVersion, old.BundleShortVersion) < 0:
+			err := insertAppEvent(tx, hostID, key, app, models.AppEventDowngraded, old.BundleShortVersion, app.BundleShortVersion)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for key, app := range previous {
+		if _, ok := current[key]; !ok {
+			err := insertAppEvent(tx, hostID, key, app, models.AppEventRemoved, app.BundleShortVersion, "")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// indexApps keys apps by bundle identifier, falling back to the path for
+// apps without one. When several copies of an app are installed, the one
+// with the first path is kept.
+func indexApps(apps []models.InstalledApp) map[string]models.InstalledApp {
+	index := make(map[string]models.InstalledApp, len(apps))
+	for _, app := range apps {
+		key := app.BundleIdentifier
+		if key == "" {
+			key = app.Path
+		}
+		if existing, ok := index[key]; ok && existing.Path < app.Path {
+			continue
+		}
+		index[key] = app
+	}
+	return index
+}
+
+// insertAppEvent records a single app event
+func insertAppEvent(tx *Tx, hostID int64, bundleIdentifier string, app models.InstalledApp, eventType, fromVersion, toVersion string) error {
+	query := `
+		INSERT INTO app_events (
+			host_id, bundle_identifier, name, path, event_type,
+			from_version, to_version
+		) VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := tx.Exec(query,
+		hostID,
+		bundleIdentifier,
+		app.Name,
+		app.Path,
+		eventType,
+		nullIfEmpty(fromVersion),
+		nullIfEmpty(toVersion),
+	)
+	if err != nil {
+		return fmt.Errorf("error inserting %s event for %s: %w", eventType, bundleIdentifier, err)
+	}
+	return nil
+}
+
+// nullIfEmpty stores empty strings as NULL
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// GetAppHistory retrieves the events of an app, oldest first. A zero hostID
+// matches every host.
+func (db *DB) GetAppHistory(bundleIdentifier string, hostID int64) ([]models.AppEvent, error) {
+	var events []models.AppEvent
+	query := `SELECT ` + appEventColumns + `
+		FROM app_events e
+		JOIN hosts h ON h.id = e.host_id
+		WHERE e.bundle_identifier = ? AND (? = 0 OR e.host_id = ?)
+		ORDER BY e.created_at, e.id
+	`
+	if err := db.Select(&events, query, bundleIdentifier, hostID, hostID); err != nil {
+		return nil, fmt.Errorf("error getting app history: %w", err)
+	}
+
+	return events, nil
+}
+
+// GetAppEvents retrieves the app events recorded since a time, oldest
+// first. A zero hostID matches every host.
+func (db *DB) GetAppEvents(since time.Time, hostID int64, limit int) ([]models.AppEvent, error) {
+	var events []models.AppEvent
+	query := `SELECT ` + appEventColumns + `
+		FROM app_events e
+		JOIN hosts h ON h.id = e.host_id
+		WHERE e.created_at >= ? AND (? = 0 OR e.host_id = ?)
+		ORDER BY e.created_at, e.id
+		LIMIT ?
+	`
+	if err := db.Select(&events, query, since.UTC(), hostID, hostID, limit); err != nil {
+		return nil, fmt.Errorf("error getting app events: %w", err)
+	}
+
+	return events, nil
+}
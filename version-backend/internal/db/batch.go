@@ -0,0 +1,89 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxBatchParams keeps multi-row statements within the placeholder limits
+// of every database, SQLite's 32766 being the lowest
+const maxBatchParams = 30000
+
+// maxBatchBytes keeps multi-row statements well within MySQL's
+// max_allowed_packet, which is 4 MB on older servers
+const maxBatchBytes = 1 << 20
+
+// deleteBatchSize is the number of rows deleted per statement, well within
+// the placeholder limits of every database
+const deleteBatchSize = 500
+
+// insertRows inserts rows into a table with multi-row INSERT statements
+// instead of a round trip per row. Statements are chunked so none exceeds
+// maxBatchParams placeholders or about maxBatchBytes of values.
+func insertRows(tx *Tx, table string, columns []string, rows [][]interface{}) error {
+	return writeRows(tx, table, columns, rows, "")
+}
+
+// upsertRows inserts rows like insertRows, updating the rows that already
+// exist with the same key instead of failing on them
+func upsertRows(tx *Tx, table, key string, columns, updated []string, rows [][]interface{}) error {
+	return writeRows(tx, table, columns, rows, tx.dialect.upsert(key, updated))
+}
+
+// writeRows runs the chunked multi-row INSERT statements of insertRows and
+// upsertRows, ending each with suffix
+func writeRows(tx *Tx, table string, columns []string, rows [][]interface{}, suffix string) error {
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", table, strings.Join(columns, ", "))
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	maxRows := maxBatchParams / len(columns)
+
+	for start := 0; start < len(rows); {
+		end, size := start, 0
+		for end < len(rows) && end-start < maxRows {
+			rowSize := valuesSize(rows[end])
+			if end > start && size+rowSize > maxBatchBytes {
+				break
+			}
+			size += rowSize
+			end++
+		}
+
+		var query strings.Builder
+		query.WriteString(prefix)
+		args := make([]interface{}, 0, (end-start)*len(columns))
+		for i, row := range rows[start:end] {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteString(placeholders)
+			args = append(args, row...)
+		}
+		if suffix != "" {
+			query.WriteString(" ")
+			query.WriteString(suffix)
+		}
+
+		if _, err := tx.Exec(query.String(), args...); err != nil {
+			return fmt.Errorf("error inserting into %s: %w", table, err)
+		}
+		start = end
+	}
+	return nil
+}
+
+// valuesSize estimates the number of bytes a row of values takes in a
+// statement
+func valuesSize(values []interface{}) int {
+	size := 0
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			size += len(v) + 4
+		case []byte:
+			size += len(v) + 4
+		default:
+			size += 24
+		}
+	}
+	return size
+}
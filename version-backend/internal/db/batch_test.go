@@ -0,0 +1,34 @@
+package db
+
+import (
+	"testing"
+)
+
+// BenchmarkInsertRows measures inserting the rows of benchApps apps with
+// insertRows on SQLite
+func BenchmarkInsertRows(b *testing.B) {
+	db := newTestDB(b)
+	info, _ := testSnapshot("host", nil)
+	if _, err := db.SaveSystemInfo(info, nil); err != nil {
+		b.Fatal(err)
+	}
+	var systemInfoID int64
+	if err := db.Get(&systemInfoID, `SELECT id FROM system_info`); err != nil {
+		b.Fatal(err)
+	}
+	apps := testApps(benchApps, 0, 0)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tx, err := db.Beginx()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := insertApps(tx, systemInfoID, apps); err != nil {
+			b.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Queries are written with ? placeholders. Get, Select and Exec rebind them
+// to the placeholders of the database driver, such as $1 on PostgreSQL.
+
+// Get runs a query returning a single row into dest
+func (db *DB) Get(dest interface{}, query string, args ...interface{}) error {
+	return db.DB.Get(dest, db.Rebind(query), args...)
+}
+
+// Select runs a query returning rows into dest
+func (db *DB) Select(dest interface{}, query string, args ...interface{}) error {
+	return db.DB.Select(dest, db.Rebind(query), args...)
+}
+
+// Exec runs a statement
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.Exec(db.Rebind(query), args...)
+}
+
+// Beginx starts a transaction rebinding its queries like DB
+func (db *DB) Beginx() (*Tx, error) {
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, dialect: db.dialect}, nil
+}
+
+// insert runs an INSERT statement and returns the ID of the inserted row
+func (db *DB) insert(query string, args ...interface{}) (int64, error) {
+	return db.dialect.insert(db.DB, query, args...)
+}
+
+// Tx is a transaction rebinding its queries to the placeholders of the
+// database driver
+type Tx struct {
+	*sqlx.Tx
+	dialect dialect
+}
+
+// Get runs a query returning a single row into dest
+func (tx *Tx) Get(dest interface{}, query string, args ...interface{}) error {
+	return tx.Tx.Get(dest, tx.Rebind(query), args...)
+}
+
+// Select runs a query returning rows into dest
+func (tx *Tx) Select(dest interface{}, query string, args ...interface{}) error {
+	return tx.Tx.Select(dest, tx.Rebind(query), args...)
+}
+
+// Exec runs a statement
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.Exec(tx.Rebind(query), args...)
+}
+
+// insert runs an INSERT statement and returns the ID of the inserted row
+func (tx *Tx) insert(query string, args ...interface{}) (int64, error) {
+	return tx.dialect.insert(tx.Tx, query, args...)
+}
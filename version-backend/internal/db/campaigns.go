@@ -0,0 +1,202 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"version-backend/internal/db/models"
+)
+
+// ErrCampaignNotFound is returned when a live query campaign does not exist
+var ErrCampaignNotFound = errors.New("campaign not found")
+
+// campaignResultColumns lists the columns selected for campaign results
+const campaignResultColumns = `
+	id, campaign_id, node_id, host_uuid, host_identifier, status, row_count,
+	results, error, created_at, sent_at, completed_at
+`
+
+// CreateCampaign stores a live query campaign together with a pending
+// result for each of its target hosts
+func (db *DB) CreateCampaign(query, selector string, targets []models.CampaignResult) (*models.Campaign, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	campaignID, err := tx.insert(`INSERT INTO query_campaigns (query, selector) VALUES (?, ?)`, query, selector)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting campaign: %w", err)
+	}
+
+	insertQuery := `
+		INSERT INTO campaign_results (
+			campaign_id, node_id, host_uuid, host_identifier, status
+		) VALUES (?, ?, ?, ?, ?)
+	`
+	for _, target := range targets {
+		_, err := tx.Exec(insertQuery,
+			campaignID,
+			target.NodeID,
+			target.HostUUID,
+			target.HostIdentifier,
+			models.CampaignStatusPending,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error inserting campaign target: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return db.GetCampaign(campaignID)
+}
+
+// GetCampaign retrieves a live query campaign by ID
+func (db *DB) GetCampaign(id int64) (*models.Campaign, error) {
+	var campaign models.Campaign
+	query := `
+		SELECT id, query, selector, created_at
+		FROM query_campaigns
+		WHERE id = ?
+	`
+	if err := db.Get(&campaign, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, fmt.Errorf("error getting campaign: %w", err)
+	}
+
+	return &campaign, nil
+}
+
+// GetCampaignResults retrieves the per-host results of a campaign
+func (db *DB) GetCampaignResults(campaignID int64) ([]models.CampaignResult, error) {
+	var results []models.CampaignResult
+	query := `SELECT ` + campaignResultColumns + `
+		FROM campaign_results
+		WHERE campaign_id = ?
+		ORDER BY id
+	`
+	if err := db.Select(&results, query, campaignID); err != nil {
+		return nil, fmt.Errorf("error getting campaign results: %w", err)
+	}
+
+	return results, nil
+}
+
+// ClaimPendingQueries returns the live queries waiting for a node and marks
+// them as sent, so each query is handed out only once
+func (db *DB) ClaimPendingQueries(nodeID int64) ([]models.PendingQuery, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var pending []models.PendingQuery
+	query := `
+		SELECT r.campaign_id, c.query
+		FROM campaign_results r
+		JOIN query_campaigns c ON c.id = r.campaign_id
+		WHERE r.node_id = ? AND r.status = ?
+		` + db.dialect.forUpdate()
+	if err := tx.Select(&pending, query, nodeID, models.CampaignStatusPending); err != nil {
+		return nil, fmt.Errorf("error getting pending queries: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	query = `
+		UPDATE campaign_results
+		SET status = ?, sent_at = CURRENT_TIMESTAMP
+		WHERE node_id = ? AND status = ?
+	`
+	if _, err := tx.Exec(query, models.CampaignStatusSent, nodeID, models.CampaignStatusPending); err != nil {
+		return nil, fmt.Errorf("error marking queries as sent: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return pending, nil
+}
+
+// CompleteCampaignResult stores the outcome of a live query on a host. It
+// reports false when the host is not a target of the campaign or has already
+// answered or timed out.
+func (db *DB) CompleteCampaignResult(result *models.CampaignResult) (bool, error) {
+	query := `
+		UPDATE campaign_results
+		SET status = ?, row_count = ?, results = ?, error = ?,
+		    completed_at = CURRENT_TIMESTAMP
+		WHERE campaign_id = ? AND host_uuid = ? AND status IN (?, ?)
+	`
+	res, err := db.Exec(query,
+		result.Status,
+		result.RowCount,
+		result.Results,
+		result.Error,
+		result.CampaignID,
+		result.HostUUID,
+		models.CampaignStatusPending,
+		models.CampaignStatusSent,
+	)
+	if err != nil {
+		return false, fmt.Errorf("error storing campaign result: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting affected rows: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// ExpireCampaign marks the hosts of a campaign that have not answered yet as
+// timed out and returns their results
+func (db *DB) ExpireCampaign(campaignID int64) ([]models.CampaignResult, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE campaign_results
+		SET status = ?, completed_at = CURRENT_TIMESTAMP
+		WHERE campaign_id = ? AND status IN (?, ?)
+	`
+	_, err = tx.Exec(query,
+		models.CampaignStatusTimeout,
+		campaignID,
+		models.CampaignStatusPending,
+		models.CampaignStatusSent,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error expiring campaign: %w", err)
+	}
+
+	var expired []models.CampaignResult
+	query = `SELECT ` + campaignResultColumns + `
+		FROM campaign_results
+		WHERE campaign_id = ? AND status = ?
+		ORDER BY id
+	`
+	if err := tx.Select(&expired, query, campaignID, models.CampaignStatusTimeout); err != nil {
+		return nil, fmt.Errorf("error getting expired targets: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return expired, nil
+}
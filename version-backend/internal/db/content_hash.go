@@ -0,0 +1,117 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"version-backend/internal/db/models"
+	"version-backend/internal/diff"
+)
+
+// snapshotContent is the canonical form of a snapshot hashed by
+// SnapshotHash. Every list is sorted so the order the collectors returned
+// rows in does not change the hash.
+type snapshotContent struct {
+	Host           models.Host
+	OSName         string
+	OSVersion      string
+	OSPlatform     string
+	OsqueryVersion string
+	Apps           [][]string
+	Packages       [][]string
+	LangPackages   [][]string
+	Extensions     [][]string
+	Queries        map[string][]string
+}
+
+// SnapshotHash returns a hash of the data collected in a snapshot: the host
+// identity and hardware, the OS fields, the installed software and the rows
+// of every collector query. IDs and timestamps are left out, so two
+// collections of an unchanged host hash the same and the second can be
+// skipped. The volatile columns of the collectors, such as the last opened
+// time of apps, are left out too: they change whenever an app is opened,
+// so a host would otherwise almost never hash the same. They are updated
+// in place by the next snapshot saved for another change.
+func SnapshotHash(info *models.SystemInfo, queries map[string]QueryRows) string {
+	content := snapshotContent{
+		OSName:         info.OSName,
+		OSVersion:      info.OSVersion,
+		OSPlatform:     info.OSPlatform,
+		OsqueryVersion: info.OsqueryVersion,
+		Apps:           make([][]string, 0, len(info.InstalledApps)),
+		Packages:       make([][]string, 0, len(info.Packages)),
+		LangPackages:   make([][]string, 0, len(info.LangPackages)),
+		Extensions:     make([][]string, 0, len(info.Extensions)),
+		Queries:        make(map[string][]string, len(queries)),
+	}
+
+	if info.Host != nil {
+		content.Host = *info.Host
+		content.Host.ID = 0
+		content.Host.CreatedAt = time.Time{}
+		content.Host.UpdatedAt = time.Time{}
+		content.Host.LastSeenAt = time.Time{}
+	}
+
+	for _, app := range info.InstalledApps {
+		content.Apps = append(content.Apps, []string{
+			app.Path, app.Name, app.BundleIdentifier, app.BundleName,
+			app.BundleShortVersion, app.DisplayName, app.MinimumSystemVersion,
+			app.CPE,
+		})
+	}
+	for _, pkg := range info.Packages {
+		content.Packages = append(content.Packages, []string{
+			pkg.Source, pkg.Name, pkg.Version, pkg.Arch, pkg.Revision,
+			pkg.SourcePackage, strconv.FormatInt(pkg.InstallTime, 10),
+		})
+	}
+	for _, pkg := range info.LangPackages {
+		content.LangPackages = append(content.LangPackages, []string{
+			pkg.Ecosystem, pkg.Name, pkg.Version, pkg.Path, pkg.Username,
+		})
+	}
+	for _, ext := range info.Extensions {
+		content.Extensions = append(content.Extensions, []string{
+			ext.Browser, ext.Username, ext.Identifier, ext.Name,
+			ext.Version, ext.Permissions, ext.ProfilePath, ext.Path,
+		})
+	}
+	sortRows(content.Apps)
+	sortRows(content.Packages)
+	sortRows(content.LangPackages)
+	sortRows(content.Extensions)
+
+	// Rows are identified by the hash the differential engine gives them
+	for name, rows := range queries {
+		index := diff.Index(rows.Rows, rows.Volatile)
+		hashes := make([]string, 0, len(index))
+		for hash := range index {
+			hashes = append(hashes, hash)
+		}
+		sort.Strings(hashes)
+		content.Queries[name] = hashes
+	}
+
+	// encoding/json sorts map keys, so equal snapshots encode identically
+	encoded, _ := json.Marshal(content)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// sortRows sorts rows of values column by column
+func sortRows(rows [][]string) {
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+}
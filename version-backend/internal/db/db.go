@@ -1,22 +1,48 @@
 package db
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"net/url"
+	"time"
 
 	"version-backend/internal/config"
 	"version-backend/internal/db/models"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 )
 
+// ErrNoSystemInfo is returned when no system information has been collected yet
+var ErrNoSystemInfo = errors.New("no system information available yet - waiting for first osquery data collection")
+
 // DB represents the database connection
 type DB struct {
 	*sqlx.DB
+	dialect dialect
 }
 
-// New creates a new database connection
+// New creates a new database connection to MariaDB/MySQL, to PostgreSQL
+// with the postgres driver or, with the sqlite driver, to an embedded SQLite
+// database file
 func New(cfg *config.DatabaseConfig) (*DB, error) {
+	switch cfg.Driver {
+	case "mysql", "":
+		return newMySQL(cfg)
+	case "postgres":
+		return newPostgres(cfg)
+	case "sqlite":
+		return newSQLite(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q, expected mysql, postgres or sqlite", cfg.Driver)
+	}
+}
+
+// newMySQL connects to a MariaDB/MySQL server
+func newMySQL(cfg *config.DatabaseConfig) (*DB, error) {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true",
 		cfg.User,
 		cfg.Password,
@@ -34,25 +60,114 @@ func New(cfg *config.DatabaseConfig) (*DB, error) {
 	db.SetMaxOpenConns(25)
 	db.SetMaxIdleConns(25)
 
-	return &DB{db}, nil
+	return &DB{DB: db, dialect: mysqlDialect{}}, nil
+}
+
+// newPostgres connects to a PostgreSQL server
+func newPostgres(cfg *config.DatabaseConfig) (*DB, error) {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     cfg.Host + ":" + cfg.Port,
+		Path:     cfg.DBName,
+		RawQuery: url.Values{"sslmode": {cfg.SSLMode}}.Encode(),
+	}
+
+	db, err := sqlx.Connect("postgres", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to database: %w", err)
+	}
+
+	// Set connection pool settings
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(25)
+
+	return &DB{DB: db, dialect: postgresDialect{}}, nil
 }
 
-// SaveSystemInfo saves or updates system information in the database
-func (db *DB) SaveSystemInfo(info *models.SystemInfo) error {
+// newSQLite opens an SQLite database file, creating it when missing.
+// Foreign keys are enforced so deletes cascade like on MariaDB, and every
+// transaction takes the write lock up front so concurrent writers wait for
+// each other instead of failing.
+func newSQLite(cfg *config.DatabaseConfig) (*DB, error) {
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=1&_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate", cfg.Path)
+
+	db, err := sqlx.Connect("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+
+	return &DB{DB: db, dialect: sqliteDialect{}}, nil
+}
+
+// SaveSystemInfo saves or updates system information in the database. The
+// raw rows of each collector query are run through the differential engine,
+// and a versioned table only gets a new snapshot when one of the queries
+// stored in it returned added or removed rows. The content hash of the
+// snapshot is stored on its system info, computed with SnapshotHash unless
+// the caller already set it. A snapshot with the same hash as the latest of
+// its host is not written, only the host is marked as seen. It reports
+// whether the snapshot was saved.
+func (db *DB) SaveSystemInfo(info *models.SystemInfo, queries map[string]QueryRows) (bool, error) {
+	if info.ContentHash == "" {
+		info.ContentHash = SnapshotHash(info, queries)
+	}
+
 	tx, err := db.Beginx()
 	if err != nil {
-		return fmt.Errorf("error starting transaction: %w", err)
+		return false, fmt.Errorf("error starting transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	// The host row is locked before comparing, so concurrent saves of a
+	// host compare with each other's snapshot rather than the same one
+	latestHash, err := latestContentHash(tx, info.Host.UUID)
+	if err != nil {
+		return false, err
+	}
+	if latestHash == info.ContentHash {
+		query := `UPDATE hosts SET last_seen_at = CURRENT_TIMESTAMP WHERE uuid = ?`
+		if _, err := tx.Exec(query, info.Host.UUID); err != nil {
+			return false, fmt.Errorf("error touching host: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return false, fmt.Errorf("error committing transaction: %w", err)
+		}
+		return false, nil
+	}
+
+	// Record the host identity
+	hostID, err := upsertHost(tx, info.Host)
+	if err != nil {
+		return false, fmt.Errorf("error saving host: %w", err)
+	}
+
+	// Record the added and removed rows of every query
+	changedTables, err := applyQueryDiffs(tx, info.Host.UUID, queries)
+	if err != nil {
+		return false, err
+	}
+
+	// Browser extensions are synced row by row rather than through the
+	// changed tables, so a snapshot whose extension collectors did not run
+	// must not close every extension of the host
+	extensionsCollected := collected(queries, "browser_extensions")
+
+	// Find the latest snapshot of the host, if any, to compare apps with
+	latestID, err := latestSystemInfoID(tx, hostID)
+	if err != nil {
+		return false, err
+	}
+
 	// First, try to find an existing system info record for this snapshot
 	var existingID int64
 	query := `
 		SELECT id FROM system_info 
-		WHERE os_name = ? AND os_version = ? AND os_platform = ? AND osquery_version = ?
+		WHERE host_id = ? AND os_name = ? AND os_version = ? AND os_platform = ? AND osquery_version = ?
 		ORDER BY created_at DESC LIMIT 1
 	`
 	err = tx.Get(&existingID, query,
+		hostID,
 		info.OSName,
 		info.OSVersion,
 		info.OSPlatform,
@@ -61,126 +176,204 @@ func (db *DB) SaveSystemInfo(info *models.SystemInfo) error {
 
 	var systemInfoID int64
 	if err == nil {
-		// System info exists, check if apps have changed
+		// System info exists, check if any collected data has changed
 		systemInfoID = existingID
 
-		// Get existing apps for comparison
-		var existingApps []models.InstalledApp
-		query = `
-			SELECT name, path, bundle_identifier, bundle_name, 
-			       bundle_short_version, display_name, minimum_system_version, 
-			       last_opened_time
-			FROM installed_apps 
-			WHERE system_info_id = ?
-		`
-		if err := tx.Select(&existingApps, query, systemInfoID); err != nil {
-			return fmt.Errorf("error getting existing apps: %w", err)
+		// The query state follows the latest snapshot of the host, so a
+		// host returning to an older OS version gets a fresh snapshot
+		isLatest := latestID == systemInfoID
+
+		appsChanged := !isLatest || changedTables["installed_apps"]
+		packagesChanged := !isLatest || changedTables["installed_packages"]
+		langPackagesChanged := !isLatest || changedTables["language_packages"]
+
+		// Browser extensions are versioned row by row so each keeps the time
+		// it was added and removed
+		extensionsChanged := false
+		if extensionsCollected {
+			extensionsChanged, err = syncExtensions(tx, systemInfoID, info.Extensions)
+			if err != nil {
+				return false, fmt.Errorf("error syncing browser extensions: %w", err)
+			}
 		}
 
-		// Compare apps and only update if there are changes
-		if appsHaveChanged(existingApps, info.InstalledApps) {
-			// Update system_info timestamp to mark the change
+		// Update system_info timestamp to mark a change, and the hash of
+		// the snapshot in any case. MySQL bumps updated_at on every update
+		// unless it is set explicitly.
+		query = `
+			UPDATE system_info 
+			SET content_hash = ?, updated_at = updated_at
+			WHERE id = ?
+		`
+		if appsChanged || packagesChanged || langPackagesChanged || extensionsChanged {
 			query = `
 				UPDATE system_info 
-				SET updated_at = CURRENT_TIMESTAMP
+				SET updated_at = CURRENT_TIMESTAMP, content_hash = ?
 				WHERE id = ?
 			`
-			if _, err := tx.Exec(query, systemInfoID); err != nil {
-				return fmt.Errorf("error updating system info: %w", err)
+		}
+		if _, err := tx.Exec(query, info.ContentHash, systemInfoID); err != nil {
+			return false, fmt.Errorf("error updating system info: %w", err)
+		}
+
+		if appsChanged {
+			// Record what changed for each app before versioning the rows
+			if err := recordAppEvents(tx, hostID, latestID, info.InstalledApps); err != nil {
+				return false, fmt.Errorf("error recording app events: %w", err)
 			}
 
-			// Archive old apps data with end_time
-			query = `
-				UPDATE installed_apps 
-				SET end_time = CURRENT_TIMESTAMP
-				WHERE system_info_id = ? AND end_time IS NULL
-			`
-			if _, err := tx.Exec(query, systemInfoID); err != nil {
-				return fmt.Errorf("error archiving old apps: %w", err)
+			if isLatest {
+				// Only the apps that changed get a new row
+				if err := syncApps(tx, systemInfoID, info.InstalledApps); err != nil {
+					return false, fmt.Errorf("error syncing apps: %w", err)
+				}
+			} else {
+				// A fresh snapshot makes the system info rank as current
+				// from now on in point-in-time queries
+				if err := archiveSnapshot(tx, "installed_apps", systemInfoID); err != nil {
+					return false, fmt.Errorf("error archiving old apps: %w", err)
+				}
+				if err := insertApps(tx, systemInfoID, info.InstalledApps); err != nil {
+					return false, fmt.Errorf("error inserting new apps: %w", err)
+				}
+			}
+		} else if err := updateApps(tx, systemInfoID, info.InstalledApps); err != nil {
+			return false, fmt.Errorf("error updating apps: %w", err)
+		}
+
+		if packagesChanged {
+			// Archive old packages data with end_time
+			if err := archiveSnapshot(tx, "installed_packages", systemInfoID); err != nil {
+				return false, fmt.Errorf("error archiving old packages: %w", err)
 			}
 
-			// Insert new apps as current snapshot
-			if err := insertApps(tx, systemInfoID, info.InstalledApps); err != nil {
-				return fmt.Errorf("error inserting new apps: %w", err)
+			// Insert new packages as current snapshot
+			if err := insertPackages(tx, systemInfoID, info.Packages); err != nil {
+				return false, fmt.Errorf("error inserting new packages: %w", err)
+			}
+		}
+
+		if langPackagesChanged {
+			// Archive old language packages data with end_time
+			if err := archiveSnapshot(tx, "language_packages", systemInfoID); err != nil {
+				return false, fmt.Errorf("error archiving old language packages: %w", err)
+			}
+
+			// Insert new language packages as current snapshot
+			if err := insertLanguagePackages(tx, systemInfoID, info.LangPackages); err != nil {
+				return false, fmt.Errorf("error inserting new language packages: %w", err)
+			}
+		}
+
+		// The host moved back to this system info, so what was current on
+		// the previous one no longer is
+		if !isLatest && latestID != 0 {
+			if err := archiveSystemInfo(tx, latestID); err != nil {
+				return false, err
 			}
 		}
 	} else {
 		// Insert new system info record
 		query = `
 			INSERT INTO system_info (
-				os_name, os_version, os_platform, osquery_version
-			) VALUES (?, ?, ?, ?)
+				host_id, os_name, os_version, os_platform, osquery_version,
+				content_hash
+			) VALUES (?, ?, ?, ?, ?, ?)
 		`
-		result, err := tx.Exec(query,
+		systemInfoID, err = tx.insert(query,
+			hostID,
 			info.OSName,
 			info.OSVersion,
 			info.OSPlatform,
 			info.OsqueryVersion,
+			info.ContentHash,
 		)
 		if err != nil {
-			return fmt.Errorf("error inserting system info: %w", err)
+			return false, fmt.Errorf("error inserting system info: %w", err)
 		}
 
-		systemInfoID, err = result.LastInsertId()
-		if err != nil {
-			return fmt.Errorf("error getting last insert ID: %w", err)
+		// Record app changes since the previous snapshot of the host, then
+		// close what was current on it
+		if latestID != 0 {
+			if err := recordAppEvents(tx, hostID, latestID, info.InstalledApps); err != nil {
+				return false, fmt.Errorf("error recording app events: %w", err)
+			}
+			if err := archiveSystemInfo(tx, latestID); err != nil {
+				return false, err
+			}
 		}
 
 		// Insert initial apps snapshot
 		if err := insertApps(tx, systemInfoID, info.InstalledApps); err != nil {
-			return fmt.Errorf("error inserting initial apps: %w", err)
+			return false, fmt.Errorf("error inserting initial apps: %w", err)
+		}
+
+		// Insert initial packages snapshot
+		if err := insertPackages(tx, systemInfoID, info.Packages); err != nil {
+			return false, fmt.Errorf("error inserting initial packages: %w", err)
+		}
+
+		// Insert initial language packages snapshot
+		if err := insertLanguagePackages(tx, systemInfoID, info.LangPackages); err != nil {
+			return false, fmt.Errorf("error inserting initial language packages: %w", err)
+		}
+
+		// Insert initial browser extensions
+		if extensionsCollected {
+			if _, err := syncExtensions(tx, systemInfoID, info.Extensions); err != nil {
+				return false, fmt.Errorf("error inserting initial browser extensions: %w", err)
+			}
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("error committing transaction: %w", err)
+		return false, fmt.Errorf("error committing transaction: %w", err)
 	}
 
-	return nil
+	return true, nil
 }
 
-// appsHaveChanged compares two sets of apps to detect changes
-func appsHaveChanged(existing, new []models.InstalledApp) bool {
-	if len(existing) != len(new) {
-		return true
-	}
+// versionedTables lists the tables whose rows are closed with end_time
+// rather than deleted
+var versionedTables = []string{
+	"installed_apps", "installed_packages", "language_packages", "browser_extensions",
+}
 
-	// Create maps for easier comparison
-	existingMap := make(map[string]models.InstalledApp)
-	for _, app := range existing {
-		key := fmt.Sprintf("%s:%s:%s", app.Name, app.Path, app.BundleIdentifier)
-		existingMap[key] = app
-	}
-
-	// Check if any app has changed
-	for _, app := range new {
-		key := fmt.Sprintf("%s:%s:%s", app.Name, app.Path, app.BundleIdentifier)
-		if existing, ok := existingMap[key]; !ok {
-			return true // New app found
-		} else {
-			// Check if any attributes changed
-			if app.BundleName != existing.BundleName ||
-				app.BundleShortVersion != existing.BundleShortVersion ||
-				app.DisplayName != existing.DisplayName ||
-				app.MinimumSystemVersion != existing.MinimumSystemVersion {
-				return true
-			}
+// archiveSystemInfo closes the open rows of every versioned table of a
+// system info, when the host moved to another OS or osquery version
+func archiveSystemInfo(tx *Tx, systemInfoID int64) error {
+	for _, table := range versionedTables {
+		if err := archiveSnapshot(tx, table, systemInfoID); err != nil {
+			return fmt.Errorf("error archiving %s of previous system info: %w", table, err)
 		}
 	}
-	return false
+	return nil
+}
+
+// archiveSnapshot closes the current snapshot of a versioned table by
+// setting end_time on its open rows
+func archiveSnapshot(tx *Tx, table string, systemInfoID int64) error {
+	query := fmt.Sprintf(`
+		UPDATE %s
+		SET end_time = CURRENT_TIMESTAMP
+		WHERE system_info_id = ? AND end_time IS NULL
+	`, table)
+	_, err := tx.Exec(query, systemInfoID)
+	return err
+}
+
+// appColumns lists the columns inserted for installed apps
+var appColumns = []string{
+	"system_info_id", "name", "path", "bundle_identifier",
+	"bundle_name", "bundle_short_version", "display_name",
+	"minimum_system_version", "last_opened_time", "cpe",
 }
 
 // insertApps handles inserting a batch of apps
-func insertApps(tx *sqlx.Tx, systemInfoID int64, apps []models.InstalledApp) error {
-	query := `
-		INSERT INTO installed_apps (
-			system_info_id, name, path, bundle_identifier, 
-			bundle_name, bundle_short_version, display_name,
-			minimum_system_version, last_opened_time
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-	for _, app := range apps {
-		_, err := tx.Exec(query,
+func insertApps(tx *Tx, systemInfoID int64, apps []models.InstalledApp) error {
+	rows := make([][]interface{}, len(apps))
+	for i, app := range apps {
+		rows[i] = []interface{}{
 			systemInfoID,
 			app.Name,
 			app.Path,
@@ -190,46 +383,437 @@ func insertApps(tx *sqlx.Tx, systemInfoID int64, apps []models.InstalledApp) err
 			app.DisplayName,
 			app.MinimumSystemVersion,
 			app.LastOpenedTime,
-		)
-		if err != nil {
-			return fmt.Errorf("error inserting app %s: %w", app.Name, err)
+			app.CPE,
+		}
+	}
+	return insertRows(tx, "installed_apps", appColumns, rows)
+}
+
+// syncApps versions installed apps row by row, keyed by path. Removed or
+// changed apps get their row closed with end_time, while new or changed
+// ones get a new row. Unchanged apps keep their open row, so created_at is
+// when the app was first seen at its version. Only their last opened time
+// is updated in place.
+func syncApps(tx *Tx, systemInfoID int64, apps []models.InstalledApp) error {
+	var existing []models.InstalledApp
+	query := `
+		SELECT 
+			id, system_info_id, name, path, bundle_identifier,
+			bundle_name, bundle_short_version, display_name,
+			minimum_system_version, last_opened_time,
+			COALESCE(cpe, '') AS cpe, created_at, end_time
+		FROM installed_apps
+		WHERE system_info_id = ? AND end_time IS NULL
+	`
+	if err := tx.Select(&existing, query, systemInfoID); err != nil {
+		return fmt.Errorf("error getting existing apps: %w", err)
+	}
+
+	existingMap := make(map[string]models.InstalledApp)
+	for _, app := range existing {
+		existingMap[app.Path] = app
+	}
+
+	// Rows are closed and inserted together so a change shares a single
+	// end_time and takes a statement per batch rather than per app
+	var closed []int64
+	var added []models.InstalledApp
+	current := make(map[string]bool)
+	for _, app := range apps {
+		if current[app.Path] {
+			continue
+		}
+		current[app.Path] = true
+
+		if old, ok := existingMap[app.Path]; ok {
+			if sameAppVersion(old, app) {
+				if old.LastOpenedTime != app.LastOpenedTime || old.CPE != app.CPE {
+					query := `UPDATE installed_apps SET last_opened_time = ?, cpe = ? WHERE id = ?`
+					if _, err := tx.Exec(query, app.LastOpenedTime, app.CPE, old.ID); err != nil {
+						return fmt.Errorf("error updating app %s: %w", app.Name, err)
+					}
+				}
+				continue
+			}
+			// Close the row of the previous version
+			closed = append(closed, old.ID)
+		}
+
+		added = append(added, app)
+	}
+
+	// Close the rows of removed apps
+	for path, old := range existingMap {
+		if !current[path] {
+			closed = append(closed, old.ID)
+		}
+	}
+
+	if err := closeApps(tx, closed); err != nil {
+		return err
+	}
+	return insertApps(tx, systemInfoID, added)
+}
+
+// updateApps updates the last opened time and the CPE of unchanged apps in
+// place on their open rows. Neither changes the app: the last opened time
+// is volatile, and the CPE follows the mappings it was inferred with.
+func updateApps(tx *Tx, systemInfoID int64, apps []models.InstalledApp) error {
+	var existing []models.InstalledApp
+	query := `
+		SELECT id, path, last_opened_time, COALESCE(cpe, '') AS cpe
+		FROM installed_apps
+		WHERE system_info_id = ? AND end_time IS NULL
+	`
+	if err := tx.Select(&existing, query, systemInfoID); err != nil {
+		return err
+	}
+
+	current := make(map[string]models.InstalledApp, len(apps))
+	for _, app := range apps {
+		current[app.Path] = app
+	}
+	for _, old := range existing {
+		app, ok := current[old.Path]
+		if !ok || (app.LastOpenedTime == old.LastOpenedTime && app.CPE == old.CPE) {
+			continue
+		}
+		query := `UPDATE installed_apps SET last_opened_time = ?, cpe = ? WHERE id = ?`
+		if _, err := tx.Exec(query, app.LastOpenedTime, app.CPE, old.ID); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-// GetLatestSystemInfo retrieves the most recent system information
-func (db *DB) GetLatestSystemInfo() (*models.SystemInfo, error) {
-	var info models.SystemInfo
+// sameAppVersion reports whether two rows describe the same version of an
+// app. The last opened time changes without the app changing, so it is
+// left out like in change detection. So is the CPE, which follows the
+// mappings rather than the app.
+func sameAppVersion(a, b models.InstalledApp) bool {
+	return a.Name == b.Name &&
+		a.Path == b.Path &&
+		a.BundleIdentifier == b.BundleIdentifier &&
+		a.BundleName == b.BundleName &&
+		a.BundleShortVersion == b.BundleShortVersion &&
+		a.DisplayName == b.DisplayName &&
+		a.MinimumSystemVersion == b.MinimumSystemVersion
+}
+
+// closeApps sets end_time on installed app rows
+func closeApps(tx *Tx, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query, args, err := sqlx.In(`
+		UPDATE installed_apps
+		SET end_time = CURRENT_TIMESTAMP
+		WHERE id IN (?)
+	`, ids)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("error closing apps: %w", err)
+	}
+	return nil
+}
+
+// insertPackages handles inserting a batch of packages
+func insertPackages(tx *Tx, systemInfoID int64, packages []models.SoftwarePackage) error {
+	columns := []string{"system_info_id", "name", "version", "arch", "source", "revision", "source_package", "install_time"}
+	rows := make([][]interface{}, len(packages))
+	for i, pkg := range packages {
+		rows[i] = []interface{}{
+			systemInfoID,
+			pkg.Name,
+			pkg.Version,
+			pkg.Arch,
+			pkg.Source,
+			pkg.Revision,
+			pkg.SourcePackage,
+			pkg.InstallTime,
+		}
+	}
+	return insertRows(tx, "installed_packages", columns, rows)
+}
+
+// insertLanguagePackages handles inserting a batch of language packages
+func insertLanguagePackages(tx *Tx, systemInfoID int64, packages []models.LanguagePackage) error {
+	columns := []string{"system_info_id", "ecosystem", "name", "version", "path", "username"}
+	rows := make([][]interface{}, len(packages))
+	for i, pkg := range packages {
+		rows[i] = []interface{}{
+			systemInfoID,
+			pkg.Ecosystem,
+			pkg.Name,
+			pkg.Version,
+			pkg.Path,
+			pkg.Username,
+		}
+	}
+	return insertRows(tx, "language_packages", columns, rows)
+}
+
+// extensionKey identifies a browser extension within a user profile
+func extensionKey(ext models.BrowserExtension) string {
+	return fmt.Sprintf("%s:%s:%s:%s", ext.Browser, ext.Username, ext.ProfilePath, ext.Identifier)
+}
 
-	// Get latest system info
+// syncExtensions versions browser extensions row by row. Removed or changed
+// extensions get their row closed with end_time, while new or changed ones
+// get a new row. Unchanged extensions keep their open row, so created_at is
+// when the extension was added. It reports whether anything changed.
+func syncExtensions(tx *Tx, systemInfoID int64, extensions []models.BrowserExtension) (bool, error) {
+	var existing []models.BrowserExtension
 	query := `
-		SELECT 
-			id, os_name, os_version, os_platform, osquery_version,
-			created_at, updated_at
-		FROM system_info
-		ORDER BY updated_at DESC, created_at DESC
-		LIMIT 1
+		SELECT id, browser, username, identifier, name, version,
+		       permissions, profile_path, path
+		FROM browser_extensions
+		WHERE system_info_id = ? AND end_time IS NULL
 	`
-	if err := db.Get(&info, query); err != nil {
-		if err.Error() == "sql: no rows in result set" {
-			return nil, fmt.Errorf("no system information available yet - waiting for first osquery data collection")
+	if err := tx.Select(&existing, query, systemInfoID); err != nil {
+		return false, fmt.Errorf("error getting existing extensions: %w", err)
+	}
+
+	existingMap := make(map[string]models.BrowserExtension)
+	for _, ext := range existing {
+		existingMap[extensionKey(ext)] = ext
+	}
+
+	changed := false
+	var added [][]interface{}
+	current := make(map[string]bool)
+	for _, ext := range extensions {
+		key := extensionKey(ext)
+		current[key] = true
+
+		if old, ok := existingMap[key]; ok {
+			if old.Version == ext.Version && old.Name == ext.Name &&
+				old.Permissions == ext.Permissions && old.Path == ext.Path {
+				continue
+			}
+			// Close the row of the previous version
+			if err := closeExtension(tx, old.ID); err != nil {
+				return false, err
+			}
 		}
-		return nil, fmt.Errorf("error getting system info: %w", err)
+
+		added = append(added, []interface{}{
+			systemInfoID,
+			ext.Browser,
+			ext.Username,
+			ext.Identifier,
+			ext.Name,
+			ext.Version,
+			ext.Permissions,
+			ext.ProfilePath,
+			ext.Path,
+		})
+		changed = true
+	}
+
+	columns := []string{
+		"system_info_id", "browser", "username", "identifier", "name",
+		"version", "permissions", "profile_path", "path",
+	}
+	if err := insertRows(tx, "browser_extensions", columns, added); err != nil {
+		return false, err
+	}
+
+	// Close the rows of removed extensions
+	for key, old := range existingMap {
+		if current[key] {
+			continue
+		}
+		if err := closeExtension(tx, old.ID); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// closeExtension sets end_time on a single browser extension row
+func closeExtension(tx *Tx, id int64) error {
+	query := `
+		UPDATE browser_extensions
+		SET end_time = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+	if _, err := tx.Exec(query, id); err != nil {
+		return fmt.Errorf("error closing extension %d: %w", id, err)
+	}
+	return nil
+}
+
+// GetLatestSystemInfo retrieves the most recent system information across
+// all hosts, with the apps that have a newer version installed elsewhere
+// marked as outdated
+func (db *DB) GetLatestSystemInfo() (*models.SystemInfo, error) {
+	info, err := db.latestSystemInfo(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.loadSystemInfoDetails(info, time.Time{}); err != nil {
+		return nil, err
+	}
+
+	if err := db.markOutdatedApps(info); err != nil {
+		return nil, err
 	}
 
-	// Get active installed apps for this system info
-	query = `
+	return info, nil
+}
+
+// GetHostLatestSystemInfo retrieves the most recent system information of a
+// host, with its outdated apps marked like GetLatestSystemInfo
+func (db *DB) GetHostLatestSystemInfo(hostID int64) (*models.SystemInfo, error) {
+	if _, err := db.GetHost(hostID); err != nil {
+		return nil, err
+	}
+
+	info, err := db.latestSystemInfo(hostID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.loadSystemInfoDetails(info, time.Time{}); err != nil {
+		return nil, err
+	}
+
+	if err := db.markOutdatedApps(info); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// loadSystemInfoDetails loads the host and the installed software of a
+// system information record as it was at a point in time. A zero time loads
+// the active installed software.
+func (db *DB) loadSystemInfoDetails(info *models.SystemInfo, at time.Time) error {
+	// Get the host this system info belongs to
+	host, err := db.GetHost(info.HostID)
+	if err != nil {
+		return err
+	}
+	info.Host = host
+
+	// Rows are active until their end_time, so past rows are selected by
+	// their created_at and end_time range
+	active := "end_time IS NULL"
+	args := []interface{}{info.ID}
+	if !at.IsZero() {
+		active = "created_at <= ? AND (end_time IS NULL OR end_time > ?)"
+		args = append(args, at.UTC(), at.UTC())
+	}
+
+	// Get the installed apps of this system info
+	query := fmt.Sprintf(`
 		SELECT 
 			id, system_info_id, name, path, bundle_identifier,
 			bundle_name, bundle_short_version, display_name,
-			minimum_system_version, last_opened_time, created_at
+			minimum_system_version, last_opened_time,
+			COALESCE(cpe, '') AS cpe, created_at, end_time
 		FROM installed_apps
+		WHERE system_info_id = ? AND %s
+		ORDER BY name, path
+	`, active)
+	if err := db.Select(&info.InstalledApps, query, args...); err != nil {
+		return fmt.Errorf("error getting installed apps: %w", err)
+	}
+
+	// Get the installed packages of this system info
+	query = fmt.Sprintf(`
+		SELECT
+			id, system_info_id, name, version, arch, source,
+			COALESCE(revision, '') AS revision,
+			COALESCE(source_package, '') AS source_package,
+			install_time, created_at, end_time
+		FROM installed_packages
+		WHERE system_info_id = ? AND %s
+		ORDER BY source, name
+	`, active)
+	if err := db.Select(&info.Packages, query, args...); err != nil {
+		return fmt.Errorf("error getting installed packages: %w", err)
+	}
+
+	// Versions are not ordered as strings, so they are sorted here
+	sortSoftware(info)
+
+	return nil
+}
+
+// GetLatestLanguagePackages retrieves the active language packages of the
+// most recent system information, optionally filtered by ecosystem
+func (db *DB) GetLatestLanguagePackages(ecosystem string) (*models.SystemInfo, error) {
+	info, err := db.latestSystemInfo(0)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT
+			id, system_info_id, ecosystem, name, version, path,
+			username, created_at
+		FROM language_packages
 		WHERE system_info_id = ? AND end_time IS NULL
-		ORDER BY last_opened_time DESC
+			AND (? = '' OR ecosystem = ?)
+		ORDER BY ecosystem, name
+	`
+	if err := db.Select(&info.LangPackages, query, info.ID, ecosystem, ecosystem); err != nil {
+		return nil, fmt.Errorf("error getting language packages: %w", err)
+	}
+
+	return info, nil
+}
+
+// GetLatestExtensions retrieves the browser extensions of the most recent
+// system information. Removed extensions are included when includeRemoved is set.
+func (db *DB) GetLatestExtensions(includeRemoved bool) (*models.SystemInfo, error) {
+	info, err := db.latestSystemInfo(0)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT
+			id, system_info_id, browser, username, identifier, name,
+			version, permissions, profile_path, path, created_at, end_time
+		FROM browser_extensions
+		WHERE system_info_id = ? AND (? OR end_time IS NULL)
+		ORDER BY browser, username, identifier, created_at
 	`
-	if err := db.Select(&info.InstalledApps, query, info.ID); err != nil {
-		return nil, fmt.Errorf("error getting installed apps: %w", err)
+	if err := db.Select(&info.Extensions, query, info.ID, includeRemoved); err != nil {
+		return nil, fmt.Errorf("error getting browser extensions: %w", err)
+	}
+
+	return info, nil
+}
+
+// latestSystemInfo retrieves the most recent system information record
+// without any of its installed software. A zero hostID matches any host.
+func (db *DB) latestSystemInfo(hostID int64) (*models.SystemInfo, error) {
+	var info models.SystemInfo
+
+	query := `
+		SELECT 
+			id, host_id, os_name, os_version, os_platform, osquery_version,
+			COALESCE(content_hash, '') AS content_hash, created_at, updated_at
+		FROM system_info
+		WHERE ? = 0 OR host_id = ?
+		ORDER BY updated_at DESC, created_at DESC
+		LIMIT 1
+	`
+	if err := db.Get(&info, query, hostID, hostID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoSystemInfo
+		}
+		return nil, fmt.Errorf("error getting system info: %w", err)
 	}
 
 	return &info, nil
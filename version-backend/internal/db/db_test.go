@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"version-backend/internal/config"
+	"version-backend/internal/db/models"
+)
+
+// benchApps is the number of apps of the hosts saved by the benchmarks,
+// about what a well-used Mac reports
+const benchApps = 400
+
+// newTestDB opens a migrated SQLite database in a temporary directory
+func newTestDB(tb testing.TB) *DB {
+	tb.Helper()
+	db, err := New(&config.DatabaseConfig{
+		Driver: "sqlite",
+		Path:   filepath.Join(tb.TempDir(), "test.db"),
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	if _, err := db.Migrate(context.Background()); err != nil {
+		tb.Fatal(err)
+	}
+	return db
+}
+
+// testApps returns n apps, the first changed of them at a different
+// version than the others
+func testApps(n, changed, release int) []models.InstalledApp {
+	apps := make([]models.InstalledApp, n)
+	for i := range apps {
+		version := "1.0." + strconv.Itoa(i)
+		if i < changed {
+			version += "." + strconv.Itoa(release)
+		}
+		apps[i] = models.InstalledApp{
+			Name:                 fmt.Sprintf("App %d.app", i),
+			Path:                 fmt.Sprintf("/Applications/App %d.app", i),
+			BundleIdentifier:     fmt.Sprintf("com.example.app%d", i),
+			BundleName:           fmt.Sprintf("App %d", i),
+			BundleShortVersion:   version,
+			DisplayName:          fmt.Sprintf("App %d", i),
+			MinimumSystemVersion: "12.0",
+			LastOpenedTime:       1700000000,
+			CPE:                  fmt.Sprintf("cpe:2.3:a:example:app_%d:%s:*:*:*:*:macos:*:*", i, version),
+		}
+	}
+	return apps
+}
+
+// testSnapshot returns the snapshot of a host with its apps and the raw
+// rows of the installed_apps query they were mapped from
+func testSnapshot(hostUUID string, apps []models.InstalledApp) (*models.SystemInfo, map[string]QueryRows) {
+	info := &models.SystemInfo{
+		OSName:         "macOS",
+		OSVersion:      "14.4",
+		OSPlatform:     "darwin",
+		OsqueryVersion: "5.12.1",
+		Host:           &models.Host{UUID: hostUUID, Hostname: hostUUID},
+		InstalledApps:  apps,
+	}
+
+	rows := make([]map[string]string, len(apps))
+	for i, app := range apps {
+		rows[i] = map[string]string{
+			"name":                   app.Name,
+			"path":                   app.Path,
+			"bundle_identifier":      app.BundleIdentifier,
+			"bundle_name":            app.BundleName,
+			"bundle_short_version":   app.BundleShortVersion,
+			"display_name":           app.DisplayName,
+			"minimum_system_version": app.MinimumSystemVersion,
+			"last_opened_time":       strconv.FormatFloat(app.LastOpenedTime, 'f', -1, 64),
+		}
+	}
+	queries := map[string]QueryRows{
+		"installed_apps": {Table: "installed_apps", Rows: rows, Volatile: []string{"last_opened_time"}},
+	}
+	return info, queries
+}
+
+// BenchmarkSaveSystemInfo measures saving a host with benchApps apps on
+// SQLite: its first snapshot, where every app and query row is inserted,
+// and a snapshot where a tenth of its apps were updated.
+func BenchmarkSaveSystemInfo(b *testing.B) {
+	b.Run("new host", func(b *testing.B) {
+		db := newTestDB(b)
+		apps := testApps(benchApps, 0, 0)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			info, queries := testSnapshot("host-"+strconv.Itoa(i), apps)
+			if _, err := db.SaveSystemInfo(info, queries); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("updated apps", func(b *testing.B) {
+		db := newTestDB(b)
+		info, queries := testSnapshot("host", testApps(benchApps, 0, 0))
+		if _, err := db.SaveSystemInfo(info, queries); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			info, queries := testSnapshot("host", testApps(benchApps, benchApps/10, i+1))
+			if _, err := db.SaveSystemInfo(info, queries); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
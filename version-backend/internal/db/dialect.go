@@ -0,0 +1,195 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// dialect holds what differs between the supported databases. Queries are
+// otherwise written in the SQL MariaDB/MySQL, PostgreSQL and SQLite all
+// accept, with ? placeholders rebound to those of the driver.
+type dialect interface {
+	// name identifies the dialect and the directory of its migrations
+	name() string
+
+	// upsert returns the clause that turns an INSERT into an update of the
+	// given columns when a row with the same key exists. The touched
+	// columns are set to the current time.
+	upsert(key string, columns []string, touched ...string) string
+
+	// greatest returns the expression selecting the largest of its arguments
+	greatest(exprs ...string) string
+
+	// forUpdate returns the clause locking the rows selected in a transaction
+	forUpdate() string
+
+	// insert runs an INSERT statement and returns the ID of the inserted row
+	insert(ext sqlx.Ext, query string, args ...interface{}) (int64, error)
+
+	// lockMigrations keeps other processes from migrating the database until
+	// unlockMigrations is called on the same connection
+	lockMigrations(ctx context.Context, conn *sql.Conn) error
+
+	// unlockMigrations releases the migration lock. failed reports whether
+	// the migration failed.
+	unlockMigrations(conn *sql.Conn, failed bool) error
+}
+
+// mysqlDialect is the dialect of MariaDB and MySQL
+type mysqlDialect struct{}
+
+func (mysqlDialect) name() string { return "mysql" }
+
+func (mysqlDialect) upsert(key string, columns []string, touched ...string) string {
+	sets := make([]string, 0, len(columns)+len(touched))
+	for _, column := range columns {
+		sets = append(sets, fmt.Sprintf("%s = VALUES(%s)", column, column))
+	}
+	for _, column := range touched {
+		sets = append(sets, column+" = CURRENT_TIMESTAMP")
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+func (mysqlDialect) greatest(exprs ...string) string {
+	return "GREATEST(" + strings.Join(exprs, ", ") + ")"
+}
+
+func (mysqlDialect) forUpdate() string { return "FOR UPDATE" }
+
+func (mysqlDialect) insert(ext sqlx.Ext, query string, args ...interface{}) (int64, error) {
+	return lastInsertID(ext, query, args...)
+}
+
+func (mysqlDialect) lockMigrations(ctx context.Context, conn *sql.Conn) error {
+	// GET_LOCK returns 1 once the lock is held, 0 on timeout and NULL on
+	// error. Named locks belong to the connection that took them.
+	var locked sql.NullInt64
+	query := `SELECT GET_LOCK(?, ?)`
+	if err := conn.QueryRowContext(ctx, query, migrationLock, migrationLockTimeout).Scan(&locked); err != nil {
+		return fmt.Errorf("error acquiring migration lock: %w", err)
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		return fmt.Errorf("timed out waiting for migration lock %s", migrationLock)
+	}
+	return nil
+}
+
+func (mysqlDialect) unlockMigrations(conn *sql.Conn, failed bool) error {
+	_, err := conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK(?)`, migrationLock)
+	return err
+}
+
+// sqliteDialect is the dialect of the embedded SQLite database
+type sqliteDialect struct{}
+
+func (sqliteDialect) name() string { return "sqlite" }
+
+func (sqliteDialect) upsert(key string, columns []string, touched ...string) string {
+	sets := make([]string, 0, len(columns)+len(touched))
+	for _, column := range columns {
+		sets = append(sets, fmt.Sprintf("%s = excluded.%s", column, column))
+	}
+	for _, column := range touched {
+		sets = append(sets, column+" = CURRENT_TIMESTAMP")
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", key, strings.Join(sets, ", "))
+}
+
+func (sqliteDialect) greatest(exprs ...string) string {
+	// The multi-argument MAX is a scalar function in SQLite
+	return "MAX(" + strings.Join(exprs, ", ") + ")"
+}
+
+func (sqliteDialect) forUpdate() string {
+	// Write transactions lock the whole database, see New
+	return ""
+}
+
+func (sqliteDialect) insert(ext sqlx.Ext, query string, args ...interface{}) (int64, error) {
+	return lastInsertID(ext, query, args...)
+}
+
+func (sqliteDialect) lockMigrations(ctx context.Context, conn *sql.Conn) error {
+	// SQLite runs DDL in transactions, so an immediate transaction both
+	// locks out other writers and makes the migration atomic
+	if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		return fmt.Errorf("error acquiring migration lock: %w", err)
+	}
+	return nil
+}
+
+func (sqliteDialect) unlockMigrations(conn *sql.Conn, failed bool) error {
+	statement := `COMMIT`
+	if failed {
+		statement = `ROLLBACK`
+	}
+	_, err := conn.ExecContext(context.Background(), statement)
+	return err
+}
+
+// postgresDialect is the dialect of PostgreSQL
+type postgresDialect struct{}
+
+func (postgresDialect) name() string { return "postgres" }
+
+func (postgresDialect) upsert(key string, columns []string, touched ...string) string {
+	sets := make([]string, 0, len(columns)+len(touched))
+	for _, column := range columns {
+		sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+	}
+	for _, column := range touched {
+		sets = append(sets, column+" = CURRENT_TIMESTAMP")
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", key, strings.Join(sets, ", "))
+}
+
+func (postgresDialect) greatest(exprs ...string) string {
+	return "GREATEST(" + strings.Join(exprs, ", ") + ")"
+}
+
+func (postgresDialect) forUpdate() string { return "FOR UPDATE" }
+
+func (postgresDialect) insert(ext sqlx.Ext, query string, args ...interface{}) (int64, error) {
+	// The driver does not report the last insert ID, so it is returned by
+	// the statement itself
+	var id int64
+	err := ext.QueryRowx(ext.Rebind(query+" RETURNING id"), args...).Scan(&id)
+	return id, err
+}
+
+func (postgresDialect) lockMigrations(ctx context.Context, conn *sql.Conn) error {
+	// Advisory locks are held by the session until released
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockKey(migrationLock)); err != nil {
+		return fmt.Errorf("error acquiring migration lock: %w", err)
+	}
+	return nil
+}
+
+func (postgresDialect) unlockMigrations(conn *sql.Conn, failed bool) error {
+	_, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, advisoryLockKey(migrationLock))
+	return err
+}
+
+// advisoryLockKey derives the numeric key of a PostgreSQL advisory lock from
+// its name
+func advisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// lastInsertID runs an INSERT statement and returns the ID the driver
+// reports for the inserted row
+func lastInsertID(ext sqlx.Ext, query string, args ...interface{}) (int64, error) {
+	result, err := ext.Exec(ext.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
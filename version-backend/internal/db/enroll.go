@@ -0,0 +1,166 @@
+package db
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"version-backend/internal/db/models"
+)
+
+// ErrInvalidEnrollSecret is returned when an enroll secret is unknown or revoked
+var ErrInvalidEnrollSecret = errors.New("invalid enroll secret")
+
+// ErrEnrollSecretNotFound is returned when an enroll secret does not exist
+var ErrEnrollSecretNotFound = errors.New("enroll secret not found")
+
+// generateKey returns a random hex-encoded key for secrets and node keys
+func generateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating key: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateEnrollSecret creates a new random enroll secret
+func (db *DB) CreateEnrollSecret(name string) (*models.EnrollSecret, error) {
+	secret, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return db.insertEnrollSecret(name, secret)
+}
+
+// EnsureEnrollSecret stores a configured enroll secret unless it already exists
+func (db *DB) EnsureEnrollSecret(name, secret string) error {
+	var count int
+	query := `SELECT COUNT(*) FROM enroll_secrets WHERE secret = ?`
+	if err := db.Get(&count, query, secret); err != nil {
+		return fmt.Errorf("error checking enroll secret: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err := db.insertEnrollSecret(name, secret)
+	return err
+}
+
+// insertEnrollSecret stores an enroll secret and returns it
+func (db *DB) insertEnrollSecret(name, secret string) (*models.EnrollSecret, error) {
+	query := `
+		INSERT INTO enroll_secrets (name, secret)
+		VALUES (?, ?)
+	`
+	id, err := db.insert(query, name, secret)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting enroll secret: %w", err)
+	}
+
+	return db.GetEnrollSecret(id)
+}
+
+// GetEnrollSecret retrieves an enroll secret by ID
+func (db *DB) GetEnrollSecret(id int64) (*models.EnrollSecret, error) {
+	var secret models.EnrollSecret
+	query := `
+		SELECT id, name, secret, created_at, rotated_at, revoked_at
+		FROM enroll_secrets
+		WHERE id = ?
+	`
+	if err := db.Get(&secret, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrEnrollSecretNotFound
+		}
+		return nil, fmt.Errorf("error getting enroll secret: %w", err)
+	}
+
+	return &secret, nil
+}
+
+// ListEnrollSecrets retrieves every enroll secret, including revoked ones
+func (db *DB) ListEnrollSecrets() ([]models.EnrollSecret, error) {
+	var secrets []models.EnrollSecret
+	query := `
+		SELECT id, name, secret, created_at, rotated_at, revoked_at
+		FROM enroll_secrets
+		ORDER BY created_at, id
+	`
+	if err := db.Select(&secrets, query); err != nil {
+		return nil, fmt.Errorf("error listing enroll secrets: %w", err)
+	}
+
+	return secrets, nil
+}
+
+// RotateEnrollSecret replaces the value of an active enroll secret. Nodes
+// enrolled with the previous value keep their node keys.
+func (db *DB) RotateEnrollSecret(id int64) (*models.EnrollSecret, error) {
+	secret, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE enroll_secrets
+		SET secret = ?, rotated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND revoked_at IS NULL
+	`
+	result, err := db.Exec(query, secret, id)
+	if err != nil {
+		return nil, fmt.Errorf("error rotating enroll secret: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("error getting affected rows: %w", err)
+	} else if rows == 0 {
+		return nil, ErrEnrollSecretNotFound
+	}
+
+	return db.GetEnrollSecret(id)
+}
+
+// RevokeEnrollSecret revokes an enroll secret so it can no longer be used to
+// enroll. With revokeNodes, every node key issued with it is revoked too.
+func (db *DB) RevokeEnrollSecret(id int64, revokeNodes bool) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE enroll_secrets
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND revoked_at IS NULL
+	`
+	result, err := tx.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("error revoking enroll secret: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("error getting affected rows: %w", err)
+	} else if rows == 0 {
+		return ErrEnrollSecretNotFound
+	}
+
+	if revokeNodes {
+		query = `
+			UPDATE node_keys
+			SET revoked_at = CURRENT_TIMESTAMP
+			WHERE enroll_secret_id = ? AND revoked_at IS NULL
+		`
+		if _, err := tx.Exec(query, id); err != nil {
+			return fmt.Errorf("error revoking node keys: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
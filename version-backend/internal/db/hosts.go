@@ -0,0 +1,146 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"version-backend/internal/db/models"
+)
+
+// ErrHostNotFound is returned when a host does not exist
+var ErrHostNotFound = errors.New("host not found")
+
+// hostColumns lists the columns of a host recorded from a snapshot
+var hostColumns = []string{
+	"uuid", "hostname", "computer_name", "cpu_brand", "cpu_type",
+	"cpu_physical_cores", "cpu_logical_cores", "physical_memory",
+	"hardware_vendor", "hardware_model", "hardware_serial",
+	"platform_vendor", "platform_version", "platform_date", "platform_revision",
+}
+
+// upsertHost inserts or refreshes a host keyed by its hardware UUID and
+// returns its ID
+func upsertHost(tx *Tx, host *models.Host) (int64, error) {
+	if host == nil || host.UUID == "" {
+		return 0, fmt.Errorf("snapshot has no host identity")
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO hosts (%s)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		%s
+	`, strings.Join(hostColumns, ", "), tx.dialect.upsert("uuid", hostColumns[1:], "updated_at", "last_seen_at"))
+	_, err := tx.Exec(query,
+		host.UUID,
+		host.Hostname,
+		host.ComputerName,
+		host.CPUBrand,
+		host.CPUType,
+		host.CPUPhysicalCores,
+		host.CPULogicalCores,
+		host.PhysicalMemory,
+		host.HardwareVendor,
+		host.HardwareModel,
+		host.HardwareSerial,
+		host.PlatformVendor,
+		host.PlatformVersion,
+		host.PlatformDate,
+		host.PlatformRevision,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	// The last insert ID is not set when an existing host was updated. The
+	// host row stays locked until the transaction ends, so concurrent saves
+	// of the same host are serialized before they diff its query state.
+	var id int64
+	if err := tx.Get(&id, `SELECT id FROM hosts WHERE uuid = ? `+tx.dialect.forUpdate(), host.UUID); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// latestContentHash returns the content hash of the latest snapshot of a
+// host, keyed by its hardware UUID, and locks the host row until the
+// transaction ends. It returns an empty hash when the host is new, has no
+// snapshot yet or its latest one predates content hashes.
+func latestContentHash(tx *Tx, hostUUID string) (string, error) {
+	var hostID int64
+	query := `SELECT id FROM hosts WHERE uuid = ? ` + tx.dialect.forUpdate()
+	if err := tx.Get(&hostID, query, hostUUID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("error locking host: %w", err)
+	}
+
+	var hash string
+	query = `
+		SELECT COALESCE(content_hash, '')
+		FROM system_info
+		WHERE host_id = ?
+		ORDER BY updated_at DESC, id DESC
+		LIMIT 1
+	`
+	if err := tx.Get(&hash, query, hostID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("error getting content hash: %w", err)
+	}
+	return hash, nil
+}
+
+// GetHost retrieves a host by ID
+func (db *DB) GetHost(id int64) (*models.Host, error) {
+	var host models.Host
+	query := `
+		SELECT
+			id, uuid, hostname, computer_name, cpu_brand, cpu_type,
+			cpu_physical_cores, cpu_logical_cores, physical_memory,
+			hardware_vendor, hardware_model, hardware_serial,
+			platform_vendor, platform_version, platform_date, platform_revision,
+			created_at, updated_at, last_seen_at
+		FROM hosts
+		WHERE id = ?
+	`
+	if err := db.Get(&host, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrHostNotFound
+		}
+		return nil, fmt.Errorf("error getting host: %w", err)
+	}
+
+	return &host, nil
+}
+
+// ListHosts retrieves every host together with the OS of its latest system
+// information, including hosts that have not saved a snapshot yet
+func (db *DB) ListHosts() ([]models.HostSummary, error) {
+	var hosts []models.HostSummary
+	query := `
+		SELECT
+			h.id, h.uuid, h.hostname, h.computer_name, h.cpu_brand, h.cpu_type,
+			h.cpu_physical_cores, h.cpu_logical_cores, h.physical_memory,
+			h.hardware_vendor, h.hardware_model, h.hardware_serial,
+			h.platform_vendor, h.platform_version, h.platform_date, h.platform_revision,
+			h.created_at, h.updated_at, h.last_seen_at,
+			s.os_name, s.os_version, s.os_platform, s.osquery_version
+		FROM hosts h
+		LEFT JOIN system_info s ON s.id = (
+			SELECT id FROM system_info
+			WHERE host_id = h.id
+			ORDER BY updated_at DESC, created_at DESC
+			LIMIT 1
+		)
+		ORDER BY h.hostname, h.id
+	`
+	if err := db.Select(&hosts, query); err != nil {
+		return nil, fmt.Errorf("error listing hosts: %w", err)
+	}
+
+	return hosts, nil
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"version-backend/internal/db/models"
+)
+
+// GetHostInventories retrieves the latest system information of every
+// host, or of a single host when hostID is not zero, with its current
+// installed apps, OS packages and language packages. Hosts are ordered by
+// hostname.
+func (db *DB) GetHostInventories(hostID int64) ([]*models.SystemInfo, error) {
+	var hostIDs []int64
+	if hostID != 0 {
+		if _, err := db.GetHost(hostID); err != nil {
+			return nil, err
+		}
+		hostIDs = append(hostIDs, hostID)
+	} else {
+		hosts, err := db.ListHosts()
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range hosts {
+			hostIDs = append(hostIDs, host.ID)
+		}
+	}
+
+	inventories := make([]*models.SystemInfo, 0, len(hostIDs))
+	for _, id := range hostIDs {
+		info, err := db.latestSystemInfo(id)
+		if err != nil {
+			// Hosts that have not reported yet have nothing installed
+			if errors.Is(err, ErrNoSystemInfo) {
+				continue
+			}
+			return nil, err
+		}
+
+		if err := db.loadSystemInfoDetails(info, time.Time{}); err != nil {
+			return nil, err
+		}
+
+		query := `
+			SELECT
+				id, system_info_id, ecosystem, name, version, path,
+				username, created_at
+			FROM language_packages
+			WHERE system_info_id = ? AND end_time IS NULL
+			ORDER BY ecosystem, name
+		`
+		if err := db.Select(&info.LangPackages, query, info.ID); err != nil {
+			return nil, fmt.Errorf("error getting language packages: %w", err)
+		}
+
+		inventories = append(inventories, info)
+	}
+
+	return inventories, nil
+}
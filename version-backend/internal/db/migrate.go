@@ -0,0 +1,379 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationLock is the named lock serializing migrations across replicas
+const migrationLock = "version_backend_schema_migrations"
+
+// migrationLockTimeout is how long to wait for another replica to finish
+// migrating, in seconds
+const migrationLockTimeout = 300
+
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// Migration is a versioned schema change. Migrations are embedded from
+// migrations/<dialect>/<version>_<name>.up.sql and the matching .down.sql
+// file, as every database gets the schema in its own SQL dialect.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is a migration together with the time it was applied, if it was
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrations returns the embedded migrations of a dialect in version order
+func Migrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := migrationFiles.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		file := entry.Name()
+		base, direction := strings.TrimSuffix(file, ".sql"), ""
+		switch {
+		case strings.HasSuffix(base, ".up"):
+			base, direction = strings.TrimSuffix(base, ".up"), "up"
+		case strings.HasSuffix(base, ".down"):
+			base, direction = strings.TrimSuffix(base, ".down"), "down"
+		default:
+			return nil, fmt.Errorf("migration %s is neither .up.sql nor .down.sql", file)
+		}
+
+		prefix, name, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s has no version prefix", file)
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join(dir, file))
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %s: %w", file, err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up migration", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrate applies every pending migration in version order and returns the
+// ones it applied. A database lock makes concurrent replicas wait for each
+// other, so each migration runs exactly once.
+func (db *DB) Migrate(ctx context.Context) ([]Migration, error) {
+	migrations, err := Migrations(db.dialect.name())
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	err = db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		legacy := legacyNone
+		if len(versions) == 0 && db.dialect.name() == "mysql" {
+			if legacy, err = legacySchema(ctx, conn); err != nil {
+				return err
+			}
+		}
+
+		for _, m := range migrations {
+			if _, ok := versions[m.Version]; ok {
+				continue
+			}
+			if m.Version == 1 && legacy == legacyNoHostID {
+				if err := execMigration(ctx, conn, legacyAddHostID); err != nil {
+					return fmt.Errorf("error upgrading scripts/init.sql schema: %w", err)
+				}
+			}
+			if err := execMigration(ctx, conn, m.Up); err != nil {
+				return fmt.Errorf("error applying migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			if m.Version == 1 && legacy != legacyNone {
+				if err := execMigration(ctx, conn, legacyAssignHost); err != nil {
+					return fmt.Errorf("error upgrading scripts/init.sql schema: %w", err)
+				}
+			}
+			query := `INSERT INTO schema_migrations (version, name) VALUES (?, ?)`
+			if _, err := conn.ExecContext(ctx, db.Rebind(query), m.Version, m.Name); err != nil {
+				return fmt.Errorf("error recording migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the given number of most recently applied migrations
+// and returns the ones it reverted
+func (db *DB) MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	migrations, err := Migrations(db.dialect.name())
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	err = db.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			m := migrations[i]
+			if _, ok := versions[m.Version]; !ok {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %d_%s cannot be reverted", m.Version, m.Name)
+			}
+			if err := execMigration(ctx, conn, m.Down); err != nil {
+				return fmt.Errorf("error reverting migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			query := `DELETE FROM schema_migrations WHERE version = ?`
+			if _, err := conn.ExecContext(ctx, db.Rebind(query), m.Version); err != nil {
+				return fmt.Errorf("error recording reverted migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// States of a MariaDB database created by the former scripts/init.sql
+const (
+	legacyNone       = iota // no init.sql schema, or already upgraded
+	legacyNoHostID          // system_info has no host_id column
+	legacyNullHostID        // host_id was added but not assigned yet
+)
+
+// legacyAddHostID adds the host_id column the initial migration expects to
+// a system_info table created by scripts/init.sql. It stays nullable until
+// the initial migration has created the hosts table.
+const legacyAddHostID = `
+ALTER TABLE system_info
+    ADD COLUMN host_id BIGINT NULL AFTER id,
+    ADD INDEX idx_system_info_host_id (host_id, updated_at);
+`
+
+// legacyAssignHost assigns the system_info rows recorded before hosts
+// existed to a placeholder host, then makes host_id required
+const legacyAssignHost = `
+INSERT INTO hosts (uuid, hostname)
+SELECT 'legacy', 'legacy' FROM DUAL
+WHERE EXISTS (SELECT 1 FROM system_info WHERE host_id IS NULL)
+AND NOT EXISTS (SELECT 1 FROM hosts WHERE uuid = 'legacy');
+UPDATE system_info SET host_id = (SELECT id FROM hosts WHERE uuid = 'legacy') WHERE host_id IS NULL;
+ALTER TABLE system_info
+    MODIFY host_id BIGINT NOT NULL,
+    ADD FOREIGN KEY (host_id) REFERENCES hosts(id) ON DELETE CASCADE;
+`
+
+// legacySchema reports whether a MariaDB database without applied
+// migrations was created by scripts/init.sql, whose system_info table has no
+// host_id column, or was left half upgraded by an interrupted upgrade.
+// CREATE TABLE IF NOT EXISTS would otherwise keep that table as is.
+func legacySchema(ctx context.Context, conn *sql.Conn) (int, error) {
+	var nullable string
+	query := `
+		SELECT IS_NULLABLE FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'system_info' AND COLUMN_NAME = 'host_id'
+	`
+	err := conn.QueryRowContext(ctx, query).Scan(&nullable)
+	switch {
+	case err == nil && nullable == "YES":
+		return legacyNullHostID, nil
+	case err == nil:
+		return legacyNone, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return legacyNone, fmt.Errorf("error checking for the scripts/init.sql schema: %w", err)
+	}
+
+	var tables int
+	query = `
+		SELECT COUNT(*) FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'system_info'
+	`
+	if err := conn.QueryRowContext(ctx, query).Scan(&tables); err != nil {
+		return legacyNone, fmt.Errorf("error checking for the scripts/init.sql schema: %w", err)
+	}
+	if tables == 0 {
+		return legacyNone, nil
+	}
+	return legacyNoHostID, nil
+}
+
+// GetMigrationStatus returns every embedded migration with the time it was
+// applied, if it was
+func (db *DB) GetMigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := Migrations(db.dialect.name())
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := createMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	versions, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = MigrationStatus{Migration: m}
+		if appliedAt, ok := versions[m.Version]; ok {
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// withMigrationLock runs fn on a single connection holding the migration
+// lock. Locks belong to the connection that took them, so every statement of
+// the migration must run on that connection.
+func (db *DB) withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := db.dialect.lockMigrations(ctx, conn); err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := db.dialect.unlockMigrations(conn, err != nil); unlockErr != nil && err == nil {
+			err = fmt.Errorf("error releasing migration lock: %w", unlockErr)
+		}
+	}()
+
+	if err := createMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// createMigrationsTable creates the table tracking applied migrations
+func createMigrationsTable(ctx context.Context, conn *sql.Conn) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("error creating schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// appliedMigrations returns the applied migration versions with the time
+// each was applied
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("error getting applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("error scanning applied migration: %w", err)
+		}
+		versions[version] = appliedAt
+	}
+	return versions, rows.Err()
+}
+
+// execMigration runs the statements of a migration one by one, as the
+// driver does not accept several statements in a single call. MySQL commits
+// DDL implicitly, so statements are idempotent where possible instead of
+// relying on a transaction.
+func execMigration(ctx context.Context, conn *sql.Conn, content string) error {
+	for _, statement := range splitStatements(content) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("%w\n%s", err, statement)
+		}
+	}
+	return nil
+}
+
+// splitStatements splits migration SQL on the semicolons ending its lines,
+// dropping -- comment lines
+func splitStatements(content string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
@@ -0,0 +1,15 @@
+-- Drop every table of the initial schema, dependents first
+DROP TABLE IF EXISTS app_events;
+DROP TABLE IF EXISTS query_events;
+DROP TABLE IF EXISTS query_state;
+DROP TABLE IF EXISTS query_results;
+DROP TABLE IF EXISTS campaign_results;
+DROP TABLE IF EXISTS query_campaigns;
+DROP TABLE IF EXISTS node_keys;
+DROP TABLE IF EXISTS enroll_secrets;
+DROP TABLE IF EXISTS browser_extensions;
+DROP TABLE IF EXISTS language_packages;
+DROP TABLE IF EXISTS installed_packages;
+DROP TABLE IF EXISTS installed_apps;
+DROP TABLE IF EXISTS system_info;
+DROP TABLE IF EXISTS hosts;
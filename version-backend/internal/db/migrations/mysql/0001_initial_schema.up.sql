@@ -0,0 +1,212 @@
+-- Initial schema. Statements are idempotent so databases created by the
+-- former scripts/init.sql adopt migrations; their system_info table gets
+-- its host_id column before this runs, see legacySchema in migrate.go.
+-- Indexes are declared with their tables, as MySQL has no CREATE INDEX IF
+-- NOT EXISTS.
+
+-- Create tables for storing system information
+CREATE TABLE IF NOT EXISTS hosts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    uuid VARCHAR(255) NOT NULL UNIQUE,
+    hostname VARCHAR(255),
+    computer_name VARCHAR(255),
+    cpu_brand VARCHAR(255),
+    cpu_type VARCHAR(100),
+    cpu_physical_cores INT,
+    cpu_logical_cores INT,
+    physical_memory BIGINT,
+    hardware_vendor VARCHAR(255),
+    hardware_model VARCHAR(255),
+    hardware_serial VARCHAR(255),
+    platform_vendor VARCHAR(255),
+    platform_version VARCHAR(255),
+    platform_date VARCHAR(100),
+    platform_revision VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS system_info (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    host_id BIGINT NOT NULL,
+    os_name VARCHAR(255) NOT NULL,
+    os_version VARCHAR(255) NOT NULL,
+    os_platform VARCHAR(255) NOT NULL,
+    osquery_version VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (host_id) REFERENCES hosts(id) ON DELETE CASCADE,
+    INDEX idx_system_info_created_at (created_at),
+    INDEX idx_system_info_host_id (host_id, updated_at)
+);
+
+CREATE TABLE IF NOT EXISTS installed_apps (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    system_info_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    path VARCHAR(512) NOT NULL,
+    bundle_identifier VARCHAR(255),
+    bundle_name VARCHAR(255),
+    bundle_short_version VARCHAR(100),
+    display_name VARCHAR(255),
+    minimum_system_version VARCHAR(50),
+    last_opened_time DOUBLE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    end_time TIMESTAMP NULL DEFAULT NULL,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE,
+    INDEX idx_installed_apps_system_info_id (system_info_id),
+    INDEX idx_installed_apps_bundle_identifier (bundle_identifier),
+    INDEX idx_installed_apps_end_time (end_time)
+);
+
+CREATE TABLE IF NOT EXISTS installed_packages (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    system_info_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    version VARCHAR(255) NOT NULL,
+    arch VARCHAR(50),
+    source VARCHAR(50) NOT NULL,
+    install_time BIGINT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    end_time TIMESTAMP NULL DEFAULT NULL,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE,
+    INDEX idx_installed_packages_system_info_id (system_info_id),
+    INDEX idx_installed_packages_name (name),
+    INDEX idx_installed_packages_end_time (end_time)
+);
+
+CREATE TABLE IF NOT EXISTS language_packages (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    system_info_id BIGINT NOT NULL,
+    ecosystem VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    version VARCHAR(255) NOT NULL,
+    path VARCHAR(1024) NOT NULL,
+    username VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    end_time TIMESTAMP NULL DEFAULT NULL,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE,
+    INDEX idx_language_packages_system_info_id (system_info_id),
+    INDEX idx_language_packages_ecosystem (ecosystem),
+    INDEX idx_language_packages_end_time (end_time)
+);
+
+CREATE TABLE IF NOT EXISTS browser_extensions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    system_info_id BIGINT NOT NULL,
+    browser VARCHAR(50) NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    identifier VARCHAR(255) NOT NULL,
+    name VARCHAR(255),
+    version VARCHAR(100),
+    permissions TEXT,
+    profile_path VARCHAR(1024),
+    path VARCHAR(1024),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    end_time TIMESTAMP NULL DEFAULT NULL,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE,
+    INDEX idx_browser_extensions_system_info_id (system_info_id),
+    INDEX idx_browser_extensions_identifier (identifier),
+    INDEX idx_browser_extensions_end_time (end_time)
+);
+
+CREATE TABLE IF NOT EXISTS enroll_secrets (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    secret VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    rotated_at TIMESTAMP NULL DEFAULT NULL,
+    revoked_at TIMESTAMP NULL DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS node_keys (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    node_key VARCHAR(64) NOT NULL UNIQUE,
+    enroll_secret_id BIGINT,
+    host_identifier VARCHAR(255) NOT NULL,
+    host_uuid VARCHAR(255),
+    platform VARCHAR(255),
+    platform_like VARCHAR(255),
+    collect_requested_at TIMESTAMP NULL DEFAULT NULL,
+    last_collected_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP NULL DEFAULT NULL,
+    FOREIGN KEY (enroll_secret_id) REFERENCES enroll_secrets(id) ON DELETE SET NULL,
+    INDEX idx_node_keys_host_uuid (host_uuid)
+);
+
+CREATE TABLE IF NOT EXISTS query_campaigns (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    query TEXT NOT NULL,
+    selector TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS campaign_results (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    campaign_id BIGINT NOT NULL,
+    node_id BIGINT,
+    host_uuid VARCHAR(255) NOT NULL,
+    host_identifier VARCHAR(255),
+    status VARCHAR(32) NOT NULL,
+    row_count INT NOT NULL DEFAULT 0,
+    results LONGTEXT,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP NULL DEFAULT NULL,
+    completed_at TIMESTAMP NULL DEFAULT NULL,
+    UNIQUE KEY uk_campaign_results_host (campaign_id, host_uuid),
+    FOREIGN KEY (campaign_id) REFERENCES query_campaigns(id) ON DELETE CASCADE,
+    FOREIGN KEY (node_id) REFERENCES node_keys(id) ON DELETE SET NULL,
+    INDEX idx_campaign_results_node_status (node_id, status)
+);
+
+CREATE TABLE IF NOT EXISTS query_results (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    host_uuid VARCHAR(255) NOT NULL,
+    pack VARCHAR(255) NOT NULL,
+    query_name VARCHAR(255) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    columns LONGTEXT NOT NULL,
+    collected_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_query_results_query (pack, query_name, collected_at),
+    INDEX idx_query_results_host_uuid (host_uuid)
+);
+
+CREATE TABLE IF NOT EXISTS query_state (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    host_uuid VARCHAR(255) NOT NULL,
+    query_name VARCHAR(255) NOT NULL,
+    row_hash CHAR(64) NOT NULL,
+    columns LONGTEXT NOT NULL,
+    UNIQUE KEY uk_query_state_row (host_uuid, query_name, row_hash)
+);
+
+CREATE TABLE IF NOT EXISTS query_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    host_uuid VARCHAR(255) NOT NULL,
+    query_name VARCHAR(255) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    row_hash CHAR(64) NOT NULL,
+    columns LONGTEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_query_events_host (host_uuid, created_at)
+);
+
+CREATE TABLE IF NOT EXISTS app_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    host_id BIGINT NOT NULL,
+    bundle_identifier VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    path VARCHAR(512) NOT NULL,
+    event_type VARCHAR(16) NOT NULL,
+    from_version VARCHAR(100),
+    to_version VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (host_id) REFERENCES hosts(id) ON DELETE CASCADE,
+    INDEX idx_app_events_bundle_identifier (bundle_identifier, created_at),
+    INDEX idx_app_events_created_at (created_at)
+);
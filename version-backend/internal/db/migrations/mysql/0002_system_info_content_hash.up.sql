@@ -0,0 +1,3 @@
+-- Hash of the collected data of the latest snapshot saved to a system info,
+-- used to skip saving identical snapshots
+ALTER TABLE system_info ADD COLUMN content_hash CHAR(64) NULL;
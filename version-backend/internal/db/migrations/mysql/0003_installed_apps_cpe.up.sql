@@ -0,0 +1,2 @@
+-- CPE 2.3 name inferred for an installed app, matched with NVD feeds
+ALTER TABLE installed_apps ADD COLUMN cpe VARCHAR(512) NULL;
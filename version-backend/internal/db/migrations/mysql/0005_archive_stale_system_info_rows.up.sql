@@ -0,0 +1,58 @@
+-- Close the rows left open on system infos a host has since moved away
+-- from, at the time the next system info of the host was created
+UPDATE installed_apps
+SET end_time = COALESCE((
+    SELECT MIN(s2.created_at)
+    FROM system_info s1
+    JOIN system_info s2 ON s2.host_id = s1.host_id AND s2.id > s1.id
+    WHERE s1.id = installed_apps.system_info_id
+), CURRENT_TIMESTAMP)
+WHERE end_time IS NULL AND EXISTS (
+    SELECT 1
+    FROM system_info s1
+    JOIN system_info s2 ON s2.host_id = s1.host_id
+    WHERE s1.id = installed_apps.system_info_id
+    AND (s2.updated_at > s1.updated_at OR (s2.updated_at = s1.updated_at AND s2.id > s1.id))
+);
+UPDATE installed_packages
+SET end_time = COALESCE((
+    SELECT MIN(s2.created_at)
+    FROM system_info s1
+    JOIN system_info s2 ON s2.host_id = s1.host_id AND s2.id > s1.id
+    WHERE s1.id = installed_packages.system_info_id
+), CURRENT_TIMESTAMP)
+WHERE end_time IS NULL AND EXISTS (
+    SELECT 1
+    FROM system_info s1
+    JOIN system_info s2 ON s2.host_id = s1.host_id
+    WHERE s1.id = installed_packages.system_info_id
+    AND (s2.updated_at > s1.updated_at OR (s2.updated_at = s1.updated_at AND s2.id > s1.id))
+);
+UPDATE language_packages
+SET end_time = COALESCE((
+    SELECT MIN(s2.created_at)
+    FROM system_info s1
+    JOIN system_info s2 ON s2.host_id = s1.host_id AND s2.id > s1.id
+    WHERE s1.id = language_packages.system_info_id
+), CURRENT_TIMESTAMP)
+WHERE end_time IS NULL AND EXISTS (
+    SELECT 1
+    FROM system_info s1
+    JOIN system_info s2 ON s2.host_id = s1.host_id
+    WHERE s1.id = language_packages.system_info_id
+    AND (s2.updated_at > s1.updated_at OR (s2.updated_at = s1.updated_at AND s2.id > s1.id))
+);
+UPDATE browser_extensions
+SET end_time = COALESCE((
+    SELECT MIN(s2.created_at)
+    FROM system_info s1
+    JOIN system_info s2 ON s2.host_id = s1.host_id AND s2.id > s1.id
+    WHERE s1.id = browser_extensions.system_info_id
+), CURRENT_TIMESTAMP)
+WHERE end_time IS NULL AND EXISTS (
+    SELECT 1
+    FROM system_info s1
+    JOIN system_info s2 ON s2.host_id = s1.host_id
+    WHERE s1.id = browser_extensions.system_info_id
+    AND (s2.updated_at > s1.updated_at OR (s2.updated_at = s1.updated_at AND s2.id > s1.id))
+);
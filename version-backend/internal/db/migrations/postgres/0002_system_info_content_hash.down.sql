@@ -0,0 +1 @@
+ALTER TABLE system_info DROP COLUMN content_hash;
@@ -0,0 +1 @@
+ALTER TABLE installed_apps DROP COLUMN cpe;
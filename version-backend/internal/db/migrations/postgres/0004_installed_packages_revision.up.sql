@@ -0,0 +1,4 @@
+-- Snap revision or flatpak branch of a package. Neither is a version, so
+-- the ones formerly stored as the version are moved here.
+ALTER TABLE installed_packages ADD COLUMN revision VARCHAR(100) NULL;
+UPDATE installed_packages SET revision = version, version = '' WHERE source IN ('snap', 'flatpak');
@@ -0,0 +1,2 @@
+-- Closed rows cannot be told apart from the ones closed by a change, so
+-- they are left closed
@@ -0,0 +1 @@
+ALTER TABLE installed_packages DROP COLUMN source_package;
@@ -0,0 +1,211 @@
+-- Initial schema in the SQLite dialect, matching the MariaDB schema. Column
+-- sizes are kept for reference; SQLite does not enforce them.
+
+-- Create tables for storing system information
+CREATE TABLE IF NOT EXISTS hosts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid VARCHAR(255) NOT NULL UNIQUE,
+    hostname VARCHAR(255),
+    computer_name VARCHAR(255),
+    cpu_brand VARCHAR(255),
+    cpu_type VARCHAR(100),
+    cpu_physical_cores INT,
+    cpu_logical_cores INT,
+    physical_memory BIGINT,
+    hardware_vendor VARCHAR(255),
+    hardware_model VARCHAR(255),
+    hardware_serial VARCHAR(255),
+    platform_vendor VARCHAR(255),
+    platform_version VARCHAR(255),
+    platform_date VARCHAR(100),
+    platform_revision VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS system_info (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    host_id BIGINT NOT NULL,
+    os_name VARCHAR(255) NOT NULL,
+    os_version VARCHAR(255) NOT NULL,
+    os_platform VARCHAR(255) NOT NULL,
+    osquery_version VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (host_id) REFERENCES hosts(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS installed_apps (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    system_info_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    path VARCHAR(512) NOT NULL,
+    bundle_identifier VARCHAR(255),
+    bundle_name VARCHAR(255),
+    bundle_short_version VARCHAR(100),
+    display_name VARCHAR(255),
+    minimum_system_version VARCHAR(50),
+    last_opened_time DOUBLE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    end_time TIMESTAMP NULL DEFAULT NULL,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS installed_packages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    system_info_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    version VARCHAR(255) NOT NULL,
+    arch VARCHAR(50),
+    source VARCHAR(50) NOT NULL,
+    install_time BIGINT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    end_time TIMESTAMP NULL DEFAULT NULL,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS language_packages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    system_info_id BIGINT NOT NULL,
+    ecosystem VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    version VARCHAR(255) NOT NULL,
+    path VARCHAR(1024) NOT NULL,
+    username VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    end_time TIMESTAMP NULL DEFAULT NULL,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS browser_extensions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    system_info_id BIGINT NOT NULL,
+    browser VARCHAR(50) NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    identifier VARCHAR(255) NOT NULL,
+    name VARCHAR(255),
+    version VARCHAR(100),
+    permissions TEXT,
+    profile_path VARCHAR(1024),
+    path VARCHAR(1024),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    end_time TIMESTAMP NULL DEFAULT NULL,
+    FOREIGN KEY (system_info_id) REFERENCES system_info(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS enroll_secrets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    secret VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    rotated_at TIMESTAMP NULL DEFAULT NULL,
+    revoked_at TIMESTAMP NULL DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS node_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    node_key VARCHAR(64) NOT NULL UNIQUE,
+    enroll_secret_id BIGINT,
+    host_identifier VARCHAR(255) NOT NULL,
+    host_uuid VARCHAR(255),
+    platform VARCHAR(255),
+    platform_like VARCHAR(255),
+    collect_requested_at TIMESTAMP NULL DEFAULT NULL,
+    last_collected_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP NULL DEFAULT NULL,
+    FOREIGN KEY (enroll_secret_id) REFERENCES enroll_secrets(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS query_campaigns (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    query TEXT NOT NULL,
+    selector TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS campaign_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    campaign_id BIGINT NOT NULL,
+    node_id BIGINT,
+    host_uuid VARCHAR(255) NOT NULL,
+    host_identifier VARCHAR(255),
+    status VARCHAR(32) NOT NULL,
+    row_count INT NOT NULL DEFAULT 0,
+    results TEXT,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP NULL DEFAULT NULL,
+    completed_at TIMESTAMP NULL DEFAULT NULL,
+    UNIQUE (campaign_id, host_uuid),
+    FOREIGN KEY (campaign_id) REFERENCES query_campaigns(id) ON DELETE CASCADE,
+    FOREIGN KEY (node_id) REFERENCES node_keys(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS query_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    host_uuid VARCHAR(255) NOT NULL,
+    pack VARCHAR(255) NOT NULL,
+    query_name VARCHAR(255) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    columns TEXT NOT NULL,
+    collected_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS query_state (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    host_uuid VARCHAR(255) NOT NULL,
+    query_name VARCHAR(255) NOT NULL,
+    row_hash CHAR(64) NOT NULL,
+    columns TEXT NOT NULL,
+    UNIQUE (host_uuid, query_name, row_hash)
+);
+
+CREATE TABLE IF NOT EXISTS query_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    host_uuid VARCHAR(255) NOT NULL,
+    query_name VARCHAR(255) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    row_hash CHAR(64) NOT NULL,
+    columns TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS app_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    host_id BIGINT NOT NULL,
+    bundle_identifier VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    path VARCHAR(512) NOT NULL,
+    event_type VARCHAR(16) NOT NULL,
+    from_version VARCHAR(100),
+    to_version VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (host_id) REFERENCES hosts(id) ON DELETE CASCADE
+);
+
+-- Indexes for better query performance
+CREATE INDEX IF NOT EXISTS idx_system_info_created_at ON system_info(created_at);
+CREATE INDEX IF NOT EXISTS idx_system_info_host_id ON system_info(host_id, updated_at);
+CREATE INDEX IF NOT EXISTS idx_installed_apps_system_info_id ON installed_apps(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_installed_apps_bundle_identifier ON installed_apps(bundle_identifier);
+CREATE INDEX IF NOT EXISTS idx_installed_apps_end_time ON installed_apps(end_time);
+CREATE INDEX IF NOT EXISTS idx_installed_packages_system_info_id ON installed_packages(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_installed_packages_name ON installed_packages(name);
+CREATE INDEX IF NOT EXISTS idx_installed_packages_end_time ON installed_packages(end_time);
+CREATE INDEX IF NOT EXISTS idx_language_packages_system_info_id ON language_packages(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_language_packages_ecosystem ON language_packages(ecosystem);
+CREATE INDEX IF NOT EXISTS idx_language_packages_end_time ON language_packages(end_time);
+CREATE INDEX IF NOT EXISTS idx_browser_extensions_system_info_id ON browser_extensions(system_info_id);
+CREATE INDEX IF NOT EXISTS idx_browser_extensions_identifier ON browser_extensions(identifier);
+CREATE INDEX IF NOT EXISTS idx_browser_extensions_end_time ON browser_extensions(end_time);
+CREATE INDEX IF NOT EXISTS idx_node_keys_host_uuid ON node_keys(host_uuid);
+CREATE INDEX IF NOT EXISTS idx_campaign_results_node_status ON campaign_results(node_id, status);
+CREATE INDEX IF NOT EXISTS idx_query_results_query ON query_results(pack, query_name, collected_at);
+CREATE INDEX IF NOT EXISTS idx_query_results_host_uuid ON query_results(host_uuid);
+CREATE INDEX IF NOT EXISTS idx_query_events_host ON query_events(host_uuid, created_at);
+CREATE INDEX IF NOT EXISTS idx_app_events_bundle_identifier ON app_events(bundle_identifier, created_at);
+CREATE INDEX IF NOT EXISTS idx_app_events_created_at ON app_events(created_at);
@@ -0,0 +1,2 @@
+UPDATE installed_packages SET version = revision WHERE source IN ('snap', 'flatpak');
+ALTER TABLE installed_packages DROP COLUMN revision;
@@ -0,0 +1,3 @@
+-- Source package a deb package was built from, which Debian and Ubuntu
+-- advisories are keyed by. Empty when it is the package itself.
+ALTER TABLE installed_packages ADD COLUMN source_package VARCHAR(255) NULL;
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// App event types
+const (
+	AppEventInstalled  = "installed"
+	AppEventRemoved    = "removed"
+	AppEventUpgraded   = "upgraded"
+	AppEventDowngraded = "downgraded"
+)
+
+// AppEvent represents a change of an installed app on a host, keyed by
+// bundle identifier. Versions are empty when not applicable, such as the
+// previous version of an installed app.
+type AppEvent struct {
+	ID               int64     `db:"id"`
+	HostID           int64     `db:"host_id"`
+	HostUUID         string    `db:"host_uuid"`
+	Hostname         string    `db:"hostname"`
+	BundleIdentifier string    `db:"bundle_identifier"`
+	Name             string    `db:"name"`
+	Path             string    `db:"path"`
+	EventType        string    `db:"event_type"`
+	FromVersion      string    `db:"from_version"`
+	ToVersion        string    `db:"to_version"`
+	CreatedAt        time.Time `db:"created_at"`
+}
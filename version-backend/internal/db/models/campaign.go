@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// Live query result statuses
+const (
+	CampaignStatusPending   = "pending"
+	CampaignStatusSent      = "sent"
+	CampaignStatusCompleted = "completed"
+	CampaignStatusFailed    = "failed"
+	CampaignStatusTimeout   = "timeout"
+)
+
+// Campaign represents a live query fanned out to a set of hosts
+type Campaign struct {
+	ID        int64     `db:"id"`
+	Query     string    `db:"query"`
+	Selector  string    `db:"selector"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// CampaignResult represents the outcome of a live query on a single host.
+// Node ID is nil for the host the server collects from locally.
+type CampaignResult struct {
+	ID             int64      `db:"id"`
+	CampaignID     int64      `db:"campaign_id"`
+	NodeID         *int64     `db:"node_id"`
+	HostUUID       string     `db:"host_uuid"`
+	HostIdentifier string     `db:"host_identifier"`
+	Status         string     `db:"status"`
+	RowCount       int        `db:"row_count"`
+	Results        *string    `db:"results"`
+	Error          *string    `db:"error"`
+	CreatedAt      time.Time  `db:"created_at"`
+	SentAt         *time.Time `db:"sent_at"`
+	CompletedAt    *time.Time `db:"completed_at"`
+}
+
+// PendingQuery is a live query waiting to be handed to a node
+type PendingQuery struct {
+	CampaignID int64  `db:"campaign_id"`
+	Query      string `db:"query"`
+}
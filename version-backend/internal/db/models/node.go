@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// Node represents an osqueryd instance enrolled through the TLS remote API
+type Node struct {
+	ID                 int64      `db:"id"`
+	NodeKey            string     `db:"node_key"`
+	EnrollSecretID     *int64     `db:"enroll_secret_id"`
+	HostIdentifier     string     `db:"host_identifier"`
+	HostUUID           string     `db:"host_uuid"`
+	Platform           string     `db:"platform"`
+	PlatformLike       string     `db:"platform_like"`
+	CollectRequestedAt *time.Time `db:"collect_requested_at"`
+	LastCollectedAt    *time.Time `db:"last_collected_at"`
+	CreatedAt          time.Time  `db:"created_at"`
+	LastSeenAt         time.Time  `db:"last_seen_at"`
+	RevokedAt          *time.Time `db:"revoked_at"`
+}
+
+// EnrollSecret represents a secret remote nodes and agents enroll with
+type EnrollSecret struct {
+	ID        int64      `db:"id"`
+	Name      string     `db:"name"`
+	Secret    string     `db:"secret"`
+	CreatedAt time.Time  `db:"created_at"`
+	RotatedAt *time.Time `db:"rotated_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// Scheduled query result actions, as reported by osquery
+const (
+	QueryActionSnapshot = "snapshot"
+	QueryActionAdded    = "added"
+	QueryActionRemoved  = "removed"
+)
+
+// QueryResult represents a row returned by a scheduled pack query. Snapshot
+// queries store every row of each run, differential queries only the rows
+// added or removed since the previous run.
+type QueryResult struct {
+	ID          int64     `db:"id"`
+	HostUUID    string    `db:"host_uuid"`
+	Pack        string    `db:"pack"`
+	QueryName   string    `db:"query_name"`
+	Action      string    `db:"action"`
+	Columns     string    `db:"columns"`
+	CollectedAt time.Time `db:"collected_at"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// QueryEvent represents a row added to or removed from the result of a
+// collector query between two snapshots of a host
+type QueryEvent struct {
+	ID        int64     `db:"id"`
+	HostUUID  string    `db:"host_uuid"`
+	QueryName string    `db:"query_name"`
+	Action    string    `db:"action"`
+	RowHash   string    `db:"row_hash"`
+	Columns   string    `db:"columns"`
+	CreatedAt time.Time `db:"created_at"`
+}
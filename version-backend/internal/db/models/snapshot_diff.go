@@ -0,0 +1,24 @@
+package models
+
+// SnapshotDiff describes how the installed apps, OS and osquery version of a
+// host changed between two system information snapshots
+type SnapshotDiff struct {
+	From    *SystemInfo
+	To      *SystemInfo
+	Added   []InstalledApp
+	Removed []InstalledApp
+	Changed []AppChange
+}
+
+// AppVersionChanged is the change of an app whose version string differs
+// between two snapshots but compares equal, such as 1.0 and 1.0.0
+const AppVersionChanged = "changed"
+
+// AppChange is an app installed in both snapshots with a different version.
+// Change is AppEventUpgraded, AppEventDowngraded or AppVersionChanged.
+type AppChange struct {
+	BundleIdentifier string
+	From             InstalledApp
+	To               InstalledApp
+	Change           string
+}
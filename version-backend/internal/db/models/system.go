@@ -7,16 +7,59 @@ import (
 // SystemInfo represents the system information stored in the database
 type SystemInfo struct {
 	ID             int64     `db:"id"`
+	HostID         int64     `db:"host_id"`
 	OSName         string    `db:"os_name"`
 	OSVersion      string    `db:"os_version"`
 	OSPlatform     string    `db:"os_platform"`
+	OSPlatformLike string    `db:"-"`
 	OsqueryVersion string    `db:"osquery_version"`
+	ContentHash    string    `db:"content_hash"`
 	CreatedAt      time.Time `db:"created_at"`
 	UpdatedAt      time.Time `db:"updated_at"`
+	Host           *Host     `db:"-"`
 	InstalledApps  []InstalledApp
+	Packages       []SoftwarePackage
+	LangPackages   []LanguagePackage
+	Extensions     []BrowserExtension
 }
 
-// InstalledApp represents an installed application in the system
+// Host represents the identity and hardware of a monitored machine, keyed by
+// its hardware UUID
+type Host struct {
+	ID               int64     `db:"id"`
+	UUID             string    `db:"uuid"`
+	Hostname         string    `db:"hostname"`
+	ComputerName     string    `db:"computer_name"`
+	CPUBrand         string    `db:"cpu_brand"`
+	CPUType          string    `db:"cpu_type"`
+	CPUPhysicalCores int       `db:"cpu_physical_cores"`
+	CPULogicalCores  int       `db:"cpu_logical_cores"`
+	PhysicalMemory   int64     `db:"physical_memory"`
+	HardwareVendor   string    `db:"hardware_vendor"`
+	HardwareModel    string    `db:"hardware_model"`
+	HardwareSerial   string    `db:"hardware_serial"`
+	PlatformVendor   string    `db:"platform_vendor"`
+	PlatformVersion  string    `db:"platform_version"`
+	PlatformDate     string    `db:"platform_date"`
+	PlatformRevision string    `db:"platform_revision"`
+	CreatedAt        time.Time `db:"created_at"`
+	UpdatedAt        time.Time `db:"updated_at"`
+	LastSeenAt       time.Time `db:"last_seen_at"`
+}
+
+// HostSummary is a host together with the OS of its latest system
+// information. The OS fields are nil for a host without a snapshot yet.
+type HostSummary struct {
+	Host
+	OSName         *string `db:"os_name"`
+	OSVersion      *string `db:"os_version"`
+	OSPlatform     *string `db:"os_platform"`
+	OsqueryVersion *string `db:"osquery_version"`
+}
+
+// InstalledApp represents an installed application in the system.
+// NewestVersion is the newest version of the app currently installed on any
+// host, set by the latest data queries when it is newer than this one.
 type InstalledApp struct {
 	ID                   int64      `db:"id"`
 	SystemInfoID         int64      `db:"system_info_id"`
@@ -28,8 +71,57 @@ type InstalledApp struct {
 	DisplayName          string     `db:"display_name"`
 	MinimumSystemVersion string     `db:"minimum_system_version"`
 	LastOpenedTime       float64    `db:"last_opened_time"`
+	CPE                  string     `db:"cpe"`
 	CreatedAt            time.Time  `db:"created_at"`
 	EndTime              *time.Time `db:"end_time"`
+	NewestVersion        string     `db:"-"`
+}
+
+// SoftwarePackage represents a package installed through an OS package manager
+type SoftwarePackage struct {
+	ID            int64      `db:"id"`
+	SystemInfoID  int64      `db:"system_info_id"`
+	Name          string     `db:"name"`
+	Version       string     `db:"version"`
+	Arch          string     `db:"arch"`
+	Source        string     `db:"source"`
+	Revision      string     `db:"revision"`
+	SourcePackage string     `db:"source_package"`
+	InstallTime   int64      `db:"install_time"`
+	CreatedAt     time.Time  `db:"created_at"`
+	EndTime       *time.Time `db:"end_time"`
+}
+
+// LanguagePackage represents a package installed through a language package
+// manager such as pip, npm, RubyGems or the Go module cache
+type LanguagePackage struct {
+	ID           int64      `db:"id"`
+	SystemInfoID int64      `db:"system_info_id"`
+	Ecosystem    string     `db:"ecosystem"`
+	Name         string     `db:"name"`
+	Version      string     `db:"version"`
+	Path         string     `db:"path"`
+	Username     string     `db:"username"`
+	CreatedAt    time.Time  `db:"created_at"`
+	EndTime      *time.Time `db:"end_time"`
+}
+
+// BrowserExtension represents a browser extension installed in a user profile.
+// Rows are versioned individually, so CreatedAt is when the extension was
+// first seen and EndTime when it was removed or changed.
+type BrowserExtension struct {
+	ID           int64      `db:"id"`
+	SystemInfoID int64      `db:"system_info_id"`
+	Browser      string     `db:"browser"`
+	Username     string     `db:"username"`
+	Identifier   string     `db:"identifier"`
+	Name         string     `db:"name"`
+	Version      string     `db:"version"`
+	Permissions  string     `db:"permissions"`
+	ProfilePath  string     `db:"profile_path"`
+	Path         string     `db:"path"`
+	CreatedAt    time.Time  `db:"created_at"`
+	EndTime      *time.Time `db:"end_time"`
 }
 
 // // ToResponse converts the model to an API response format
@@ -0,0 +1,242 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"version-backend/internal/db/models"
+)
+
+// ErrNodeNotFound is returned when a node key is unknown
+var ErrNodeNotFound = errors.New("node not found")
+
+// ErrHostClaimed is returned when a node claims a host already bound to
+// another active node
+var ErrHostClaimed = errors.New("host belongs to another node")
+
+// EnrollNode checks an enroll secret and issues a new node key. A host that
+// enrolls again, such as after osqueryd lost its database, replaces its
+// active node: the old node key is revoked in the same transaction. It
+// returns the IDs of the nodes replaced.
+func (db *DB) EnrollNode(enrollSecret string, node *models.Node) ([]int64, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The secret stays locked until the node is inserted, so two nodes
+	// enrolling with it for the same host are serialized
+	var secretID int64
+	query := `
+		SELECT id FROM enroll_secrets
+		WHERE secret = ? AND revoked_at IS NULL
+	` + db.dialect.forUpdate()
+	if err := tx.Get(&secretID, query, enrollSecret); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidEnrollSecret
+		}
+		return nil, fmt.Errorf("error checking enroll secret: %w", err)
+	}
+	node.EnrollSecretID = &secretID
+
+	node.NodeKey, err = generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	// Revoke the active nodes of the host, keeping one active node per host
+	var replaced []int64
+	if node.HostUUID != "" {
+		query = `
+			SELECT id FROM node_keys
+			WHERE host_uuid = ? AND revoked_at IS NULL
+		` + db.dialect.forUpdate()
+		if err := tx.Select(&replaced, query, node.HostUUID); err != nil {
+			return nil, fmt.Errorf("error getting existing nodes: %w", err)
+		}
+
+		query = `
+			UPDATE node_keys
+			SET revoked_at = CURRENT_TIMESTAMP
+			WHERE host_uuid = ? AND revoked_at IS NULL
+		`
+		if _, err := tx.Exec(query, node.HostUUID); err != nil {
+			return nil, fmt.Errorf("error revoking existing nodes: %w", err)
+		}
+	}
+
+	query = `
+		INSERT INTO node_keys (
+			node_key, enroll_secret_id, host_identifier, host_uuid,
+			platform, platform_like
+		) VALUES (?, ?, ?, ?, ?, ?)
+	`
+	node.ID, err = tx.insert(query,
+		node.NodeKey,
+		secretID,
+		node.HostIdentifier,
+		node.HostUUID,
+		node.Platform,
+		node.PlatformLike,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error inserting node: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return replaced, nil
+}
+
+// AuthenticateNode retrieves the node owning an active node key and records
+// that it was seen. Unknown and revoked keys are rejected with ErrNodeNotFound.
+func (db *DB) AuthenticateNode(nodeKey string) (*models.Node, error) {
+	var node models.Node
+	query := `
+		SELECT
+			id, node_key, enroll_secret_id, host_identifier, host_uuid,
+			platform, platform_like, collect_requested_at, last_collected_at,
+			created_at, last_seen_at, revoked_at
+		FROM node_keys
+		WHERE node_key = ? AND revoked_at IS NULL
+	`
+	if err := db.Get(&node, query, nodeKey); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNodeNotFound
+		}
+		return nil, fmt.Errorf("error getting node: %w", err)
+	}
+
+	query = `
+		UPDATE node_keys
+		SET last_seen_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`
+	if _, err := db.Exec(query, node.ID); err != nil {
+		return nil, fmt.Errorf("error updating node: %w", err)
+	}
+
+	return &node, nil
+}
+
+// ClaimCollection marks a node as asked to run the collectors, unless it was
+// already asked within the interval. It reports whether the claim succeeded.
+func (db *DB) ClaimCollection(nodeID int64, interval time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	query := `
+		UPDATE node_keys
+		SET collect_requested_at = ?
+		WHERE id = ? AND (collect_requested_at IS NULL OR collect_requested_at < ?)
+	`
+	result, err := db.Exec(query, now, nodeID, now.Add(-interval))
+	if err != nil {
+		return false, fmt.Errorf("error claiming collection: %w", err)
+	}
+
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting affected rows: %w", err)
+	}
+
+	return claimed > 0, nil
+}
+
+// ClaimHost binds a node enrolled without a host UUID to the host of its
+// first snapshot, unless another active node already owns that host. The
+// host row is written first and stays locked until the claim commits, so
+// two nodes claiming the same new host are serialized.
+func (db *DB) ClaimHost(nodeID int64, host *models.Host) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := upsertHost(tx, host); err != nil {
+		return fmt.Errorf("error saving host: %w", err)
+	}
+
+	var owners int
+	query := `
+		SELECT COUNT(*) FROM node_keys
+		WHERE host_uuid = ? AND id <> ? AND revoked_at IS NULL
+	`
+	if err := tx.Get(&owners, query, host.UUID, nodeID); err != nil {
+		return fmt.Errorf("error getting host owners: %w", err)
+	}
+	if owners > 0 {
+		return ErrHostClaimed
+	}
+
+	query = `
+		UPDATE node_keys
+		SET host_uuid = ?
+		WHERE id = ? AND (host_uuid IS NULL OR host_uuid = '')
+	`
+	if _, err := tx.Exec(query, host.UUID, nodeID); err != nil {
+		return fmt.Errorf("error updating node: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// MarkCollected records that a node delivered its collector results and
+// links it to the host they identified
+func (db *DB) MarkCollected(nodeID int64, hostUUID string) error {
+	query := `
+		UPDATE node_keys
+		SET last_collected_at = CURRENT_TIMESTAMP, host_uuid = ?
+		WHERE id = ?
+	`
+	if _, err := db.Exec(query, hostUUID, nodeID); err != nil {
+		return fmt.Errorf("error updating node: %w", err)
+	}
+	return nil
+}
+
+// ListNodes retrieves every enrolled node, including revoked ones
+func (db *DB) ListNodes() ([]models.Node, error) {
+	var nodes []models.Node
+	query := `
+		SELECT
+			id, node_key, enroll_secret_id, host_identifier, host_uuid,
+			platform, platform_like, collect_requested_at, last_collected_at,
+			created_at, last_seen_at, revoked_at
+		FROM node_keys
+		ORDER BY created_at, id
+	`
+	if err := db.Select(&nodes, query); err != nil {
+		return nil, fmt.Errorf("error listing nodes: %w", err)
+	}
+
+	return nodes, nil
+}
+
+// RevokeNode revokes the node key of a node, so its requests are rejected
+// until it enrolls again
+func (db *DB) RevokeNode(id int64) error {
+	query := `
+		UPDATE node_keys
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND revoked_at IS NULL
+	`
+	result, err := db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("error revoking node: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("error getting affected rows: %w", err)
+	} else if rows == 0 {
+		return ErrNodeNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"version-backend/internal/db/models"
+	"version-backend/internal/diff"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// QueryRows holds the raw rows a collector query returned for a snapshot
+type QueryRows struct {
+	// Table is the table the collected data is stored in
+	Table string
+
+	// Rows are the rows returned by the query
+	Rows []map[string]string
+
+	// Volatile lists the columns ignored when comparing rows
+	Volatile []string
+}
+
+// applyQueryDiffs compares the rows of every query with the previous run on
+// the host, records the added and removed rows as query events and replaces
+// the stored query state. It returns the tables whose queries changed.
+func applyQueryDiffs(tx *Tx, hostUUID string, queries map[string]QueryRows) (map[string]bool, error) {
+	changedTables := make(map[string]bool)
+	for name, rows := range queries {
+		changes, err := applyQueryDiff(tx, hostUUID, name, rows)
+		if err != nil {
+			return nil, fmt.Errorf("error diffing %s: %w", name, err)
+		}
+		if !changes.Empty() {
+			changedTables[rows.Table] = true
+		}
+	}
+	return changedTables, nil
+}
+
+// collected reports whether a query storing its data in table ran for a
+// snapshot
+func collected(queries map[string]QueryRows, table string) bool {
+	for _, rows := range queries {
+		if rows.Table == table {
+			return true
+		}
+	}
+	return false
+}
+
+// applyQueryDiff runs a single query through the differential engine
+func applyQueryDiff(tx *Tx, hostUUID, name string, rows QueryRows) (diff.Changes, error) {
+	var state []struct {
+		RowHash string `db:"row_hash"`
+		Columns string `db:"columns"`
+	}
+	query := `
+		SELECT row_hash, columns
+		FROM query_state
+		WHERE host_uuid = ? AND query_name = ?
+	`
+	if err := tx.Select(&state, query, hostUUID, name); err != nil {
+		return diff.Changes{}, fmt.Errorf("error getting query state: %w", err)
+	}
+
+	previous := make(map[string]diff.Row, len(state))
+	for _, row := range state {
+		var columns diff.Row
+		if err := json.Unmarshal([]byte(row.Columns), &columns); err != nil {
+			return diff.Changes{}, fmt.Errorf("error decoding query state: %w", err)
+		}
+		previous[row.RowHash] = columns
+	}
+
+	changes := diff.Compute(previous, diff.Index(rows.Rows, rows.Volatile))
+
+	var events, added [][]interface{}
+	var removed []string
+	for hash, row := range changes.Removed {
+		columns, err := json.Marshal(row)
+		if err != nil {
+			return diff.Changes{}, fmt.Errorf("error encoding row: %w", err)
+		}
+		events = append(events, []interface{}{hostUUID, name, models.QueryActionRemoved, hash, string(columns)})
+		removed = append(removed, hash)
+	}
+	for hash, row := range changes.Added {
+		columns, err := json.Marshal(row)
+		if err != nil {
+			return diff.Changes{}, fmt.Errorf("error encoding row: %w", err)
+		}
+		events = append(events, []interface{}{hostUUID, name, models.QueryActionAdded, hash, string(columns)})
+		added = append(added, []interface{}{hostUUID, name, hash, string(columns)})
+	}
+
+	eventColumns := []string{"host_uuid", "query_name", "action", "row_hash", "columns"}
+	if err := insertRows(tx, "query_events", eventColumns, events); err != nil {
+		return diff.Changes{}, err
+	}
+
+	for start := 0; start < len(removed); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(removed) {
+			end = len(removed)
+		}
+		query, args, err := sqlx.In(`
+			DELETE FROM query_state
+			WHERE host_uuid = ? AND query_name = ? AND row_hash IN (?)
+		`, hostUUID, name, removed[start:end])
+		if err != nil {
+			return diff.Changes{}, err
+		}
+		if _, err := tx.Exec(query, args...); err != nil {
+			return diff.Changes{}, fmt.Errorf("error deleting query state: %w", err)
+		}
+	}
+
+	// A row already in the state is overwritten rather than failing the
+	// whole snapshot on the unique key
+	stateColumns := []string{"host_uuid", "query_name", "row_hash", "columns"}
+	if err := upsertRows(tx, "query_state", "host_uuid, query_name, row_hash", stateColumns, []string{"columns"}, added); err != nil {
+		return diff.Changes{}, err
+	}
+
+	return changes, nil
+}
+
+// GetQueryEvents retrieves the rows added to or removed from the collector
+// queries of a host, most recent first. An empty query name matches every
+// query.
+func (db *DB) GetQueryEvents(hostUUID, queryName string, since time.Time, limit int) ([]models.QueryEvent, error) {
+	query := `
+		SELECT id, host_uuid, query_name, action, row_hash, columns, created_at
+		FROM query_events
+		WHERE host_uuid = ? AND created_at >= ?
+	`
+	args := []interface{}{hostUUID, since.UTC()}
+
+	if queryName != "" {
+		query += ` AND query_name = ?`
+		args = append(args, queryName)
+	}
+
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ?`
+	args = append(args, limit)
+
+	var events []models.QueryEvent
+	if err := db.Select(&events, query, args...); err != nil {
+		return nil, fmt.Errorf("error getting query events: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"version-backend/internal/db/models"
+)
+
+// QueryResultFilter selects the scheduled query results to retrieve
+type QueryResultFilter struct {
+	Pack      string
+	QueryName string
+	HostUUID  string
+	Since     time.Time
+	Limit     int
+}
+
+// SaveQueryResults stores the rows returned by a scheduled query run
+func (db *DB) SaveQueryResults(results []models.QueryResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	columns := []string{"host_uuid", "pack", "query_name", "action", "columns", "collected_at"}
+	rows := make([][]interface{}, len(results))
+	for i, result := range results {
+		rows[i] = []interface{}{
+			result.HostUUID,
+			result.Pack,
+			result.QueryName,
+			result.Action,
+			result.Columns,
+			result.CollectedAt.UTC(),
+		}
+	}
+	if err := insertRows(tx, "query_results", columns, rows); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetQueryResults retrieves the stored results of a scheduled query, most
+// recent first
+func (db *DB) GetQueryResults(filter QueryResultFilter) ([]models.QueryResult, error) {
+	query := `
+		SELECT id, host_uuid, pack, query_name, action, columns, collected_at, created_at
+		FROM query_results
+		WHERE pack = ? AND query_name = ?
+	`
+	args := []interface{}{filter.Pack, filter.QueryName}
+
+	if filter.HostUUID != "" {
+		query += ` AND host_uuid = ?`
+		args = append(args, filter.HostUUID)
+	}
+	if !filter.Since.IsZero() {
+		query += ` AND collected_at >= ?`
+		args = append(args, filter.Since.UTC())
+	}
+
+	query += ` ORDER BY collected_at DESC, id DESC LIMIT ?`
+	args = append(args, filter.Limit)
+
+	var results []models.QueryResult
+	if err := db.Select(&results, query, args...); err != nil {
+		return nil, fmt.Errorf("error getting query results: %w", err)
+	}
+
+	return results, nil
+}
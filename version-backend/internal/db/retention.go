@@ -0,0 +1,366 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"version-backend/internal/db/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// replacedWithin is the longest time between closing an app row and
+// inserting the row replacing it during a single save. Rows further apart
+// were seen in different collections, so the app was missing in between.
+const replacedWithin = time.Minute
+
+// RetentionPolicy selects the archived rows of the versioned tables to prune
+type RetentionPolicy struct {
+	// MaxAge is how long archived rows are kept after they were closed.
+	// Zero keeps them forever.
+	MaxAge time.Duration
+
+	// MinSnapshots is the number of most recent snapshots kept per host
+	// whatever their age, that is the rows closed by its last changes
+	MinSnapshots int
+
+	// Compact merges consecutive rows of an app that hold the same version
+	// into one
+	Compact bool
+}
+
+// PruneResult reports the rows removed by PruneSnapshots
+type PruneResult struct {
+	// RowsPruned is the number of rows of every versioned table closed
+	// before the maximum age
+	RowsPruned int64
+
+	// RowsCompacted is the number of app rows merged into the row that
+	// followed them
+	RowsCompacted int64
+}
+
+// archivedRow is a row of a versioned table closed by a change
+type archivedRow struct {
+	ID      int64     `db:"id"`
+	HostID  int64     `db:"host_id"`
+	EndTime time.Time `db:"end_time"`
+}
+
+// hostChange is a change of a host that closed archived rows
+type hostChange struct {
+	HostID  int64     `db:"host_id"`
+	EndTime time.Time `db:"end_time"`
+}
+
+// PruneSnapshots removes the archived rows of the versioned tables the
+// policy does not keep. Unchanged runs of apps are compacted first so they
+// count as a single change towards the snapshots kept per host. Open rows
+// are never removed.
+func (db *DB) PruneSnapshots(policy RetentionPolicy) (*PruneResult, error) {
+	result := &PruneResult{}
+
+	if policy.Compact {
+		compacted, err := db.compactApps()
+		if err != nil {
+			return result, err
+		}
+		result.RowsCompacted = compacted
+	}
+
+	if policy.MaxAge > 0 {
+		pruned, err := db.pruneArchived(time.Now().Add(-policy.MaxAge), policy.MinSnapshots)
+		if err != nil {
+			return result, err
+		}
+		result.RowsPruned = pruned
+	}
+
+	return result, nil
+}
+
+// compactApps merges every archived app row replaced by a row holding the
+// same version into that row, which is backdated to when the run started.
+// Such runs were left by snapshots rewriting every app. It returns the
+// number of rows removed.
+func (db *DB) compactApps() (int64, error) {
+	var systemInfoIDs []int64
+	query := `SELECT DISTINCT system_info_id FROM installed_apps WHERE end_time IS NOT NULL`
+	if err := db.Select(&systemInfoIDs, query); err != nil {
+		return 0, fmt.Errorf("error getting system infos with archived apps: %w", err)
+	}
+
+	var compacted int64
+	for _, systemInfoID := range systemInfoIDs {
+		rows, err := db.compactSystemInfoApps(systemInfoID)
+		if err != nil {
+			return compacted, err
+		}
+		compacted += rows
+	}
+	return compacted, nil
+}
+
+// compactSystemInfoApps compacts the app rows of a system info and returns
+// the number of rows removed
+func (db *DB) compactSystemInfoApps(systemInfoID int64) (int64, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var apps []models.InstalledApp
+	query := `
+		SELECT
+			id, system_info_id, name, path, bundle_identifier,
+			bundle_name, bundle_short_version, display_name,
+			minimum_system_version, last_opened_time, created_at, end_time
+		FROM installed_apps
+		WHERE system_info_id = ?
+		ORDER BY path, id
+	`
+	if err := tx.Select(&apps, query, systemInfoID); err != nil {
+		return 0, fmt.Errorf("error getting apps: %w", err)
+	}
+
+	switches, err := hostSwitches(tx, systemInfoID)
+	if err != nil {
+		return 0, err
+	}
+
+	var merged []int64
+	for i := 0; i+1 < len(apps); i++ {
+		previous, next := &apps[i], &apps[i+1]
+		if previous.EndTime == nil || !sameAppVersion(*previous, *next) {
+			continue
+		}
+		if next.CreatedAt.Sub(*previous.EndTime) > replacedWithin {
+			continue
+		}
+		if switchedBetween(switches, previous.CreatedAt, next.CreatedAt) {
+			continue
+		}
+
+		// The next row now covers the whole run
+		query := `UPDATE installed_apps SET created_at = ? WHERE id = ?`
+		if _, err := tx.Exec(query, previous.CreatedAt.UTC(), next.ID); err != nil {
+			return 0, fmt.Errorf("error backdating app %s: %w", next.Name, err)
+		}
+		next.CreatedAt = previous.CreatedAt
+		merged = append(merged, previous.ID)
+	}
+
+	rows, err := deleteArchived(tx, "installed_apps", merged)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return rows, nil
+}
+
+// hostSwitches returns when the host of a system info switched to one of
+// its other system infos, that is when their apps were inserted. A host
+// returning to the system info gets a fresh snapshot, which ranks it as
+// current in point-in-time queries, so runs spanning a switch are kept.
+func hostSwitches(tx *Tx, systemInfoID int64) ([]time.Time, error) {
+	var switches []time.Time
+	query := `
+		SELECT DISTINCT a.created_at
+		FROM installed_apps a
+		JOIN system_info s ON s.id = a.system_info_id
+		JOIN system_info c ON c.host_id = s.host_id
+		WHERE c.id = ? AND s.id <> c.id
+	`
+	if err := tx.Select(&switches, query, systemInfoID); err != nil {
+		return nil, fmt.Errorf("error getting host switches: %w", err)
+	}
+
+	var created []time.Time
+	query = `
+		SELECT s.created_at
+		FROM system_info s
+		JOIN system_info c ON c.host_id = s.host_id
+		WHERE c.id = ? AND s.id <> c.id
+	`
+	if err := tx.Select(&created, query, systemInfoID); err != nil {
+		return nil, fmt.Errorf("error getting host switches: %w", err)
+	}
+	return append(switches, created...), nil
+}
+
+// switchedBetween reports whether a switch happened after from and no later
+// than to
+func switchedBetween(switches []time.Time, from, to time.Time) bool {
+	for _, at := range switches {
+		if at.After(from) && !at.After(to) {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneArchived removes the archived rows of every versioned table closed
+// before the cutoff, keeping the rows closed by the last minSnapshots
+// changes of every host. Every snapshot since the oldest change kept can
+// still be rebuilt from the rows left. It returns the number of rows
+// removed.
+func (db *DB) pruneArchived(cutoff time.Time, minSnapshots int) (int64, error) {
+	keepFrom, err := db.keptChanges(cutoff, minSnapshots)
+	if err != nil {
+		return 0, err
+	}
+
+	var pruned int64
+	for _, table := range versionedTables {
+		rows, err := db.pruneTable(table, cutoff, keepFrom)
+		pruned += rows
+		if err != nil {
+			return pruned, err
+		}
+	}
+	return pruned, nil
+}
+
+// keptChanges returns, for every host with more than minSnapshots changes,
+// the time before which its archived rows may be removed: the cutoff, or
+// its minSnapshots-th most recent change when that is older. Changes are
+// counted across the versioned tables, as the rows one change closes share
+// its end_time. Hosts missing from the map keep all their rows.
+func (db *DB) keptChanges(cutoff time.Time, minSnapshots int) (map[int64]time.Time, error) {
+	selects := make([]string, len(versionedTables))
+	for i, table := range versionedTables {
+		selects[i] = fmt.Sprintf(`
+			SELECT s.host_id AS host_id, t.end_time AS end_time
+			FROM %s t
+			JOIN system_info s ON s.id = t.system_info_id
+			WHERE t.end_time IS NOT NULL
+		`, table)
+	}
+	query := strings.Join(selects, " UNION ") + ` ORDER BY host_id, end_time DESC`
+
+	var changes []hostChange
+	if err := db.Select(&changes, query); err != nil {
+		return nil, fmt.Errorf("error getting archived changes: %w", err)
+	}
+
+	byHost := make(map[int64][]time.Time)
+	for _, change := range changes {
+		byHost[change.HostID] = append(byHost[change.HostID], change.EndTime)
+	}
+
+	keepFrom := make(map[int64]time.Time, len(byHost))
+	for hostID, changedAt := range byHost {
+		if minSnapshots > 0 && len(changedAt) <= minSnapshots {
+			continue
+		}
+		keepFrom[hostID] = cutoff
+		if minSnapshots > 0 {
+			if kept := changedAt[minSnapshots-1]; kept.Before(cutoff) {
+				keepFrom[hostID] = kept
+			}
+		}
+	}
+	return keepFrom, nil
+}
+
+// pruneTable removes the archived rows of a versioned table closed before
+// the time kept for their host. Rows are read and removed a page at a time
+// in id order, so large tables are never loaded at once. It returns the
+// number of rows removed.
+func (db *DB) pruneTable(table string, cutoff time.Time, keepFrom map[int64]time.Time) (int64, error) {
+	query := fmt.Sprintf(`
+		SELECT t.id, s.host_id, t.end_time
+		FROM %s t
+		JOIN system_info s ON s.id = t.system_info_id
+		WHERE t.end_time IS NOT NULL AND t.end_time < ? AND t.id > ?
+		ORDER BY t.id
+		LIMIT %d
+	`, table, deleteBatchSize)
+
+	var pruned, lastID int64
+	for {
+		var page []archivedRow
+		if err := db.Select(&page, query, cutoff.UTC(), lastID); err != nil {
+			return pruned, fmt.Errorf("error getting archived %s: %w", table, err)
+		}
+		if len(page) == 0 {
+			return pruned, nil
+		}
+		lastID = page[len(page)-1].ID
+
+		var expired []int64
+		for _, row := range page {
+			if kept, ok := keepFrom[row.HostID]; ok && row.EndTime.Before(kept) {
+				expired = append(expired, row.ID)
+			}
+		}
+
+		rows, err := db.deleteArchivedPage(table, expired)
+		pruned += rows
+		if err != nil {
+			return pruned, err
+		}
+		if len(page) < deleteBatchSize {
+			return pruned, nil
+		}
+	}
+}
+
+// deleteArchivedPage removes archived rows of a table in a transaction of
+// their own and returns how many were removed
+func (db *DB) deleteArchivedPage(table string, ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deleted, err := deleteArchived(tx, table, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return deleted, nil
+}
+
+// deleteArchived removes archived rows of a table in batches and returns
+// how many were removed
+func deleteArchived(tx *Tx, table string, ids []int64) (int64, error) {
+	var deleted int64
+	for start := 0; start < len(ids); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		query, args, err := sqlx.In(fmt.Sprintf(`
+			DELETE FROM %s
+			WHERE id IN (?) AND end_time IS NOT NULL
+		`, table), ids[start:end])
+		if err != nil {
+			return deleted, err
+		}
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return deleted, fmt.Errorf("error deleting %s: %w", table, err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += rows
+	}
+	return deleted, nil
+}
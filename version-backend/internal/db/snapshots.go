@@ -0,0 +1,167 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"version-backend/internal/db/models"
+	"version-backend/pkg/version"
+)
+
+// ErrSnapshotNotFound is returned when a system information snapshot does not exist
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// systemInfoColumns lists the columns selected for system information
+const systemInfoColumns = `
+	s.id, s.host_id, s.os_name, s.os_version, s.os_platform, s.osquery_version,
+	COALESCE(s.content_hash, '') AS content_hash, s.created_at, s.updated_at
+`
+
+// GetSnapshot retrieves a system information snapshot by ID together with
+// its installed software as it was at its created_at, like GetHostSystemInfoAt
+func (db *DB) GetSnapshot(id int64) (*models.SystemInfo, error) {
+	var info models.SystemInfo
+	query := `SELECT ` + systemInfoColumns + ` FROM system_info s WHERE s.id = ?`
+	if err := db.Get(&info, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSnapshotNotFound
+		}
+		return nil, fmt.Errorf("error getting snapshot: %w", err)
+	}
+
+	if err := db.loadSystemInfoDetails(&info, info.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// GetSystemInfoAt retrieves the most recent system information across all
+// hosts as it was at a point in time, including the installed software that
+// has since been removed or replaced
+func (db *DB) GetSystemInfoAt(at time.Time) (*models.SystemInfo, error) {
+	return db.GetHostSystemInfoAt(0, at)
+}
+
+// GetHostSystemInfoAt retrieves the system information of a host as it was
+// at a point in time, reconstructed from the created_at and end_time ranges
+// of the versioned tables. A zero hostID matches any host.
+func (db *DB) GetHostSystemInfoAt(hostID int64, at time.Time) (*models.SystemInfo, error) {
+	if hostID != 0 {
+		if _, err := db.GetHost(hostID); err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := db.systemInfoAt(hostID, at)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.loadSystemInfoDetails(info, at); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// systemInfoAt retrieves the system information record that was current at a
+// point in time, without any of its installed software. That is the record
+// whose software snapshot was most recently replaced at or before the time.
+// A zero hostID matches any host.
+func (db *DB) systemInfoAt(hostID int64, at time.Time) (*models.SystemInfo, error) {
+	var info models.SystemInfo
+
+	changedAt := db.dialect.greatest(`
+			COALESCE((
+				SELECT MAX(a.created_at) FROM installed_apps a
+				WHERE a.system_info_id = s.id AND a.created_at <= ?
+			), s.created_at)`, `
+			COALESCE((
+				SELECT MAX(p.created_at) FROM installed_packages p
+				WHERE p.system_info_id = s.id AND p.created_at <= ?
+			), s.created_at)`,
+	)
+	query := `SELECT ` + systemInfoColumns + `
+		FROM system_info s
+		WHERE (? = 0 OR s.host_id = ?) AND s.created_at <= ?
+		ORDER BY ` + changedAt + ` DESC, s.id DESC
+		LIMIT 1
+	`
+	at = at.UTC()
+	if err := db.Get(&info, query, hostID, hostID, at, at, at); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoSystemInfo
+		}
+		return nil, fmt.Errorf("error getting system info: %w", err)
+	}
+
+	return &info, nil
+}
+
+// DiffSnapshots compares the installed apps of two snapshots, keyed by
+// bundle identifier like app events. Apps are sorted by key.
+func DiffSnapshots(from, to *models.SystemInfo) *models.SnapshotDiff {
+	diff := &models.SnapshotDiff{From: from, To: to}
+
+	previous := indexApps(from.InstalledApps)
+	current := indexApps(to.InstalledApps)
+
+	for _, key := range sortedAppKeys(current) {
+		app := current[key]
+		old, existed := previous[key]
+		if !existed {
+			diff.Added = append(diff.Added, app)
+			continue
+		}
+
+		if app.BundleShortVersion == old.BundleShortVersion {
+			continue
+		}
+		switch cmp := version.Compare(app.BundleShortVersion, old.BundleShortVersion); {
+		case cmp > 0:
+			diff.Changed = append(diff.Changed, models.AppChange{
+				BundleIdentifier: key,
+				From:             old,
+				To:               app,
+				Change:           models.AppEventUpgraded,
+			})
+		case cmp < 0:
+			diff.Changed = append(diff.Changed, models.AppChange{
+				BundleIdentifier: key,
+				From:             old,
+				To:               app,
+				Change:           models.AppEventDowngraded,
+			})
+		default:
+			// Versions such as 1.0 and 1.0.0 compare equal but still differ
+			diff.Changed = append(diff.Changed, models.AppChange{
+				BundleIdentifier: key,
+				From:             old,
+				To:               app,
+				Change:           models.AppVersionChanged,
+			})
+		}
+	}
+
+	for _, key := range sortedAppKeys(previous) {
+		if _, ok := current[key]; !ok {
+			diff.Removed = append(diff.Removed, previous[key])
+		}
+	}
+
+	return diff
+}
+
+// sortedAppKeys returns the keys of an app index in order
+func sortedAppKeys(apps map[string]models.InstalledApp) []string {
+	keys := make([]string, 0, len(apps))
+	for key := range apps {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"version-backend/internal/db/models"
+)
+
+// Store is the storage used by the API handlers and the collection
+// services. DB implements it on MariaDB/MySQL, PostgreSQL and SQLite.
+type Store interface {
+	// Connection
+	Ping() error
+	Stats() sql.DBStats
+	Close() error
+
+	// Snapshots
+	SaveSystemInfo(info *models.SystemInfo, queries map[string]QueryRows) (bool, error)
+	GetLatestSystemInfo() (*models.SystemInfo, error)
+	GetHostLatestSystemInfo(hostID int64) (*models.SystemInfo, error)
+	GetLatestLanguagePackages(ecosystem string) (*models.SystemInfo, error)
+	GetLatestExtensions(includeRemoved bool) (*models.SystemInfo, error)
+	GetLastDataUpdate() (time.Time, error)
+	GetSnapshot(id int64) (*models.SystemInfo, error)
+	GetSystemInfoAt(at time.Time) (*models.SystemInfo, error)
+	GetHostSystemInfoAt(hostID int64, at time.Time) (*models.SystemInfo, error)
+
+	// Hosts
+	GetHost(id int64) (*models.Host, error)
+	ListHosts() ([]models.HostSummary, error)
+	GetHostInventories(hostID int64) ([]*models.SystemInfo, error)
+
+	// Change history
+	GetQueryEvents(hostUUID, queryName string, since time.Time, limit int) ([]models.QueryEvent, error)
+	GetAppHistory(bundleIdentifier string, hostID int64) ([]models.AppEvent, error)
+	GetAppEvents(since time.Time, hostID int64, limit int) ([]models.AppEvent, error)
+
+	// Scheduled query results
+	SaveQueryResults(results []models.QueryResult) error
+	GetQueryResults(filter QueryResultFilter) ([]models.QueryResult, error)
+
+	// Enrollment
+	CreateEnrollSecret(name string) (*models.EnrollSecret, error)
+	EnsureEnrollSecret(name, secret string) error
+	GetEnrollSecret(id int64) (*models.EnrollSecret, error)
+	ListEnrollSecrets() ([]models.EnrollSecret, error)
+	RotateEnrollSecret(id int64) (*models.EnrollSecret, error)
+	RevokeEnrollSecret(id int64, revokeNodes bool) error
+	EnrollNode(enrollSecret string, node *models.Node) ([]int64, error)
+	AuthenticateNode(nodeKey string) (*models.Node, error)
+	ClaimCollection(nodeID int64, interval time.Duration) (bool, error)
+	ClaimHost(nodeID int64, host *models.Host) error
+	MarkCollected(nodeID int64, hostUUID string) error
+	ListNodes() ([]models.Node, error)
+	RevokeNode(id int64) error
+
+	// Live queries
+	CreateCampaign(query, selector string, targets []models.CampaignResult) (*models.Campaign, error)
+	GetCampaign(id int64) (*models.Campaign, error)
+	GetCampaignResults(campaignID int64) ([]models.CampaignResult, error)
+	ClaimPendingQueries(nodeID int64) ([]models.PendingQuery, error)
+	CompleteCampaignResult(result *models.CampaignResult) (bool, error)
+	ExpireCampaign(campaignID int64) ([]models.CampaignResult, error)
+
+	// Retention
+	PruneSnapshots(policy RetentionPolicy) (*PruneResult, error)
+}
+
+var _ Store = (*DB)(nil)
+
+// GetLastDataUpdate returns when system information was last saved. It
+// returns ErrNoSystemInfo when none has been collected yet.
+func (db *DB) GetLastDataUpdate() (time.Time, error) {
+	var updatedAt time.Time
+	query := `SELECT updated_at FROM system_info ORDER BY updated_at DESC LIMIT 1`
+	if err := db.Get(&updatedAt, query); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, ErrNoSystemInfo
+		}
+		return time.Time{}, fmt.Errorf("error getting last data update: %w", err)
+	}
+	return updatedAt, nil
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"version-backend/internal/db/models"
+	"version-backend/pkg/version"
+)
+
+// sortSoftware orders the installed software of a snapshot by name, the
+// newest version first when several are installed, as string ordering
+// would put 10.0 before 9.0
+func sortSoftware(info *models.SystemInfo) {
+	apps := info.InstalledApps
+	sort.SliceStable(apps, func(i, j int) bool {
+		a, b := apps[i], apps[j]
+		if x, y := strings.ToLower(a.Name), strings.ToLower(b.Name); x != y {
+			return x < y
+		}
+		if c := version.Compare(a.BundleShortVersion, b.BundleShortVersion); c != 0 {
+			return c > 0
+		}
+		return a.Path < b.Path
+	})
+
+	packages := info.Packages
+	sort.SliceStable(packages, func(i, j int) bool {
+		a, b := packages[i], packages[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return version.ForSource(a.Source).Compare(a.Version, b.Version) > 0
+	})
+}
+
+// markOutdatedApps sets NewestVersion on the current apps of a snapshot
+// that have a newer version installed on any host, keyed by bundle
+// identifier like app events
+func (db *DB) markOutdatedApps(info *models.SystemInfo) error {
+	var installed []struct {
+		BundleIdentifier   string `db:"bundle_identifier"`
+		BundleShortVersion string `db:"bundle_short_version"`
+	}
+	query := `
+		SELECT DISTINCT a.bundle_identifier, a.bundle_short_version
+		FROM installed_apps a
+		JOIN installed_apps c ON c.bundle_identifier = a.bundle_identifier
+		WHERE c.system_info_id = ? AND c.end_time IS NULL
+		AND a.end_time IS NULL AND a.bundle_short_version <> ''
+	`
+	if err := db.Select(&installed, query, info.ID); err != nil {
+		return fmt.Errorf("error getting installed app versions: %w", err)
+	}
+
+	newest := make(map[string]string)
+	for _, app := range installed {
+		if current, ok := newest[app.BundleIdentifier]; !ok || version.Compare(app.BundleShortVersion, current) > 0 {
+			newest[app.BundleIdentifier] = app.BundleShortVersion
+		}
+	}
+
+	for i := range info.InstalledApps {
+		app := &info.InstalledApps[i]
+		if app.BundleIdentifier == "" || app.EndTime != nil {
+			continue
+		}
+		if v, ok := newest[app.BundleIdentifier]; ok && version.Compare(v, app.BundleShortVersion) > 0 {
+			app.NewestVersion = v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Package diff implements the differential engine shared by the collectors
+// and the query packs. Rows are identified by a hash of their columns, and
+// each run of a query is compared with the previous one to find the rows
+// added and removed in between, as osquery does in differential mode.
+package diff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Row is a row returned by an osquery query
+type Row = map[string]string
+
+// Changes holds the rows added and removed between two runs of a query,
+// keyed by row hash
+type Changes struct {
+	Added   map[string]Row
+	Removed map[string]Row
+}
+
+// Empty reports whether the two runs returned the same rows
+func (c Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0
+}
+
+// Hash returns a stable hash of a row. Volatile columns, such as a last
+// opened time, are left out so they do not count as changes.
+func Hash(row Row, volatile []string) string {
+	if len(volatile) > 0 {
+		stable := make(Row, len(row))
+		for column, value := range row {
+			stable[column] = value
+		}
+		for _, column := range volatile {
+			delete(stable, column)
+		}
+		row = stable
+	}
+
+	// encoding/json sorts map keys, so equal rows encode identically
+	encoded, _ := json.Marshal(row)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// Index keys the rows of a run by hash. Duplicate rows collapse into one.
+func Index(rows []Row, volatile []string) map[string]Row {
+	index := make(map[string]Row, len(rows))
+	for _, row := range rows {
+		index[Hash(row, volatile)] = row
+	}
+	return index
+}
+
+// Compute compares the indexed rows of the current run with the previous
+// one. Every row of a first run is reported as added.
+func Compute(previous, current map[string]Row) Changes {
+	changes := Changes{
+		Added:   make(map[string]Row),
+		Removed: make(map[string]Row),
+	}
+	for hash, row := range current {
+		if _, ok := previous[hash]; !ok {
+			changes.Added[hash] = row
+		}
+	}
+	for hash, row := range previous {
+		if _, ok := current[hash]; !ok {
+			changes.Removed[hash] = row
+		}
+	}
+	return changes
+}
@@ -0,0 +1,176 @@
+package diff
+
+import (
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	volatile := []string{"last_opened_time"}
+
+	tests := []struct {
+		name  string
+		a, b  Row
+		equal bool
+	}{
+		{
+			name:  "same columns",
+			a:     Row{"name": "Safari", "version": "17.0"},
+			b:     Row{"version": "17.0", "name": "Safari"},
+			equal: true,
+		},
+		{
+			name:  "volatile column changed",
+			a:     Row{"name": "Safari", "version": "17.0", "last_opened_time": "1700000000"},
+			b:     Row{"name": "Safari", "version": "17.0", "last_opened_time": "1700009999"},
+			equal: true,
+		},
+		{
+			name:  "volatile column missing",
+			a:     Row{"name": "Safari", "version": "17.0", "last_opened_time": "1700000000"},
+			b:     Row{"name": "Safari", "version": "17.0"},
+			equal: true,
+		},
+		{
+			name:  "stable column changed",
+			a:     Row{"name": "Safari", "version": "17.0"},
+			b:     Row{"name": "Safari", "version": "17.1"},
+			equal: false,
+		},
+		{
+			name:  "empty value differs from missing column",
+			a:     Row{"name": "Safari", "path": ""},
+			b:     Row{"name": "Safari"},
+			equal: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			equal := Hash(tt.a, volatile) == Hash(tt.b, volatile)
+			if equal != tt.equal {
+				t.Errorf("Hash(%v) == Hash(%v) is %v, want %v", tt.a, tt.b, equal, tt.equal)
+			}
+		})
+	}
+}
+
+func TestHashKeepsVolatileColumns(t *testing.T) {
+	row := Row{"name": "Safari", "last_opened_time": "1700000000"}
+	Hash(row, []string{"last_opened_time"})
+	if _, ok := row["last_opened_time"]; !ok {
+		t.Error("Hash removed a volatile column from the row")
+	}
+}
+
+func TestIndex(t *testing.T) {
+	volatile := []string{"last_opened_time"}
+
+	tests := []struct {
+		name string
+		rows []Row
+		want int
+	}{
+		{name: "no rows", rows: nil, want: 0},
+		{
+			name: "distinct rows",
+			rows: []Row{{"name": "Safari"}, {"name": "Mail"}},
+			want: 2,
+		},
+		{
+			name: "duplicate rows",
+			rows: []Row{{"name": "Safari"}, {"name": "Safari"}, {"name": "Mail"}},
+			want: 2,
+		},
+		{
+			name: "duplicates differing in a volatile column",
+			rows: []Row{
+				{"name": "Safari", "last_opened_time": "1"},
+				{"name": "Safari", "last_opened_time": "2"},
+			},
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(Index(tt.rows, volatile)); got != tt.want {
+				t.Errorf("len(Index()) = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompute(t *testing.T) {
+	volatile := []string{"last_opened_time"}
+	safari := Row{"name": "Safari", "version": "17.0", "last_opened_time": "1"}
+	safariOpened := Row{"name": "Safari", "version": "17.0", "last_opened_time": "2"}
+	safariUpdated := Row{"name": "Safari", "version": "17.1", "last_opened_time": "2"}
+	mail := Row{"name": "Mail", "version": "16.0"}
+
+	tests := []struct {
+		name     string
+		previous []Row
+		current  []Row
+		added    []Row
+		removed  []Row
+	}{
+		{
+			name:    "first run",
+			current: []Row{safari, mail},
+			added:   []Row{safari, mail},
+		},
+		{
+			name:     "unchanged",
+			previous: []Row{safari, mail},
+			current:  []Row{safari, mail},
+		},
+		{
+			name:     "only a volatile column changed",
+			previous: []Row{safari},
+			current:  []Row{safariOpened},
+		},
+		{
+			name:     "row updated",
+			previous: []Row{safari, mail},
+			current:  []Row{safariUpdated, mail},
+			added:    []Row{safariUpdated},
+			removed:  []Row{safari},
+		},
+		{
+			name:     "row removed",
+			previous: []Row{safari, mail},
+			current:  []Row{mail},
+			removed:  []Row{safari},
+		},
+		{
+			name:     "duplicate rows",
+			previous: []Row{mail},
+			current:  []Row{mail, mail, safari, safari},
+			added:    []Row{safari},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := Compute(Index(tt.previous, volatile), Index(tt.current, volatile))
+			checkRows(t, "added", changes.Added, tt.added, volatile)
+			checkRows(t, "removed", changes.Removed, tt.removed, volatile)
+			if empty := len(tt.added) == 0 && len(tt.removed) == 0; changes.Empty() != empty {
+				t.Errorf("Empty() = %v, want %v", changes.Empty(), empty)
+			}
+		})
+	}
+}
+
+// checkRows checks that the rows of a change set are the expected ones
+func checkRows(t *testing.T, kind string, got map[string]Row, want []Row, volatile []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%d rows %s, want %d", len(got), kind, len(want))
+	}
+	for _, row := range want {
+		if _, ok := got[Hash(row, volatile)]; !ok {
+			t.Errorf("row %v not %s", row, kind)
+		}
+	}
+}
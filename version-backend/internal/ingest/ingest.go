@@ -0,0 +1,222 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"version-backend/internal/cpe"
+	"version-backend/internal/db"
+	"version-backend/internal/db/models"
+	"version-backend/internal/osquery"
+	"version-backend/pkg/logger"
+)
+
+// LatencyTarget is how long saving a snapshot should take at most, such as
+// a host with 400 apps on a database next to the server.
+// BenchmarkSaveSystemInfo in internal/db saves one in 30 to 40 ms on
+// SQLite, and the target leaves about three times that for the round trips
+// to a database server. Slower saves are logged and counted in the stats.
+const LatencyTarget = 100 * time.Millisecond
+
+// ErrInvalidPayload is returned when a snapshot fails validation
+var ErrInvalidPayload = errors.New("invalid payload")
+
+// ErrHostMismatch is returned when a node sends a snapshot of another host
+var ErrHostMismatch = errors.New("snapshot host does not match the enrolled node")
+
+// Payload is the body agents POST to /api/ingest
+type Payload struct {
+	Results osquery.Results `json:"results"`
+}
+
+// Stats summarizes the time taken to save the snapshots ingested since the
+// server started. Unchanged counts the snapshots skipped because they were
+// identical to the latest of their host, which are not timed.
+type Stats struct {
+	Snapshots int64
+	Unchanged int64
+	Slow      int64
+	Total     time.Duration
+	Max       time.Duration
+	Last      time.Duration
+}
+
+// Service validates collected snapshots and stores them
+type Service struct {
+	db       db.Store
+	registry *osquery.Registry
+	cpes     *cpe.Inferrer
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// NewService creates a new ingestion service. cpes infers the CPE names of
+// installed apps and may be nil to leave them unset.
+func NewService(db db.Store, registry *osquery.Registry, cpes *cpe.Inferrer) *Service {
+	return &Service{
+		db:       db,
+		registry: registry,
+		cpes:     cpes,
+	}
+}
+
+// IngestNode maps the raw collector results sent by an enrolled agent or
+// osqueryd node into a snapshot, checks it describes the node's own host and
+// saves it. It reports whether the snapshot was saved like Save. A node
+// enrolled without a host UUID is bound to the host of its first snapshot,
+// provided no other active node owns that host.
+func (s *Service) IngestNode(node *models.Node, results osquery.Results) (*models.SystemInfo, bool, error) {
+	snapshot, err := s.registry.Map(results)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	if err := Validate(snapshot); err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	switch {
+	case node.HostUUID == "":
+		if err := s.db.ClaimHost(node.ID, snapshot.Host); err != nil {
+			if errors.Is(err, db.ErrHostClaimed) {
+				return nil, false, ErrHostMismatch
+			}
+			return nil, false, err
+		}
+		node.HostUUID = snapshot.Host.UUID
+	case snapshot.Host.UUID != node.HostUUID:
+		return nil, false, ErrHostMismatch
+	}
+
+	saved, err := s.Save(snapshot, results)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := s.db.MarkCollected(node.ID, snapshot.Host.UUID); err != nil {
+		return nil, false, err
+	}
+
+	return snapshot, saved, nil
+}
+
+// Save validates a snapshot and saves it to the database, along with the
+// raw collector results it was mapped from for change detection. The CPE
+// names of its apps are inferred first. A snapshot with the same content
+// hash as the latest of its host is not written, only the host is marked
+// as seen. It reports whether the snapshot was saved.
+func (s *Service) Save(snapshot *models.SystemInfo, results osquery.Results) (bool, error) {
+	if err := Validate(snapshot); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	if s.cpes != nil {
+		for i := range snapshot.InstalledApps {
+			snapshot.InstalledApps[i].CPE = s.cpes.Infer(&snapshot.InstalledApps[i])
+		}
+	}
+
+	// Collectors missing from the results did not run, so they are left
+	// out of change detection rather than treated as returning no rows
+	queries := make(map[string]db.QueryRows)
+	for _, collector := range s.registry.Collectors() {
+		rows, ok := results[collector.Name()]
+		if !ok {
+			continue
+		}
+		queries[collector.Name()] = db.QueryRows{
+			Table:    collector.Table(),
+			Rows:     rows,
+			Volatile: collector.Volatile(),
+		}
+	}
+
+	// The content hash is compared in the save transaction, with the host
+	// locked, so concurrent collections of a host cannot both be skipped
+	// or both be saved
+	start := time.Now()
+	saved, err := s.db.SaveSystemInfo(snapshot, queries)
+	if err != nil {
+		return false, fmt.Errorf("failed to save system info: %w", err)
+	}
+	if !saved {
+		s.mu.Lock()
+		s.stats.Unchanged++
+		s.mu.Unlock()
+		return false, nil
+	}
+	s.record(snapshot, time.Since(start))
+
+	return true, nil
+}
+
+// record adds the time taken to save a snapshot to the stats
+func (s *Service) record(snapshot *models.SystemInfo, elapsed time.Duration) {
+	s.mu.Lock()
+	s.stats.Snapshots++
+	s.stats.Total += elapsed
+	s.stats.Last = elapsed
+	if elapsed > s.stats.Max {
+		s.stats.Max = elapsed
+	}
+	slow := elapsed > LatencyTarget
+	if slow {
+		s.stats.Slow++
+	}
+	s.mu.Unlock()
+
+	if slow {
+		logger.Warn("Snapshot save exceeded latency target", map[string]interface{}{
+			"host_uuid": snapshot.Host.UUID,
+			"apps":      len(snapshot.InstalledApps),
+			"elapsed":   elapsed.String(),
+			"target":    LatencyTarget.String(),
+		})
+	}
+}
+
+// Stats returns a copy of the ingestion stats
+func (s *Service) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Validate checks that a snapshot carries the fields needed to store it
+func Validate(snapshot *models.SystemInfo) error {
+	if snapshot.Host == nil || snapshot.Host.UUID == "" {
+		return fmt.Errorf("missing host identity")
+	}
+	if snapshot.OSName == "" || snapshot.OSPlatform == "" {
+		return fmt.Errorf("missing OS version")
+	}
+	if snapshot.OsqueryVersion == "" {
+		return fmt.Errorf("missing osquery version")
+	}
+
+	for _, app := range snapshot.InstalledApps {
+		if app.Name == "" || app.Path == "" {
+			return fmt.Errorf("installed app without name or path")
+		}
+	}
+	for _, pkg := range snapshot.Packages {
+		if pkg.Name == "" || pkg.Source == "" {
+			return fmt.Errorf("package without name or source")
+		}
+	}
+	for _, pkg := range snapshot.LangPackages {
+		if pkg.Name == "" || pkg.Ecosystem == "" {
+			return fmt.Errorf("language package without name or ecosystem")
+		}
+	}
+	for _, ext := range snapshot.Extensions {
+		if ext.Identifier == "" || ext.Browser == "" {
+			return fmt.Errorf("browser extension without identifier or browser")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,335 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"version-backend/internal/db"
+	"version-backend/internal/db/models"
+	"version-backend/internal/osquery"
+	"version-backend/pkg/logger"
+)
+
+// QueryPrefix prefixes the distributed query names used to run live queries
+// on remote nodes. It is followed by the campaign ID.
+const QueryPrefix = "live:"
+
+// ErrInvalidQuery is returned when a live query has no SQL statement
+var ErrInvalidQuery = errors.New("a query is required")
+
+// ErrInvalidSelector is returned when a host selector selects nothing
+var ErrInvalidSelector = errors.New("a host selector is required")
+
+// ErrNoTargets is returned when no reachable host matches the selector
+var ErrNoTargets = errors.New("no reachable host matches the selector")
+
+// Selector chooses the hosts a live query runs on. Hosts listed by ID or
+// UUID are selected, or every host with All; Platforms then restricts the
+// selection to hosts of the given platforms.
+type Selector struct {
+	All       bool     `json:"all,omitempty"`
+	HostIDs   []int64  `json:"host_ids,omitempty"`
+	HostUUIDs []string `json:"host_uuids,omitempty"`
+	Platforms []string `json:"platforms,omitempty"`
+}
+
+// Service fans live queries out to the local osquery socket and to enrolled
+// remote nodes, and collects their results into campaigns
+type Service struct {
+	db    db.Store
+	local *osquery.Client
+
+	mu      sync.Mutex
+	waiters map[int64]chan models.CampaignResult
+}
+
+// NewService creates a new live query service. local may be nil when the
+// server does not collect from a local osquery socket.
+func NewService(db db.Store, local *osquery.Client) *Service {
+	return &Service{
+		db:      db,
+		local:   local,
+		waiters: make(map[int64]chan models.CampaignResult),
+	}
+}
+
+// Run starts a campaign running the query on every host matching the
+// selector. It returns the campaign, its targets and a channel receiving each
+// host result as it arrives. Hosts that have not answered within the timeout
+// are reported as timed out before the channel is closed. The campaign runs
+// to completion even if the caller stops reading.
+func (s *Service) Run(ctx context.Context, query string, selector Selector, timeout time.Duration) (*models.Campaign, []models.CampaignResult, <-chan models.CampaignResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil, nil, ErrInvalidQuery
+	}
+
+	targets, local, err := s.resolveTargets(ctx, selector)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(targets) == 0 {
+		return nil, nil, nil, ErrNoTargets
+	}
+
+	encodedSelector, err := json.Marshal(selector)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error encoding selector: %w", err)
+	}
+
+	// Both channels hold one result per target, so sends never block
+	updates := make(chan models.CampaignResult, len(targets))
+	results := make(chan models.CampaignResult, len(targets))
+
+	// Nodes can pick the query up as soon as the campaign is created, so
+	// the waiter is registered under the same lock. A result recorded in
+	// between waits for the lock and then finds the waiter.
+	s.mu.Lock()
+	campaign, err := s.db.CreateCampaign(query, string(encodedSelector), targets)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, nil, nil, err
+	}
+	s.waiters[campaign.ID] = updates
+	s.mu.Unlock()
+
+	logger.Info("Started live query campaign", map[string]interface{}{
+		"campaign_id": campaign.ID,
+		"targets":     len(targets),
+	})
+
+	if local != nil {
+		go s.runLocal(campaign, local, timeout)
+	}
+	go s.collect(campaign, len(targets), timeout, updates, results)
+
+	return campaign, targets, results, nil
+}
+
+// PendingQueries returns the live queries waiting for a node, keyed by
+// distributed query name, and marks them as sent
+func (s *Service) PendingQueries(node *models.Node) (map[string]string, error) {
+	pending, err := s.db.ClaimPendingQueries(node.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make(map[string]string, len(pending))
+	for _, query := range pending {
+		queries[QueryPrefix+strconv.FormatInt(query.CampaignID, 10)] = query.Query
+	}
+	return queries, nil
+}
+
+// ParseQueryName extracts the campaign ID from a distributed query name. It
+// reports false for names that are not live queries.
+func ParseQueryName(name string) (int64, bool) {
+	id, ok := strings.CutPrefix(name, QueryPrefix)
+	if !ok {
+		return 0, false
+	}
+	campaignID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return campaignID, true
+}
+
+// RecordNodeResult stores the result of a live query sent back by a remote
+// node. Results for campaigns the node is not a target of, or that have
+// already timed out, are ignored.
+func (s *Service) RecordNodeResult(node *models.Node, campaignID int64, rows []map[string]string, queryErr error) error {
+	if node.HostUUID == "" {
+		return nil
+	}
+	return s.record(newResult(campaignID, node.HostUUID, node.HostIdentifier, rows, queryErr))
+}
+
+// runLocal runs the campaign query on the local osquery socket
+func (s *Service) runLocal(campaign *models.Campaign, local *osquery.HostIdentity, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	rows, err := s.local.Query(ctx, campaign.Query)
+	if err := s.record(newResult(campaign.ID, local.UUID, local.Hostname, rows, err)); err != nil {
+		logger.Error("Failed to store local live query result", err, map[string]interface{}{
+			"campaign_id": campaign.ID,
+		})
+	}
+}
+
+// record stores a host result and forwards it to the campaign waiting for it
+func (s *Service) record(result *models.CampaignResult) error {
+	stored, err := s.db.CompleteCampaignResult(result)
+	if err != nil || !stored {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if updates, ok := s.waiters[result.CampaignID]; ok {
+		select {
+		case updates <- *result:
+		default:
+		}
+	}
+	return nil
+}
+
+// collect forwards host results until every target has answered or the
+// timeout expires, then reports the remaining targets as timed out
+func (s *Service) collect(campaign *models.Campaign, targets int, timeout time.Duration, updates <-chan models.CampaignResult, results chan<- models.CampaignResult) {
+	defer close(results)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	received := 0
+	for received < targets {
+		select {
+		case result := <-updates:
+			results <- result
+			received++
+		case <-timer.C:
+			targets = received
+		}
+	}
+
+	s.mu.Lock()
+	delete(s.waiters, campaign.ID)
+	s.mu.Unlock()
+
+	// Results stored after the timeout fired but before the waiter was
+	// removed are still buffered
+	for drained := false; !drained; {
+		select {
+		case result := <-updates:
+			results <- result
+			received++
+		default:
+			drained = true
+		}
+	}
+
+	expired, err := s.db.ExpireCampaign(campaign.ID)
+	if err != nil {
+		logger.Error("Failed to expire live query campaign", err, map[string]interface{}{
+			"campaign_id": campaign.ID,
+		})
+	}
+	for _, result := range expired {
+		results <- result
+	}
+
+	logger.Info("Finished live query campaign", map[string]interface{}{
+		"campaign_id": campaign.ID,
+		"answered":    received,
+		"timed_out":   len(expired),
+	})
+}
+
+// resolveTargets lists the reachable hosts matching the selector. The local
+// osquery host is preferred over a remote node reporting the same host.
+func (s *Service) resolveTargets(ctx context.Context, selector Selector) ([]models.CampaignResult, *osquery.HostIdentity, error) {
+	if !selector.All && len(selector.HostIDs) == 0 && len(selector.HostUUIDs) == 0 && len(selector.Platforms) == 0 {
+		return nil, nil, ErrInvalidSelector
+	}
+
+	uuids := make(map[string]bool)
+	for _, uuid := range selector.HostUUIDs {
+		uuids[uuid] = true
+	}
+	for _, id := range selector.HostIDs {
+		host, err := s.db.GetHost(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		uuids[host.UUID] = true
+	}
+
+	matches := func(uuid, platform, platformLike string) bool {
+		if len(uuids) > 0 && !selector.All && !uuids[uuid] {
+			return false
+		}
+		return osquery.MatchesPlatform(selector.Platforms, platform, platformLike)
+	}
+
+	var targets []models.CampaignResult
+	seen := make(map[string]bool)
+
+	var local *osquery.HostIdentity
+	if s.local != nil {
+		host, err := s.local.Identify(ctx)
+		if err != nil {
+			logger.Error("Failed to identify the local osquery host", err, nil)
+		} else if matches(host.UUID, host.Platform, host.PlatformLike) {
+			local = host
+			seen[host.UUID] = true
+			targets = append(targets, models.CampaignResult{
+				HostUUID:       host.UUID,
+				HostIdentifier: host.Hostname,
+			})
+		}
+	}
+
+	nodes, err := s.db.ListNodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, node := range nodes {
+		if node.RevokedAt != nil || node.HostUUID == "" || seen[node.HostUUID] {
+			continue
+		}
+		if !matches(node.HostUUID, node.Platform, node.PlatformLike) {
+			continue
+		}
+		seen[node.HostUUID] = true
+		nodeID := node.ID
+		targets = append(targets, models.CampaignResult{
+			NodeID:         &nodeID,
+			HostUUID:       node.HostUUID,
+			HostIdentifier: node.HostIdentifier,
+		})
+	}
+
+	return targets, local, nil
+}
+
+// newResult builds the stored result of a live query on a host
+func newResult(campaignID int64, hostUUID, hostIdentifier string, rows []map[string]string, queryErr error) *models.CampaignResult {
+	result := &models.CampaignResult{
+		CampaignID:     campaignID,
+		HostUUID:       hostUUID,
+		HostIdentifier: hostIdentifier,
+		Status:         models.CampaignStatusCompleted,
+	}
+
+	if queryErr != nil {
+		message := queryErr.Error()
+		result.Status = models.CampaignStatusFailed
+		result.Error = &message
+		return result
+	}
+
+	if rows == nil {
+		rows = []map[string]string{}
+	}
+	encoded, err := json.Marshal(rows)
+	if err != nil {
+		message := fmt.Sprintf("error encoding rows: %v", err)
+		result.Status = models.CampaignStatusFailed
+		result.Error = &message
+		return result
+	}
+
+	results := string(encoded)
+	result.RowCount = len(rows)
+	result.Results = &results
+	return result
+}
@@ -3,7 +3,6 @@ package osquery
 import (
 	"context"
 	"fmt"
-	"strconv"
 	"time"
 
 	"version-backend/internal/db/models"
@@ -39,60 +38,67 @@ func (c *Client) Close() {
 	}
 }
 
-// GetSystemInfo retrieves system information using osquery
-func (c *Client) GetSystemInfo(ctx context.Context) (*models.SystemInfo, error) {
-	// Get OS version
-	osVersionRows, err := c.instance.QueryContext(ctx, Queries.GetOSVersion)
+// Collect runs a single collector against osquery and maps the returned
+// rows into the snapshot. The raw rows are returned so they can be forwarded
+// to a remote server. Errors reported by osquery itself, such as a missing
+// table, are returned as errors rather than mapped as no rows.
+func (c *Client) Collect(ctx context.Context, collector Collector, snapshot *models.SystemInfo) ([]map[string]string, error) {
+	rows, err := c.instance.QueryContext(ctx, collector.Query())
 	if err != nil {
-		return nil, fmt.Errorf("error querying OS version: %w", err)
+		return nil, fmt.Errorf("error querying %s: %w", collector.Name(), err)
 	}
-	if len(osVersionRows.Response) == 0 {
-		return nil, fmt.Errorf("no OS version information found")
+	if rows.Status != nil && rows.Status.Code != 0 {
+		return nil, fmt.Errorf("osquery error in %s: %s", collector.Name(), rows.Status.Message)
 	}
 
-	// Get osquery version
-	osqueryVersionRows, err := c.instance.QueryContext(ctx, Queries.GetOsqueryVersion)
-	if err != nil {
-		return nil, fmt.Errorf("error querying osquery version: %w", err)
-	}
-	if len(osqueryVersionRows.Response) == 0 {
-		return nil, fmt.Errorf("no osquery version information found")
+	if err := collector.Map(rows.Response, snapshot); err != nil {
+		return nil, fmt.Errorf("error mapping %s: %w", collector.Name(), err)
 	}
 
-	// Get installed applications
-	appsRows, err := c.instance.QueryContext(ctx, Queries.GetInstalledApps)
+	return rows.Response, nil
+}
+
+// Query runs an arbitrary SQL statement against osquery and returns the
+// resulting rows. Errors reported by osquery itself are returned as errors.
+func (c *Client) Query(ctx context.Context, sql string) ([]map[string]string, error) {
+	rows, err := c.instance.QueryContext(ctx, sql)
 	if err != nil {
-		return nil, fmt.Errorf("error querying installed applications: %w", err)
+		return nil, fmt.Errorf("error querying osquery: %w", err)
 	}
-
-	// Create system info
-	sysInfo := &models.SystemInfo{
-		OSName:         osVersionRows.Response[0]["name"],
-		OSVersion:      osVersionRows.Response[0]["version"],
-		OSPlatform:     osVersionRows.Response[0]["platform"],
-		OsqueryVersion: osqueryVersionRows.Response[0]["version"],
-		InstalledApps:  make([]models.InstalledApp, 0, len(appsRows.Response)),
+	if rows.Status != nil && rows.Status.Code != 0 {
+		return nil, fmt.Errorf("osquery error: %s", rows.Status.Message)
 	}
 
-	// Add installed applications
-	for _, row := range appsRows.Response {
-		var lastOpenedTime float64
-		if timestamp, err := strconv.ParseFloat(row["last_opened_time"], 64); err == nil {
-			lastOpenedTime = timestamp
-		}
+	return rows.Response, nil
+}
 
-		app := models.InstalledApp{
-			Name:                 row["name"],
-			Path:                 row["path"],
-			BundleIdentifier:     row["bundle_identifier"],
-			BundleName:           row["bundle_name"],
-			BundleShortVersion:   row["bundle_short_version"],
-			DisplayName:          row["display_name"],
-			MinimumSystemVersion: row["minimum_system_version"],
-			LastOpenedTime:       lastOpenedTime,
-		}
-		sysInfo.InstalledApps = append(sysInfo.InstalledApps, app)
+// HostIdentity describes the host behind an osquery socket
+type HostIdentity struct {
+	UUID           string
+	Hostname       string
+	Platform       string
+	PlatformLike   string
+	OsqueryVersion string
+}
+
+// Identify returns the identity of the host behind the osquery socket
+func (c *Client) Identify(ctx context.Context) (*HostIdentity, error) {
+	rows, err := c.Query(ctx, `
+		SELECT s.uuid, s.hostname, o.platform, o.platform_like, i.version
+		FROM system_info s, os_version o, osquery_info i
+	`)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 || rows[0]["uuid"] == "" {
+		return nil, fmt.Errorf("osquery returned no host uuid")
 	}
 
-	return sysInfo, nil
+	return &HostIdentity{
+		UUID:           rows[0]["uuid"],
+		Hostname:       rows[0]["hostname"],
+		Platform:       rows[0]["platform"],
+		PlatformLike:   rows[0]["platform_like"],
+		OsqueryVersion: rows[0]["version"],
+	}, nil
 }
@@ -0,0 +1,140 @@
+package osquery
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"version-backend/internal/db/models"
+)
+
+// Collector is a self-contained unit of data collection. It declares the
+// osquery SQL it runs, how the resulting rows map onto a snapshot, where the
+// data is stored and which API endpoint exposes it.
+type Collector interface {
+	// Name uniquely identifies the collector within a registry
+	Name() string
+
+	// Query returns the osquery SQL executed by the collector
+	Query() string
+
+	// Table returns the database table the collected data is stored in
+	Table() string
+
+	// Endpoint returns the API path exposing the collected data
+	Endpoint() string
+
+	// Platforms returns the platforms the collector applies to. An empty
+	// list means the collector runs everywhere.
+	Platforms() []string
+
+	// Volatile returns the result columns that change without the collected
+	// data changing, such as a last opened time. They are ignored by change
+	// detection.
+	Volatile() []string
+
+	// Map converts the rows returned by Query into the snapshot
+	Map(rows []map[string]string, snapshot *models.SystemInfo) error
+}
+
+// Results holds the raw rows returned by each collector, keyed by collector name
+type Results map[string][]map[string]string
+
+// Supports reports whether the collector applies to the platform of the
+// snapshot. It relies on the os_version fields, so it must be evaluated after
+// the OS version has been collected.
+func Supports(c Collector, snapshot *models.SystemInfo) bool {
+	return MatchesPlatform(c.Platforms(), snapshot.OSPlatform, snapshot.OSPlatformLike)
+}
+
+// MatchesPlatform reports whether an os_version platform and platform_like
+// pair matches any of the given platforms. An empty list matches everything.
+func MatchesPlatform(platforms []string, platform, platformLike string) bool {
+	if len(platforms) == 0 {
+		return true
+	}
+
+	// os_version reports the distribution id as the platform on Linux, with
+	// the distribution family in platform_like
+	tokens := append([]string{platform}, strings.Fields(platformLike)...)
+	switch platform {
+	case "darwin", "windows", "freebsd", "":
+	default:
+		tokens = append(tokens, "linux")
+	}
+
+	for _, candidate := range platforms {
+		for _, token := range tokens {
+			if candidate == token {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Registry holds an ordered set of collectors
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []Collector
+	names      map[string]struct{}
+}
+
+// NewRegistry creates an empty collector registry
+func NewRegistry() *Registry {
+	return &Registry{
+		names: make(map[string]struct{}),
+	}
+}
+
+// Register adds a collector to the registry. Collectors run in the order
+// they were registered.
+func (r *Registry) Register(c Collector) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.names[c.Name()]; exists {
+		return fmt.Errorf("collector %s is already registered", c.Name())
+	}
+
+	r.names[c.Name()] = struct{}{}
+	r.collectors = append(r.collectors, c)
+	return nil
+}
+
+// Collectors returns the registered collectors in registration order
+func (r *Registry) Collectors() []Collector {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	collectors := make([]Collector, len(r.collectors))
+	copy(collectors, r.collectors)
+	return collectors
+}
+
+// Map converts collector results into a snapshot, running every collector
+// that applies to the snapshot platform in registration order. Collectors
+// missing from the results are mapped with no rows, so required data sets
+// such as the OS version fail the mapping.
+func (r *Registry) Map(results Results) (*models.SystemInfo, error) {
+	snapshot := &models.SystemInfo{}
+	for _, collector := range r.Collectors() {
+		if !Supports(collector, snapshot) {
+			continue
+		}
+		if err := collector.Map(results[collector.Name()], snapshot); err != nil {
+			return nil, fmt.Errorf("error mapping %s: %w", collector.Name(), err)
+		}
+	}
+	return snapshot, nil
+}
+
+// DefaultRegistry returns a registry populated with the built-in collectors
+func DefaultRegistry() *Registry {
+	registry := NewRegistry()
+	for _, c := range builtinCollectors() {
+		// Built-in collector names are unique, so registration cannot fail
+		_ = registry.Register(c)
+	}
+	return registry
+}
@@ -0,0 +1,491 @@
+package osquery
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"version-backend/internal/db/models"
+)
+
+// collectorSpec holds the static description shared by the built-in collectors
+type collectorSpec struct {
+	name      string
+	query     string
+	table     string
+	endpoint  string
+	platforms []string
+	volatile  []string
+}
+
+// Name returns the collector name
+func (s collectorSpec) Name() string { return s.name }
+
+// Query returns the osquery SQL executed by the collector
+func (s collectorSpec) Query() string { return s.query }
+
+// Table returns the database table the collected data is stored in
+func (s collectorSpec) Table() string { return s.table }
+
+// Endpoint returns the API path exposing the collected data
+func (s collectorSpec) Endpoint() string { return s.endpoint }
+
+// Platforms returns the platforms the collector applies to
+func (s collectorSpec) Platforms() []string { return s.platforms }
+
+// Volatile returns the columns ignored by change detection
+func (s collectorSpec) Volatile() []string { return s.volatile }
+
+// builtinCollectors returns the collectors shipped with the backend
+func builtinCollectors() []Collector {
+	return []Collector{
+		osVersionCollector{collectorSpec{
+			name:     "os_version",
+			query:    Queries.GetOSVersion,
+			table:    "system_info",
+			endpoint: "/api/latest_data",
+		}},
+		osqueryInfoCollector{collectorSpec{
+			name:     "osquery_info",
+			query:    Queries.GetOsqueryVersion,
+			table:    "system_info",
+			endpoint: "/api/latest_data",
+		}},
+		hostCollector{collectorSpec{
+			name:     "system_info",
+			query:    Queries.GetSystemInfo,
+			table:    "hosts",
+			endpoint: "/api/latest_data",
+		}},
+		platformInfoCollector{collectorSpec{
+			name:     "platform_info",
+			query:    Queries.GetPlatformInfo,
+			table:    "hosts",
+			endpoint: "/api/latest_data",
+		}},
+		installedAppsCollector{collectorSpec{
+			name:      "installed_apps",
+			query:     Queries.GetInstalledApps,
+			table:     "installed_apps",
+			endpoint:  "/api/latest_data",
+			platforms: []string{"darwin"},
+			volatile:  []string{"last_opened_time"},
+		}},
+		packageCollector{collectorSpec{
+			name:      "deb_packages",
+			query:     Queries.GetDebPackages,
+			table:     "installed_packages",
+			endpoint:  "/api/latest_data",
+			platforms: []string{"debian"},
+		}, "deb", mapDebRow},
+		packageCollector{collectorSpec{
+			name:      "rpm_packages",
+			query:     Queries.GetRpmPackages,
+			table:     "installed_packages",
+			endpoint:  "/api/latest_data",
+			platforms: []string{"rhel", "fedora", "centos", "suse", "amzn"},
+		}, "rpm", mapRpmRow},
+		packageCollector{collectorSpec{
+			name:      "apk_packages",
+			query:     Queries.GetApkPackages,
+			table:     "installed_packages",
+			endpoint:  "/api/latest_data",
+			platforms: []string{"alpine"},
+		}, "apk", mapPackageRow},
+		packageCollector{collectorSpec{
+			name:      "snap_packages",
+			query:     Queries.GetSnapPackages,
+			table:     "installed_packages",
+			endpoint:  "/api/latest_data",
+			platforms: []string{"linux"},
+		}, "snap", mapSnapRow},
+		packageCollector{collectorSpec{
+			name:      "flatpak_packages",
+			query:     Queries.GetFlatpakPackages,
+			table:     "installed_packages",
+			endpoint:  "/api/latest_data",
+			platforms: []string{"linux"},
+		}, "flatpak", mapFlatpakRow},
+		languagePackageCollector{collectorSpec{
+			name:     "python_packages",
+			query:    Queries.GetPythonPackages,
+			table:    "language_packages",
+			endpoint: "/api/packages",
+		}, "pip", mapLanguagePackageRow},
+		languagePackageCollector{collectorSpec{
+			name:     "npm_packages",
+			query:    Queries.GetNpmPackages,
+			table:    "language_packages",
+			endpoint: "/api/packages",
+		}, "npm", mapLanguagePackageRow},
+		languagePackageCollector{collectorSpec{
+			name:      "ruby_gems",
+			query:     Queries.GetRubyGems,
+			table:     "language_packages",
+			endpoint:  "/api/packages",
+			platforms: []string{"darwin", "linux"},
+		}, "gem", mapGemRow},
+		languagePackageCollector{collectorSpec{
+			name:      "go_modules",
+			query:     Queries.GetGoModules,
+			table:     "language_packages",
+			endpoint:  "/api/packages",
+			platforms: []string{"darwin", "linux"},
+		}, "go", mapGoModuleRow},
+		extensionCollector{collectorSpec{
+			name:     "chrome_extensions",
+			query:    Queries.GetChromeExtensions,
+			table:    "browser_extensions",
+			endpoint: "/api/extensions",
+		}, "chrome"},
+		extensionCollector{collectorSpec{
+			name:     "firefox_addons",
+			query:    Queries.GetFirefoxAddons,
+			table:    "browser_extensions",
+			endpoint: "/api/extensions",
+		}, "firefox"},
+		extensionCollector{collectorSpec{
+			name:      "safari_extensions",
+			query:     Queries.GetSafariExtensions,
+			table:     "browser_extensions",
+			endpoint:  "/api/extensions",
+			platforms: []string{"darwin"},
+		}, "safari"},
+	}
+}
+
+// osVersionCollector collects the operating system version
+type osVersionCollector struct{ collectorSpec }
+
+// Map sets the OS fields of the snapshot
+func (osVersionCollector) Map(rows []map[string]string, snapshot *models.SystemInfo) error {
+	if len(rows) == 0 {
+		return fmt.Errorf("no OS version information found")
+	}
+
+	snapshot.OSName = rows[0]["name"]
+	snapshot.OSVersion = rows[0]["version"]
+	snapshot.OSPlatform = rows[0]["platform"]
+	snapshot.OSPlatformLike = rows[0]["platform_like"]
+	return nil
+}
+
+// osqueryInfoCollector collects the running osquery version
+type osqueryInfoCollector struct{ collectorSpec }
+
+// Map sets the osquery version of the snapshot
+func (osqueryInfoCollector) Map(rows []map[string]string, snapshot *models.SystemInfo) error {
+	if len(rows) == 0 {
+		return fmt.Errorf("no osquery version information found")
+	}
+
+	snapshot.OsqueryVersion = rows[0]["version"]
+	return nil
+}
+
+// hostCollector collects the host identity and hardware
+type hostCollector struct{ collectorSpec }
+
+// Map sets the host identity of the snapshot
+func (hostCollector) Map(rows []map[string]string, snapshot *models.SystemInfo) error {
+	if len(rows) == 0 || rows[0]["uuid"] == "" {
+		return fmt.Errorf("no host identity information found")
+	}
+
+	if snapshot.Host == nil {
+		snapshot.Host = &models.Host{}
+	}
+
+	row := rows[0]
+	snapshot.Host.UUID = row["uuid"]
+	snapshot.Host.Hostname = row["hostname"]
+	snapshot.Host.ComputerName = row["computer_name"]
+	snapshot.Host.CPUBrand = row["cpu_brand"]
+	snapshot.Host.CPUType = row["cpu_type"]
+	snapshot.Host.CPUPhysicalCores, _ = strconv.Atoi(row["cpu_physical_cores"])
+	snapshot.Host.CPULogicalCores, _ = strconv.Atoi(row["cpu_logical_cores"])
+	snapshot.Host.PhysicalMemory, _ = strconv.ParseInt(row["physical_memory"], 10, 64)
+	snapshot.Host.HardwareVendor = row["hardware_vendor"]
+	snapshot.Host.HardwareModel = row["hardware_model"]
+	snapshot.Host.HardwareSerial = row["hardware_serial"]
+	return nil
+}
+
+// platformInfoCollector collects the firmware information of the host
+type platformInfoCollector struct{ collectorSpec }
+
+// Map sets the firmware fields of the snapshot host. platform_info may be
+// empty when osquery lacks the privileges to read it.
+func (platformInfoCollector) Map(rows []map[string]string, snapshot *models.SystemInfo) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	if snapshot.Host == nil {
+		snapshot.Host = &models.Host{}
+	}
+
+	snapshot.Host.PlatformVendor = rows[0]["vendor"]
+	snapshot.Host.PlatformVersion = rows[0]["version"]
+	snapshot.Host.PlatformDate = rows[0]["date"]
+	snapshot.Host.PlatformRevision = rows[0]["revision"]
+	return nil
+}
+
+// installedAppsCollector collects the installed macOS applications
+type installedAppsCollector struct{ collectorSpec }
+
+// Map appends the installed applications to the snapshot
+func (installedAppsCollector) Map(rows []map[string]string, snapshot *models.SystemInfo) error {
+	snapshot.InstalledApps = make([]models.InstalledApp, 0, len(rows))
+	for _, row := range rows {
+		var lastOpenedTime float64
+		if timestamp, err := strconv.ParseFloat(row["last_opened_time"], 64); err == nil {
+			lastOpenedTime = timestamp
+		}
+
+		app := models.InstalledApp{
+			Name:                 row["name"],
+			Path:                 row["path"],
+			BundleIdentifier:     row["bundle_identifier"],
+			BundleName:           row["bundle_name"],
+			BundleShortVersion:   row["bundle_short_version"],
+			DisplayName:          row["display_name"],
+			MinimumSystemVersion: row["minimum_system_version"],
+			LastOpenedTime:       lastOpenedTime,
+		}
+		snapshot.InstalledApps = append(snapshot.InstalledApps, app)
+	}
+	return nil
+}
+
+// packageCollector collects the packages of one OS package manager into the
+// common installed-software model
+type packageCollector struct {
+	collectorSpec
+	source string
+	mapRow func(row map[string]string) (models.SoftwarePackage, bool)
+}
+
+// Map appends the packages to the snapshot, tagged with the package source
+func (c packageCollector) Map(rows []map[string]string, snapshot *models.SystemInfo) error {
+	for _, row := range rows {
+		pkg, ok := c.mapRow(row)
+		if !ok {
+			continue
+		}
+		pkg.Source = c.source
+		snapshot.Packages = append(snapshot.Packages, pkg)
+	}
+	return nil
+}
+
+// mapPackageRow maps package manager tables exposing name, version and arch
+func mapPackageRow(row map[string]string) (models.SoftwarePackage, bool) {
+	return models.SoftwarePackage{
+		Name:    row["name"],
+		Version: row["version"],
+		Arch:    row["arch"],
+	}, row["name"] != ""
+}
+
+// mapDebRow maps a deb_packages row. Its source is the Source field of
+// dpkg, the source package name followed by its version when that differs
+// from the version of the binary package, as in openssl (3.0.11-1).
+func mapDebRow(row map[string]string) (models.SoftwarePackage, bool) {
+	pkg, ok := mapPackageRow(row)
+	pkg.SourcePackage, _, _ = strings.Cut(strings.TrimSpace(row["source"]), " ")
+	return pkg, ok
+}
+
+// mapRpmRow maps an rpm_packages row, combining epoch, version and release
+// into the full EVR string
+func mapRpmRow(row map[string]string) (models.SoftwarePackage, bool) {
+	version := row["version"]
+	if row["release"] != "" {
+		version += "-" + row["release"]
+	}
+	if epoch := row["epoch"]; epoch != "" && epoch != "0" {
+		version = epoch + ":" + version
+	}
+
+	installTime, _ := strconv.ParseInt(row["install_time"], 10, 64)
+	return models.SoftwarePackage{
+		Name:        row["name"],
+		Version:     version,
+		Arch:        row["arch"],
+		InstallTime: installTime,
+	}, row["name"] != ""
+}
+
+// mapSnapRow maps a snap file named <name>_<revision>.snap. The file name
+// only holds the revision snapd assigned, not the version of the snap, so
+// the version is left empty rather than compared as one.
+func mapSnapRow(row map[string]string) (models.SoftwarePackage, bool) {
+	base := strings.TrimSuffix(row["filename"], ".snap")
+	sep := strings.LastIndex(base, "_")
+	if sep <= 0 {
+		return models.SoftwarePackage{}, false
+	}
+	name, revision := base[:sep], base[sep+1:]
+
+	installTime, _ := strconv.ParseInt(row["mtime"], 10, 64)
+	return models.SoftwarePackage{
+		Name:        name,
+		Revision:    revision,
+		InstallTime: installTime,
+	}, true
+}
+
+// mapFlatpakRow maps a flatpak deployment directory laid out as
+// /var/lib/flatpak/app/<id>/<arch>/<branch>. The branch, such as stable, is
+// a release channel rather than a version, so it is stored as the revision
+// and the version is left empty.
+func mapFlatpakRow(row map[string]string) (models.SoftwarePackage, bool) {
+	rel := strings.TrimPrefix(path.Clean(row["path"]), "/var/lib/flatpak/app/")
+	parts := strings.Split(rel, "/")
+	if len(parts) != 3 || parts[1] == "current" {
+		return models.SoftwarePackage{}, false
+	}
+
+	installTime, _ := strconv.ParseInt(row["mtime"], 10, 64)
+	return models.SoftwarePackage{
+		Name:        parts[0],
+		Arch:        parts[1],
+		Revision:    parts[2],
+		InstallTime: installTime,
+	}, true
+}
+
+// languagePackageCollector collects the packages of one language ecosystem
+type languagePackageCollector struct {
+	collectorSpec
+	ecosystem string
+	mapRow    func(row map[string]string) (models.LanguagePackage, bool)
+}
+
+// Map appends the language packages to the snapshot, tagged with the ecosystem
+func (c languagePackageCollector) Map(rows []map[string]string, snapshot *models.SystemInfo) error {
+	for _, row := range rows {
+		pkg, ok := c.mapRow(row)
+		if !ok {
+			continue
+		}
+		pkg.Ecosystem = c.ecosystem
+		snapshot.LangPackages = append(snapshot.LangPackages, pkg)
+	}
+	return nil
+}
+
+// mapLanguagePackageRow maps package tables exposing name, version and path
+func mapLanguagePackageRow(row map[string]string) (models.LanguagePackage, bool) {
+	return models.LanguagePackage{
+		Name:     row["name"],
+		Version:  row["version"],
+		Path:     row["path"],
+		Username: row["username"],
+	}, row["name"] != ""
+}
+
+// gemspecPattern splits a gemspec file name into name, version and an
+// optional platform suffix, e.g. nokogiri-1.15.4-x86_64-linux.gemspec
+var gemspecPattern = regexp.MustCompile(`^(.+?)-(\d[^-]*)(?:-.+)?\.gemspec$`)
+
+// mapGemRow maps a gemspec file to a Ruby gem
+func mapGemRow(row map[string]string) (models.LanguagePackage, bool) {
+	match := gemspecPattern.FindStringSubmatch(row["filename"])
+	if match == nil {
+		return models.LanguagePackage{}, false
+	}
+
+	return models.LanguagePackage{
+		Name:     match[1],
+		Version:  match[2],
+		Path:     row["path"],
+		Username: row["username"],
+	}, true
+}
+
+// mapGoModuleRow maps a module zip in the Go module download cache, laid out
+// as .../cache/download/<escaped module path>/@v/<version>.zip
+func mapGoModuleRow(row map[string]string) (models.LanguagePackage, bool) {
+	_, rel, ok := strings.Cut(row["path"], "/cache/download/")
+	if !ok {
+		return models.LanguagePackage{}, false
+	}
+	module, file, ok := strings.Cut(rel, "/@v/")
+	if !ok || strings.Contains(file, "/") {
+		return models.LanguagePackage{}, false
+	}
+
+	return models.LanguagePackage{
+		Name:     unescapeModulePath(module),
+		Version:  unescapeModulePath(strings.TrimSuffix(file, ".zip")),
+		Path:     row["path"],
+		Username: row["username"],
+	}, true
+}
+
+// unescapeModulePath reverses the module cache case encoding, where each
+// upper-case letter is stored as '!' followed by its lower-case form
+func unescapeModulePath(escaped string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range escaped {
+		switch {
+		case r == '!':
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// extensionCollector collects the extensions of one browser family
+type extensionCollector struct {
+	collectorSpec
+	browser string
+}
+
+// Map appends the browser extensions to the snapshot
+func (c extensionCollector) Map(rows []map[string]string, snapshot *models.SystemInfo) error {
+	for _, row := range rows {
+		if row["identifier"] == "" {
+			continue
+		}
+
+		ext := models.BrowserExtension{
+			Browser:     c.browser,
+			Username:    row["username"],
+			Identifier:  row["identifier"],
+			Name:        row["name"],
+			Version:     row["version"],
+			Permissions: row["permissions"],
+			ProfilePath: row["profile_path"],
+			Path:        row["path"],
+		}
+
+		// chrome_extensions also covers Edge, Brave and other Chromium browsers
+		if browserType := row["browser_type"]; browserType != "" {
+			ext.Browser = browserType
+		}
+
+		// firefox_addons has no profile column, but add-ons live in
+		// <profile>/extensions
+		if ext.ProfilePath == "" && c.browser == "firefox" {
+			if profile, _, ok := strings.Cut(ext.Path, "/extensions/"); ok {
+				ext.ProfilePath = profile
+			}
+		}
+
+		snapshot.Extensions = append(snapshot.Extensions, ext)
+	}
+	return nil
+}
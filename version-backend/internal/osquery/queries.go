@@ -8,14 +8,57 @@ var Queries = struct {
 	// GetOsqueryVersion retrieves the installed osquery version
 	GetOsqueryVersion string
 
+	// GetSystemInfo retrieves the host identity and hardware information
+	GetSystemInfo string
+
+	// GetPlatformInfo retrieves the firmware information of the host
+	GetPlatformInfo string
+
 	// GetInstalledApps retrieves the list of installed applications
 	GetInstalledApps string
+
+	// GetDebPackages retrieves the packages installed through dpkg
+	GetDebPackages string
+
+	// GetRpmPackages retrieves the packages installed through rpm
+	GetRpmPackages string
+
+	// GetApkPackages retrieves the packages installed through apk
+	GetApkPackages string
+
+	// GetSnapPackages retrieves the snap packages stored by snapd
+	GetSnapPackages string
+
+	// GetFlatpakPackages retrieves the system-wide flatpak applications
+	GetFlatpakPackages string
+
+	// GetPythonPackages retrieves the installed Python packages with their owner
+	GetPythonPackages string
+
+	// GetNpmPackages retrieves the installed npm packages with their owner
+	GetNpmPackages string
+
+	// GetRubyGems retrieves the installed Ruby gems from their gemspec files
+	GetRubyGems string
+
+	// GetGoModules retrieves the Go modules present in the users' module caches
+	GetGoModules string
+
+	// GetChromeExtensions retrieves the Chromium-based browser extensions of every user
+	GetChromeExtensions string
+
+	// GetFirefoxAddons retrieves the Firefox add-ons of every user
+	GetFirefoxAddons string
+
+	// GetSafariExtensions retrieves the Safari extensions of every user
+	GetSafariExtensions string
 }{
 	GetOSVersion: `
 		SELECT
 			name,
 			version,
-			platform
+			platform,
+			platform_like
 		FROM os_version
 		LIMIT 1;
 	`,
@@ -27,6 +70,33 @@ var Queries = struct {
 		LIMIT 1;
 	`,
 
+	GetSystemInfo: `
+		SELECT
+			uuid,
+			hostname,
+			computer_name,
+			cpu_brand,
+			cpu_type,
+			cpu_physical_cores,
+			cpu_logical_cores,
+			physical_memory,
+			hardware_vendor,
+			hardware_model,
+			hardware_serial
+		FROM system_info
+		LIMIT 1;
+	`,
+
+	GetPlatformInfo: `
+		SELECT
+			vendor,
+			version,
+			date,
+			revision
+		FROM platform_info
+		LIMIT 1;
+	`,
+
 	GetInstalledApps: `
 		SELECT
 			name,
@@ -43,4 +113,152 @@ var Queries = struct {
 			AND path LIKE '/Applications/%'
 		ORDER BY last_opened_time DESC;
 	`,
+
+	GetDebPackages: `
+		SELECT
+			name,
+			version,
+			arch,
+			source
+		FROM deb_packages
+		ORDER BY name;
+	`,
+
+	GetRpmPackages: `
+		SELECT
+			name,
+			version,
+			release,
+			epoch,
+			arch,
+			install_time
+		FROM rpm_packages
+		ORDER BY name;
+	`,
+
+	GetApkPackages: `
+		SELECT
+			name,
+			version,
+			arch
+		FROM apk_packages
+		ORDER BY name;
+	`,
+
+	GetSnapPackages: `
+		SELECT
+			filename,
+			mtime
+		FROM file
+		WHERE path LIKE '/var/lib/snapd/snaps/%.snap'
+		ORDER BY filename;
+	`,
+
+	GetFlatpakPackages: `
+		SELECT
+			path,
+			mtime
+		FROM file
+		WHERE
+			path LIKE '/var/lib/flatpak/app/%/%/%'
+			AND type = 'directory'
+		ORDER BY path;
+	`,
+
+	GetPythonPackages: `
+		SELECT
+			p.name,
+			p.version,
+			p.path,
+			u.username
+		FROM python_packages p
+		LEFT JOIN file f ON f.path = p.path
+		LEFT JOIN users u ON u.uid = f.uid
+		ORDER BY p.name;
+	`,
+
+	GetNpmPackages: `
+		SELECT
+			p.name,
+			p.version,
+			p.path,
+			u.username
+		FROM npm_packages p
+		LEFT JOIN file f ON f.path = p.path
+		LEFT JOIN users u ON u.uid = f.uid
+		ORDER BY p.name;
+	`,
+
+	GetRubyGems: `
+		SELECT
+			f.path,
+			f.filename,
+			u.username
+		FROM file f
+		LEFT JOIN users u ON u.uid = f.uid
+		WHERE
+			f.path LIKE '/usr/lib/ruby/gems/%/specifications/%.gemspec'
+			OR f.path LIKE '/usr/local/lib/ruby/gems/%/specifications/%.gemspec'
+			OR f.path LIKE '/var/lib/gems/%/specifications/%.gemspec'
+			OR f.path LIKE '/Library/Ruby/Gems/%/specifications/%.gemspec'
+			OR f.path LIKE '/home/%/.gem/ruby/%/specifications/%.gemspec'
+			OR f.path LIKE '/Users/%/.gem/ruby/%/specifications/%.gemspec'
+		ORDER BY f.filename;
+	`,
+
+	GetGoModules: `
+		SELECT
+			f.path,
+			f.filename,
+			u.username
+		FROM file f
+		LEFT JOIN users u ON u.uid = f.uid
+		WHERE
+			(
+				f.path LIKE '/home/%/go/pkg/mod/cache/download/%%'
+				OR f.path LIKE '/Users/%/go/pkg/mod/cache/download/%%'
+				OR f.path LIKE '/root/go/pkg/mod/cache/download/%%'
+			)
+			AND f.filename LIKE '%.zip'
+		ORDER BY f.path;
+	`,
+
+	GetChromeExtensions: `
+		SELECT
+			u.username,
+			c.browser_type,
+			c.name,
+			c.identifier,
+			c.version,
+			c.permissions,
+			c.profile_path,
+			c.path
+		FROM users u
+		CROSS JOIN chrome_extensions c USING (uid)
+		ORDER BY c.identifier;
+	`,
+
+	GetFirefoxAddons: `
+		SELECT
+			u.username,
+			f.name,
+			f.identifier,
+			f.version,
+			f.path
+		FROM users u
+		CROSS JOIN firefox_addons f USING (uid)
+		ORDER BY f.identifier;
+	`,
+
+	GetSafariExtensions: `
+		SELECT
+			u.username,
+			s.name,
+			s.identifier,
+			s.version,
+			s.path
+		FROM users u
+		CROSS JOIN safari_extensions s USING (uid)
+		ORDER BY s.identifier;
+	`,
 }
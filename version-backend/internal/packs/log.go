@@ -0,0 +1,80 @@
+package packs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"version-backend/internal/db/models"
+)
+
+// Delimiter separates the pack and query names in the names of scheduled
+// queries reported by osqueryd. It is sent to nodes as the pack_delimiter
+// option.
+const Delimiter = "/"
+
+// resultLogLine is a line of osqueryd's result log, in either the event,
+// batch or snapshot format
+type resultLogLine struct {
+	Name        string              `json:"name"`
+	UnixTime    json.RawMessage     `json:"unixTime"`
+	Action      string              `json:"action"`
+	Columns     map[string]string   `json:"columns"`
+	Snapshot    []map[string]string `json:"snapshot"`
+	DiffResults struct {
+		Added   []map[string]string `json:"added"`
+		Removed []map[string]string `json:"removed"`
+	} `json:"diffResults"`
+}
+
+// ResultsFromLog converts a line of osqueryd's result log into query
+// results for the host. Lines of queries outside packs are ignored.
+func ResultsFromLog(hostUUID string, raw json.RawMessage) ([]models.QueryResult, error) {
+	var line resultLogLine
+	if err := json.Unmarshal(raw, &line); err != nil {
+		return nil, fmt.Errorf("error decoding result log: %w", err)
+	}
+
+	parts := strings.SplitN(line.Name, Delimiter, 3)
+	if len(parts) != 3 || parts[0] != "pack" {
+		return nil, nil
+	}
+
+	collectedAt := time.Now()
+	if unixTime, err := strconv.ParseInt(strings.Trim(string(line.UnixTime), `"`), 10, 64); err == nil {
+		collectedAt = time.Unix(unixTime, 0)
+	}
+
+	var results []models.QueryResult
+	add := func(action string, row map[string]string) {
+		columns, _ := json.Marshal(row)
+		results = append(results, models.QueryResult{
+			HostUUID:    hostUUID,
+			Pack:        parts[1],
+			QueryName:   parts[2],
+			Action:      action,
+			Columns:     string(columns),
+			CollectedAt: collectedAt,
+		})
+	}
+
+	switch {
+	case line.Action == models.QueryActionSnapshot:
+		for _, row := range line.Snapshot {
+			add(models.QueryActionSnapshot, row)
+		}
+	case line.Action == models.QueryActionAdded || line.Action == models.QueryActionRemoved:
+		add(line.Action, line.Columns)
+	default:
+		for _, row := range line.DiffResults.Added {
+			add(models.QueryActionAdded, row)
+		}
+		for _, row := range line.DiffResults.Removed {
+			add(models.QueryActionRemoved, row)
+		}
+	}
+
+	return results, nil
+}
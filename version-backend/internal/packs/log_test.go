@@ -0,0 +1,109 @@
+package packs
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"version-backend/internal/db/models"
+)
+
+func TestResultsFromLog(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []models.QueryResult
+	}{
+		{
+			name: "event format",
+			line: `{"name": "pack/security/listening_ports", "unixTime": 1700000000, "action": "added",
+				"columns": {"port": "22"}}`,
+			want: []models.QueryResult{
+				{Pack: "security", QueryName: "listening_ports", Action: "added", Columns: `{"port":"22"}`},
+			},
+		},
+		{
+			name: "batch format",
+			line: `{"name": "pack/security/listening_ports", "unixTime": "1700000000",
+				"diffResults": {"added": [{"port": "443"}], "removed": [{"port": "22"}, {"port": "80"}]}}`,
+			want: []models.QueryResult{
+				{Pack: "security", QueryName: "listening_ports", Action: "added", Columns: `{"port":"443"}`},
+				{Pack: "security", QueryName: "listening_ports", Action: "removed", Columns: `{"port":"22"}`},
+				{Pack: "security", QueryName: "listening_ports", Action: "removed", Columns: `{"port":"80"}`},
+			},
+		},
+		{
+			name: "snapshot format",
+			line: `{"name": "pack/hardware/usb_devices", "unixTime": 1700000000, "action": "snapshot",
+				"snapshot": [{"model": "Keyboard"}, {"model": "Mouse"}]}`,
+			want: []models.QueryResult{
+				{Pack: "hardware", QueryName: "usb_devices", Action: "snapshot", Columns: `{"model":"Keyboard"}`},
+				{Pack: "hardware", QueryName: "usb_devices", Action: "snapshot", Columns: `{"model":"Mouse"}`},
+			},
+		},
+		{
+			name: "query name containing the delimiter",
+			line: `{"name": "pack/security/ports/tcp", "unixTime": 1700000000, "action": "removed", "columns": {}}`,
+			want: []models.QueryResult{
+				{Pack: "security", QueryName: "ports/tcp", Action: "removed", Columns: `{}`},
+			},
+		},
+		{
+			name: "query outside packs",
+			line: `{"name": "listening_ports", "unixTime": 1700000000, "action": "added", "columns": {"port": "22"}}`,
+		},
+		{
+			name: "other prefix",
+			line: `{"name": "schedule/security/listening_ports", "unixTime": 1700000000, "action": "added", "columns": {}}`,
+		},
+		{
+			name: "pack name without query",
+			line: `{"name": "pack/security", "unixTime": 1700000000, "action": "added", "columns": {}}`,
+		},
+		{
+			name: "empty batch",
+			line: `{"name": "pack/security/listening_ports", "unixTime": 1700000000, "diffResults": {}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := ResultsFromLog("host", json.RawMessage(tt.line))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != len(tt.want) {
+				t.Fatalf("%d results, want %d: %+v", len(results), len(tt.want), results)
+			}
+			for i, got := range results {
+				want := tt.want[i]
+				if got.HostUUID != "host" || got.Pack != want.Pack || got.QueryName != want.QueryName ||
+					got.Action != want.Action || got.Columns != want.Columns {
+					t.Errorf("result %d = %+v, want %+v", i, got, want)
+				}
+				if !got.CollectedAt.Equal(time.Unix(1700000000, 0)) {
+					t.Errorf("result %d collected at %s, want the unixTime of the line", i, got.CollectedAt)
+				}
+			}
+		})
+	}
+}
+
+func TestResultsFromLogTime(t *testing.T) {
+	before := time.Now().Add(-time.Second)
+	results, err := ResultsFromLog("host", json.RawMessage(`{"name": "pack/p/q", "unixTime": "soon", "action": "added", "columns": {}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].CollectedAt.Before(before) {
+		t.Errorf("results = %+v, want one collected now", results)
+	}
+}
+
+func TestResultsFromLogInvalid(t *testing.T) {
+	for _, line := range []string{`{"name": `, `"pack/p/q"`, `{"name": "pack/p/q", "snapshot": "rows"}`} {
+		if _, err := ResultsFromLog("host", json.RawMessage(line)); err == nil {
+			t.Errorf("ResultsFromLog(%s) accepted invalid input", line)
+		}
+	}
+}
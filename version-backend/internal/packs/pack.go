@@ -0,0 +1,191 @@
+package packs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"version-backend/internal/osquery"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Pack is a set of scheduled queries, in osquery's pack format
+type Pack struct {
+	Name      string           `json:"-" yaml:"-"`
+	Platform  string           `json:"platform,omitempty" yaml:"platform,omitempty"`
+	Version   string           `json:"version,omitempty" yaml:"version,omitempty"`
+	Discovery []string         `json:"discovery,omitempty" yaml:"discovery,omitempty"`
+	Queries   map[string]Query `json:"queries" yaml:"queries"`
+}
+
+// Query is a scheduled query of a pack. Queries are differential unless
+// Snapshot is set: each run only reports the rows added or removed since the
+// previous run.
+type Query struct {
+	Query       string `json:"query" yaml:"query"`
+	Interval    int    `json:"interval" yaml:"interval"`
+	Platform    string `json:"platform,omitempty" yaml:"platform,omitempty"`
+	Version     string `json:"version,omitempty" yaml:"version,omitempty"`
+	Snapshot    bool   `json:"snapshot,omitempty" yaml:"snapshot,omitempty"`
+	Removed     *bool  `json:"removed,omitempty" yaml:"removed,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// ReportsRemoved reports whether removed rows of a differential query are
+// recorded. osquery records them unless removed is explicitly false.
+func (q Query) ReportsRemoved() bool {
+	return q.Removed == nil || *q.Removed
+}
+
+// Load reads every pack in a directory. Packs are read from .json, .conf,
+// .yaml and .yml files and named after the file. A missing directory holds
+// no packs.
+func Load(dir string) ([]Pack, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading pack directory: %w", err)
+	}
+
+	var packs []Pack
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		ext := filepath.Ext(entry.Name())
+		switch ext {
+		case ".json", ".conf", ".yaml", ".yml":
+		default:
+			continue
+		}
+
+		pack, err := LoadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, *pack)
+	}
+
+	sort.Slice(packs, func(i, j int) bool {
+		return packs[i].Name < packs[j].Name
+	})
+	return packs, nil
+}
+
+// LoadFile reads and validates a single pack file
+func LoadFile(path string) (*Pack, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading pack %s: %w", path, err)
+	}
+
+	ext := filepath.Ext(path)
+	pack := &Pack{Name: strings.TrimSuffix(filepath.Base(path), ext)}
+	switch ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, pack)
+	default:
+		err = json.Unmarshal(data, pack)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing pack %s: %w", path, err)
+	}
+
+	if err := pack.validate(); err != nil {
+		return nil, fmt.Errorf("invalid pack %s: %w", path, err)
+	}
+	return pack, nil
+}
+
+// validate checks every query of the pack can be scheduled
+func (p *Pack) validate() error {
+	if len(p.Queries) == 0 {
+		return fmt.Errorf("pack has no queries")
+	}
+	for name, query := range p.Queries {
+		if strings.TrimSpace(query.Query) == "" {
+			return fmt.Errorf("query %s has no SQL", name)
+		}
+		if query.Interval <= 0 {
+			return fmt.Errorf("query %s has no interval", name)
+		}
+	}
+	return nil
+}
+
+// QueryNames returns the names of the pack queries in sorted order
+func (p *Pack) QueryNames() []string {
+	names := make([]string, 0, len(p.Queries))
+	for name := range p.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Applies reports whether a query of the pack runs on a host, based on the
+// pack and query platforms and minimum osquery versions
+func (p *Pack) Applies(query Query, host *osquery.HostIdentity) bool {
+	return matchesPlatform(p.Platform, host) &&
+		matchesPlatform(query.Platform, host) &&
+		versionAtLeast(host.OsqueryVersion, p.Version) &&
+		versionAtLeast(host.OsqueryVersion, query.Version)
+}
+
+// matchesPlatform matches an osquery pack platform list such as
+// "darwin,linux" against a host. posix covers every platform but Windows.
+func matchesPlatform(platforms string, host *osquery.HostIdentity) bool {
+	var candidates []string
+	for _, platform := range strings.Split(platforms, ",") {
+		switch platform = strings.TrimSpace(platform); platform {
+		case "", "all", "any":
+			return true
+		case "posix":
+			candidates = append(candidates, "darwin", "linux", "freebsd")
+		default:
+			candidates = append(candidates, platform)
+		}
+	}
+	return osquery.MatchesPlatform(candidates, host.Platform, host.PlatformLike)
+}
+
+// versionAtLeast reports whether a dotted version is at least the minimum.
+// An empty minimum is always satisfied.
+func versionAtLeast(version, minimum string) bool {
+	if minimum == "" {
+		return true
+	}
+
+	have := strings.Split(version, ".")
+	want := strings.Split(minimum, ".")
+	for i := range want {
+		var h int
+		if i < len(have) {
+			h = leadingInt(have[i])
+		}
+		w := leadingInt(want[i])
+		if h != w {
+			return h > w
+		}
+	}
+	return true
+}
+
+// leadingInt parses the leading digits of a version component, so that
+// "2-rc1" reads as 2
+func leadingInt(s string) int {
+	end := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if end >= 0 {
+		s = s[:end]
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,223 @@
+package packs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"version-backend/internal/osquery"
+)
+
+// writePack writes a pack file to a directory and returns its path
+func writePack(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		err     string
+		queries []string
+	}{
+		{
+			name: "JSON pack",
+			file: "security.json",
+			content: `{"platform": "darwin", "queries": {
+				"listening_ports": {"query": "SELECT * FROM listening_ports;", "interval": 300},
+				"startup_items": {"query": "SELECT * FROM startup_items;", "interval": 3600, "snapshot": true}
+			}}`,
+			queries: []string{"listening_ports", "startup_items"},
+		},
+		{
+			name:    "osquery .conf pack",
+			file:    "incident.conf",
+			content: `{"queries": {"processes": {"query": "SELECT * FROM processes;", "interval": 60}}}`,
+			queries: []string{"processes"},
+		},
+		{
+			name: "YAML pack",
+			file: "hardware.yaml",
+			content: `
+queries:
+  usb_devices:
+    query: SELECT * FROM usb_devices;
+    interval: 600
+    removed: false
+`,
+			queries: []string{"usb_devices"},
+		},
+		{name: "no queries", file: "empty.json", content: `{"queries": {}}`, err: "pack has no queries"},
+		{
+			name:    "query without SQL",
+			file:    "nosql.json",
+			content: `{"queries": {"blank": {"query": "  ", "interval": 60}}}`,
+			err:     "query blank has no SQL",
+		},
+		{
+			name:    "query without interval",
+			file:    "nointerval.json",
+			content: `{"queries": {"once": {"query": "SELECT 1;"}}}`,
+			err:     "query once has no interval",
+		},
+		{name: "invalid JSON", file: "broken.json", content: `{"queries": `, err: "error parsing pack"},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pack, err := LoadFile(writePack(t, dir, tt.file, tt.content))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("LoadFile() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := strings.TrimSuffix(tt.file, filepath.Ext(tt.file)); pack.Name != want {
+				t.Errorf("Name = %q, want %q", pack.Name, want)
+			}
+			if got := pack.QueryNames(); !reflect.DeepEqual(got, tt.queries) {
+				t.Errorf("QueryNames() = %v, want %v", got, tt.queries)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writePack(t, dir, "b.json", `{"queries": {"q": {"query": "SELECT 1;", "interval": 60}}}`)
+	writePack(t, dir, "a.yml", "queries:\n  q:\n    query: SELECT 1;\n    interval: 60\n")
+	writePack(t, dir, "notes.txt", "not a pack")
+
+	packs, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, pack := range packs {
+		names = append(names, pack.Name)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("loaded %v, want %v", names, want)
+	}
+
+	packs, err = Load(filepath.Join(dir, "missing"))
+	if err != nil || packs != nil {
+		t.Errorf("Load(missing) = %v, %v, want no packs", packs, err)
+	}
+
+	writePack(t, dir, "c.json", `{"queries": {}}`)
+	if _, err := Load(dir); err == nil {
+		t.Error("Load accepted a directory with an invalid pack")
+	}
+}
+
+func TestReportsRemoved(t *testing.T) {
+	no, yes := false, true
+	tests := []struct {
+		removed *bool
+		want    bool
+	}{
+		{nil, true},
+		{&yes, true},
+		{&no, false},
+	}
+
+	for _, tt := range tests {
+		if got := (Query{Removed: tt.removed}).ReportsRemoved(); got != tt.want {
+			t.Errorf("ReportsRemoved() with removed %v = %v, want %v", tt.removed, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesPlatform(t *testing.T) {
+	mac := &osquery.HostIdentity{Platform: "darwin"}
+	ubuntu := &osquery.HostIdentity{Platform: "ubuntu", PlatformLike: "debian"}
+	windows := &osquery.HostIdentity{Platform: "windows"}
+
+	tests := []struct {
+		platforms string
+		host      *osquery.HostIdentity
+		want      bool
+	}{
+		{"", windows, true},
+		{"all", windows, true},
+		{"any", mac, true},
+		{"darwin", mac, true},
+		{"darwin", ubuntu, false},
+		{"darwin, linux", ubuntu, true},
+		{"debian", ubuntu, true},
+		{"posix", mac, true},
+		{"posix", ubuntu, true},
+		{"posix", windows, false},
+		{"windows", windows, true},
+		{"linux,all", mac, true},
+	}
+
+	for _, tt := range tests {
+		if got := matchesPlatform(tt.platforms, tt.host); got != tt.want {
+			t.Errorf("matchesPlatform(%q, %s) = %v, want %v", tt.platforms, tt.host.Platform, got, tt.want)
+		}
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version string
+		minimum string
+		want    bool
+	}{
+		{"5.12.1", "", true},
+		{"5.12.1", "5.12.1", true},
+		{"5.12.1", "5.12", true},
+		{"5.12", "5.12.1", false},
+		{"5.12.1", "5.2.0", true},
+		{"5.9.0", "5.10.0", false},
+		{"6.0", "5.99", true},
+		{"5.12.0-rc1", "5.12", true},
+		{"", "1.0", false},
+	}
+
+	for _, tt := range tests {
+		if got := versionAtLeast(tt.version, tt.minimum); got != tt.want {
+			t.Errorf("versionAtLeast(%q, %q) = %v, want %v", tt.version, tt.minimum, got, tt.want)
+		}
+	}
+}
+
+func TestApplies(t *testing.T) {
+	host := &osquery.HostIdentity{Platform: "darwin", OsqueryVersion: "5.10.2"}
+
+	tests := []struct {
+		name  string
+		pack  Pack
+		query Query
+		want  bool
+	}{
+		{"no constraints", Pack{}, Query{}, true},
+		{"pack platform", Pack{Platform: "linux"}, Query{}, false},
+		{"query platform", Pack{Platform: "posix"}, Query{Platform: "windows"}, false},
+		{"pack version", Pack{Version: "5.11"}, Query{}, false},
+		{"query version", Pack{Version: "5.0"}, Query{Version: "5.10.2"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pack.Applies(tt.query, host); got != tt.want {
+				t.Errorf("Applies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
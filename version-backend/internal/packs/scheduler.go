@@ -0,0 +1,139 @@
+package packs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"version-backend/internal/db"
+	"version-backend/internal/db/models"
+	"version-backend/internal/diff"
+	"version-backend/internal/osquery"
+	"version-backend/pkg/logger"
+)
+
+// Scheduler runs the pack queries on the local osquery socket, each on its
+// own interval, and stores their results
+type Scheduler struct {
+	client *osquery.Client
+	db     db.Store
+	packs  []Pack
+}
+
+// NewScheduler creates a scheduler for the given packs
+func NewScheduler(client *osquery.Client, db db.Store, packs []Pack) *Scheduler {
+	return &Scheduler{
+		client: client,
+		db:     db,
+		packs:  packs,
+	}
+}
+
+// Run schedules every pack query that applies to the local host and blocks
+// until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context) error {
+	host, err := s.client.Identify(ctx)
+	if err != nil {
+		return err
+	}
+
+	scheduled := 0
+	done := make(chan struct{})
+	for _, pack := range s.packs {
+		for _, name := range pack.QueryNames() {
+			query := pack.Queries[name]
+			if !pack.Applies(query, host) {
+				continue
+			}
+
+			scheduled++
+			go func(pack, name string, query Query) {
+				s.schedule(ctx, host, pack, name, query)
+				done <- struct{}{}
+			}(pack.Name, name, query)
+		}
+	}
+
+	logger.Info("Scheduled pack queries", map[string]interface{}{
+		"packs":   len(s.packs),
+		"queries": scheduled,
+	})
+
+	for i := 0; i < scheduled; i++ {
+		<-done
+	}
+	return nil
+}
+
+// schedule runs a query immediately and then on every interval. The row
+// hashes of the previous run are kept to compute differential results.
+func (s *Scheduler) schedule(ctx context.Context, host *osquery.HostIdentity, pack, name string, query Query) {
+	ticker := time.NewTicker(time.Duration(query.Interval) * time.Second)
+	defer ticker.Stop()
+
+	var previous map[string]diff.Row
+	for {
+		current, err := s.run(ctx, host, pack, name, query, previous)
+		if err != nil {
+			logger.Error("Failed to run pack query", err, map[string]interface{}{
+				"pack":  pack,
+				"query": name,
+			})
+		} else {
+			previous = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run executes a query once and stores its results. It returns the rows of
+// the run keyed by hash.
+func (s *Scheduler) run(ctx context.Context, host *osquery.HostIdentity, pack, name string, query Query, previous map[string]diff.Row) (map[string]diff.Row, error) {
+	rows, err := s.client.Query(ctx, query.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	current := diff.Index(rows, nil)
+
+	collectedAt := time.Now()
+	result := func(action string, row map[string]string) models.QueryResult {
+		columns, _ := json.Marshal(row)
+		return models.QueryResult{
+			HostUUID:    host.UUID,
+			Pack:        pack,
+			QueryName:   name,
+			Action:      action,
+			Columns:     string(columns),
+			CollectedAt: collectedAt,
+		}
+	}
+
+	var results []models.QueryResult
+	if query.Snapshot {
+		for _, row := range rows {
+			results = append(results, result(models.QueryActionSnapshot, row))
+		}
+	} else {
+		// As in osquery, every row of the first run is reported as added
+		changes := diff.Compute(previous, current)
+		for _, row := range changes.Added {
+			results = append(results, result(models.QueryActionAdded, row))
+		}
+		if query.ReportsRemoved() {
+			for _, row := range changes.Removed {
+				results = append(results, result(models.QueryActionRemoved, row))
+			}
+		}
+	}
+
+	if err := s.db.SaveQueryResults(results); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
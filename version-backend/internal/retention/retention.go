@@ -0,0 +1,111 @@
+package retention
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"version-backend/internal/config"
+	"version-backend/internal/db"
+	"version-backend/pkg/logger"
+)
+
+// Metrics counts the work of the retention job since the server started
+type Metrics struct {
+	Runs          int64
+	Failures      int64
+	RowsPruned    int64
+	RowsCompacted int64
+	LastRunAt     time.Time
+	LastDuration  time.Duration
+	LastError     string
+}
+
+// Job prunes the archived rows of the versioned tables on an interval
+// according to the retention policy
+type Job struct {
+	db       db.Store
+	policy   db.RetentionPolicy
+	interval time.Duration
+
+	mu      sync.Mutex
+	metrics Metrics
+}
+
+// NewJob creates a retention job from the retention configuration
+func NewJob(store db.Store, cfg config.RetentionConfig) *Job {
+	return &Job{
+		db: store,
+		policy: db.RetentionPolicy{
+			MaxAge:       time.Duration(cfg.Days) * 24 * time.Hour,
+			MinSnapshots: cfg.MinSnapshots,
+			Compact:      cfg.Compact,
+		},
+		interval: time.Duration(cfg.Interval) * time.Second,
+	}
+}
+
+// Enabled reports whether the job runs, that is whether it has an interval
+// and the policy removes anything
+func (j *Job) Enabled() bool {
+	return j.interval > 0 && (j.policy.MaxAge > 0 || j.policy.Compact)
+}
+
+// Run prunes immediately and then on every interval until the context is
+// cancelled
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.RunOnce()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce prunes the archived rows once and records the outcome in
+// the metrics
+func (j *Job) RunOnce() (*db.PruneResult, error) {
+	start := time.Now()
+	result, err := j.db.PruneSnapshots(j.policy)
+
+	j.mu.Lock()
+	j.metrics.Runs++
+	j.metrics.LastRunAt = start
+	j.metrics.LastDuration = time.Since(start)
+	j.metrics.LastError = ""
+	if result != nil {
+		j.metrics.RowsPruned += result.RowsPruned
+		j.metrics.RowsCompacted += result.RowsCompacted
+	}
+	if err != nil {
+		j.metrics.Failures++
+		j.metrics.LastError = err.Error()
+	}
+	j.mu.Unlock()
+
+	if err != nil {
+		logger.Error("Failed to prune archived rows", err, nil)
+		return result, err
+	}
+	if result.RowsPruned > 0 || result.RowsCompacted > 0 {
+		logger.Info("Pruned archived rows", map[string]interface{}{
+			"rows_pruned":    result.RowsPruned,
+			"rows_compacted": result.RowsCompacted,
+			"duration":       time.Since(start).String(),
+		})
+	}
+	return result, nil
+}
+
+// Metrics returns a copy of the metrics of the job
+func (j *Job) Metrics() Metrics {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.metrics
+}
@@ -0,0 +1,99 @@
+package vuln
+
+import (
+	"testing"
+)
+
+func TestCVSS3Score(t *testing.T) {
+	tests := []struct {
+		vector string
+		want   float64
+		ok     bool
+	}{
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", 9.8, true},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", 10.0, true},
+		{"CVSS:3.0/AV:N/AC:L/PR:L/UI:N/S:C/C:H/I:H/A:H", 9.9, true},
+		{"CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:H/A:H", 7.8, true},
+		{"CVSS:3.1/AV:L/AC:L/PR:H/UI:N/S:U/C:H/I:H/A:H", 6.7, true},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N", 6.1, true},
+		{"CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:N/A:N", 5.9, true},
+
+		// No impact scores 0
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", 0, true},
+
+		// Metrics in another order
+		{"CVSS:3.1/S:U/C:H/I:H/A:H/AV:N/AC:L/PR:N/UI:N", 9.8, true},
+
+		// Invalid vectors
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H", 0, false},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:X/C:H/I:H/A:H", 0, false},
+		{"CVSS:3.1/AV:N/AC:L/PR:X/UI:N/S:U/C:H/I:H/A:H", 0, false},
+		{"AV:N/AC:L/Au:N/C:P/I:P/A:P", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := CVSS3Score(tt.vector)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("CVSS3Score(%q) = %v, %v, want %v, %v", tt.vector, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSeverityForScore(t *testing.T) {
+	tests := []struct {
+		score float64
+		want  string
+	}{
+		{10, SeverityCritical},
+		{9.0, SeverityCritical},
+		{8.9, SeverityHigh},
+		{7.0, SeverityHigh},
+		{6.9, SeverityMedium},
+		{4.0, SeverityMedium},
+		{3.9, SeverityLow},
+		{0.1, SeverityLow},
+		{0, SeverityNone},
+	}
+
+	for _, tt := range tests {
+		if got := SeverityForScore(tt.score); got != tt.want {
+			t.Errorf("SeverityForScore(%v) = %s, want %s", tt.score, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeSeverity(t *testing.T) {
+	tests := []struct {
+		severity string
+		want     string
+	}{
+		{"CRITICAL", SeverityCritical},
+		{"high", SeverityHigh},
+		{"Important", SeverityHigh},
+		{"MODERATE", SeverityMedium},
+		{" medium ", SeverityMedium},
+		{"low", SeverityLow},
+		{"NONE", SeverityNone},
+		{"unknown", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeSeverity(tt.severity); got != tt.want {
+			t.Errorf("normalizeSeverity(%q) = %q, want %q", tt.severity, got, tt.want)
+		}
+	}
+}
+
+func TestSeverityRank(t *testing.T) {
+	ordered := []string{"", SeverityNone, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+	for i := 1; i < len(ordered); i++ {
+		if SeverityRank(ordered[i-1]) >= SeverityRank(ordered[i]) {
+			t.Errorf("SeverityRank(%q) >= SeverityRank(%q)", ordered[i-1], ordered[i])
+		}
+	}
+	if SeverityRank("moderate") != SeverityRank(SeverityMedium) {
+		t.Error("SeverityRank(moderate) differs from SeverityRank(MEDIUM)")
+	}
+}
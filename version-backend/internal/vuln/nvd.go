@@ -0,0 +1,271 @@
+package vuln
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"version-backend/internal/cpe"
+)
+
+// nvdFeed is an NVD JSON feed, either a 2.0 feed or API response, with
+// vulnerabilities, or a legacy 1.1 feed with CVE_Items
+type nvdFeed struct {
+	Vulnerabilities []struct {
+		CVE nvdCVE `json:"cve"`
+	} `json:"vulnerabilities"`
+	Items []nvdItem `json:"CVE_Items"`
+}
+
+// nvdCVE is a CVE in the NVD 2.0 format
+type nvdCVE struct {
+	ID           string `json:"id"`
+	Descriptions []struct {
+		Lang  string `json:"lang"`
+		Value string `json:"value"`
+	} `json:"descriptions"`
+	Metrics struct {
+		V31 []nvdMetric `json:"cvssMetricV31"`
+		V30 []nvdMetric `json:"cvssMetricV30"`
+		V2  []nvdMetric `json:"cvssMetricV2"`
+	} `json:"metrics"`
+	Configurations []struct {
+		Nodes []nvdNode `json:"nodes"`
+	} `json:"configurations"`
+}
+
+// nvdMetric is a CVSS score of an NVD 2.0 CVE. Version 2 metrics carry
+// their rating outside cvssData.
+type nvdMetric struct {
+	Type     string `json:"type"`
+	CVSSData struct {
+		BaseScore    float64 `json:"baseScore"`
+		BaseSeverity string  `json:"baseSeverity"`
+	} `json:"cvssData"`
+	BaseSeverity string `json:"baseSeverity"`
+}
+
+// nvdItem is a CVE in the legacy NVD 1.1 format
+type nvdItem struct {
+	CVE struct {
+		Meta struct {
+			ID string `json:"ID"`
+		} `json:"CVE_data_meta"`
+		Description struct {
+			Data []struct {
+				Lang  string `json:"lang"`
+				Value string `json:"value"`
+			} `json:"description_data"`
+		} `json:"description"`
+	} `json:"cve"`
+	Configurations struct {
+		Nodes []nvdNode `json:"nodes"`
+	} `json:"configurations"`
+	Impact struct {
+		V3 struct {
+			CVSS struct {
+				BaseScore    float64 `json:"baseScore"`
+				BaseSeverity string  `json:"baseSeverity"`
+			} `json:"cvssV3"`
+		} `json:"baseMetricV3"`
+		V2 struct {
+			CVSS struct {
+				BaseScore float64 `json:"baseScore"`
+			} `json:"cvssV2"`
+			Severity string `json:"severity"`
+		} `json:"baseMetricV2"`
+	} `json:"impact"`
+}
+
+// nvdNode is a node of the configurations affected by a CVE. The 1.1
+// format nests nodes in children and names the matches cpe_match.
+type nvdNode struct {
+	Negate   bool       `json:"negate"`
+	CPEMatch []nvdMatch `json:"cpeMatch"`
+	Matches  []nvdMatch `json:"cpe_match"`
+	Children []nvdNode  `json:"children"`
+}
+
+// nvdMatch is a CPE affected by a CVE, in a version range when its version
+// is a wildcard. The 1.1 format names the CPE cpe23Uri.
+type nvdMatch struct {
+	Vulnerable            bool   `json:"vulnerable"`
+	Criteria              string `json:"criteria"`
+	CPE23URI              string `json:"cpe23Uri"`
+	VersionStartIncluding string `json:"versionStartIncluding"`
+	VersionStartExcluding string `json:"versionStartExcluding"`
+	VersionEndIncluding   string `json:"versionEndIncluding"`
+	VersionEndExcluding   string `json:"versionEndExcluding"`
+}
+
+// LoadNVD reads the CVEs of an NVD JSON feed file, gzipped when its name
+// ends in .gz as downloaded. Only application CPEs are kept, as they are
+// what the installed apps are matched with. A missing file holds no CVEs.
+func LoadNVD(path string) ([]*Advisory, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errorf(path, err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, errorf(path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var feed nvdFeed
+	if err := json.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, errorf(path, err)
+	}
+
+	var advisories []*Advisory
+	for _, v := range feed.Vulnerabilities {
+		if advisory := v.CVE.advisory(); len(advisory.Affected) > 0 {
+			advisories = append(advisories, advisory)
+		}
+	}
+	for _, item := range feed.Items {
+		if advisory := item.advisory(); len(advisory.Affected) > 0 {
+			advisories = append(advisories, advisory)
+		}
+	}
+	return advisories, nil
+}
+
+// advisory converts an NVD 2.0 CVE, scored by its primary CVSS v3 metric,
+// else its CVSS v2 one
+func (c *nvdCVE) advisory() *Advisory {
+	advisory := &Advisory{ID: c.ID, Source: "nvd"}
+	for _, d := range c.Descriptions {
+		if d.Lang == "en" {
+			advisory.Summary = d.Value
+			break
+		}
+	}
+
+	for _, metrics := range [][]nvdMetric{c.Metrics.V31, c.Metrics.V30, c.Metrics.V2} {
+		for _, m := range metrics {
+			if advisory.Score == 0 || m.Type == "Primary" {
+				advisory.Score = m.CVSSData.BaseScore
+				advisory.Severity = normalizeSeverity(m.CVSSData.BaseSeverity)
+				if advisory.Severity == "" {
+					advisory.Severity = normalizeSeverity(m.BaseSeverity)
+				}
+			}
+		}
+		if advisory.Score > 0 {
+			break
+		}
+	}
+	if advisory.Severity == "" && advisory.Score > 0 {
+		advisory.Severity = SeverityForScore(advisory.Score)
+	}
+
+	products := make(map[string]int)
+	for _, configuration := range c.Configurations {
+		addNVDNodes(advisory, products, configuration.Nodes)
+	}
+	return advisory
+}
+
+// advisory converts a CVE of a legacy 1.1 feed
+func (item *nvdItem) advisory() *Advisory {
+	advisory := &Advisory{ID: item.CVE.Meta.ID, Source: "nvd"}
+	for _, d := range item.CVE.Description.Data {
+		if d.Lang == "en" {
+			advisory.Summary = d.Value
+			break
+		}
+	}
+
+	switch {
+	case item.Impact.V3.CVSS.BaseScore > 0:
+		advisory.Score = item.Impact.V3.CVSS.BaseScore
+		advisory.Severity = normalizeSeverity(item.Impact.V3.CVSS.BaseSeverity)
+	case item.Impact.V2.CVSS.BaseScore > 0:
+		advisory.Score = item.Impact.V2.CVSS.BaseScore
+		advisory.Severity = normalizeSeverity(item.Impact.V2.Severity)
+	}
+	if advisory.Severity == "" && advisory.Score > 0 {
+		advisory.Severity = SeverityForScore(advisory.Score)
+	}
+
+	addNVDNodes(advisory, make(map[string]int), item.Configurations.Nodes)
+	return advisory
+}
+
+// addNVDNodes adds the vulnerable application CPEs of configuration nodes
+// to an advisory, one affected entry per vendor and product, indexed in
+// products. The platforms a configuration requires are not checked, so an
+// app is matched whatever OS it runs on, except for CPEs targeting another
+// OS than macOS.
+func addNVDNodes(advisory *Advisory, products map[string]int, nodes []nvdNode) {
+	for _, node := range nodes {
+		addNVDNodes(advisory, products, node.Children)
+		if node.Negate {
+			continue
+		}
+
+		for _, match := range append(node.CPEMatch, node.Matches...) {
+			if !match.Vulnerable {
+				continue
+			}
+			uri := match.Criteria
+			if uri == "" {
+				uri = match.CPE23URI
+			}
+			name, ok := cpe.Parse(uri)
+			if !ok || name.Part != "a" || !targetsMacOS(name.TargetSW) {
+				continue
+			}
+			// A version of - means the product has no versions, which
+			// no installed app version can be matched against
+			if name.Version == "-" {
+				continue
+			}
+
+			key := name.Vendor + ":" + name.Product
+			i, ok := products[key]
+			if !ok {
+				i = len(advisory.Affected)
+				products[key] = i
+				advisory.Affected = append(advisory.Affected, Affected{
+					Name:   name.Product,
+					Vendor: name.Vendor,
+				})
+			}
+			affected := &advisory.Affected[i]
+
+			switch {
+			case name.Version != "*":
+				affected.Versions = append(affected.Versions, name.Version)
+			default:
+				r := Range{
+					Introduced:   match.VersionStartIncluding,
+					Fixed:        match.VersionEndExcluding,
+					LastAffected: match.VersionEndIncluding,
+				}
+				if match.VersionStartExcluding != "" {
+					r.Introduced = match.VersionStartExcluding
+					r.IntroducedExcluded = true
+				}
+				affected.Ranges = append(affected.Ranges, r)
+			}
+		}
+	}
+}
+
+// targetsMacOS reports whether the target software of a CPE allows macOS
+func targetsMacOS(targetSW string) bool {
+	return targetSW == "*" || targetSW == "-" || strings.Contains(targetSW, "mac")
+}
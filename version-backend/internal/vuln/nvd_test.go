@@ -0,0 +1,215 @@
+package vuln
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"version-backend/pkg/version"
+)
+
+// nvdFeedJSON is an NVD 2.0 feed. CVE-2024-0002 only affects a CPE without
+// versions and apps of another OS, so it affects no macOS app.
+const nvdFeedJSON = `{
+	"vulnerabilities": [
+		{"cve": {
+			"id": "CVE-2024-0001",
+			"descriptions": [{"lang": "es", "value": "Desbordamiento"}, {"lang": "en", "value": "Overflow in Chrome"}],
+			"metrics": {
+				"cvssMetricV31": [
+					{"type": "Secondary", "cvssData": {"baseScore": 5.0, "baseSeverity": "MEDIUM"}},
+					{"type": "Primary", "cvssData": {"baseScore": 8.8, "baseSeverity": "HIGH"}}
+				]
+			},
+			"configurations": [{"nodes": [{
+				"cpeMatch": [
+					{"vulnerable": true, "criteria": "cpe:2.3:a:google:chrome:*:*:*:*:*:*:*:*", "versionEndExcluding": "120.0.6099.129"},
+					{"vulnerable": true, "criteria": "cpe:2.3:a:google:chrome:99.0:*:*:*:*:*:*:*"},
+					{"vulnerable": false, "criteria": "cpe:2.3:a:google:chrome_os:*:*:*:*:*:*:*:*"},
+					{"vulnerable": true, "criteria": "cpe:2.3:o:apple:macos:*:*:*:*:*:*:*:*"}
+				],
+				"children": [{"cpeMatch": [
+					{"vulnerable": true, "criteria": "cpe:2.3:a:mozilla:firefox:*:*:*:*:*:macos:*:*", "versionStartIncluding": "115.0", "versionEndIncluding": "115.5"}
+				]}]
+			}]}]
+		}},
+		{"cve": {
+			"id": "CVE-2024-0002",
+			"configurations": [{"nodes": [
+				{"cpeMatch": [
+					{"vulnerable": true, "criteria": "cpe:2.3:a:example:widget:-:*:*:*:*:*:*:*"},
+					{"vulnerable": true, "criteria": "cpe:2.3:a:example:tool:*:*:*:*:*:windows:*:*"}
+				]},
+				{"negate": true, "cpeMatch": [
+					{"vulnerable": true, "criteria": "cpe:2.3:a:example:other:*:*:*:*:*:*:*:*"}
+				]}
+			]}]
+		}}
+	]
+}`
+
+// nvdLegacyFeedJSON is a legacy NVD 1.1 feed
+const nvdLegacyFeedJSON = `{
+	"CVE_Items": [{
+		"cve": {
+			"CVE_data_meta": {"ID": "CVE-2020-0001"},
+			"description": {"description_data": [{"lang": "en", "value": "Flaw in VLC"}]}
+		},
+		"configurations": {"nodes": [{"cpe_match": [
+			{"vulnerable": true, "cpe23Uri": "cpe:2.3:a:videolan:vlc_media_player:*:*:*:*:*:*:*:*", "versionStartExcluding": "3.0.0", "versionEndExcluding": "3.0.11"}
+		]}]},
+		"impact": {"baseMetricV2": {"cvssV2": {"baseScore": 6.8}, "severity": "MEDIUM"}}
+	}]
+}`
+
+// writeFeed writes a feed to a temporary file, gzipped when its name ends
+// in .gz, and returns its path
+func writeFeed(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if filepath.Ext(name) == ".gz" {
+		gz := gzip.NewWriter(file)
+		if _, err := gz.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	if _, err := file.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadNVD(t *testing.T) {
+	for _, name := range []string{"nvdcve-2.0-recent.json", "nvdcve-2.0-recent.json.gz"} {
+		t.Run(name, func(t *testing.T) {
+			advisories, err := LoadNVD(writeFeed(t, name, nvdFeedJSON))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(advisories) != 1 {
+				t.Fatalf("loaded %d advisories, want 1", len(advisories))
+			}
+
+			advisory := advisories[0]
+			if advisory.ID != "CVE-2024-0001" || advisory.Summary != "Overflow in Chrome" || advisory.Source != "nvd" {
+				t.Errorf("ID, Summary, Source = %q, %q, %q", advisory.ID, advisory.Summary, advisory.Source)
+			}
+			if advisory.Score != 8.8 || advisory.Severity != SeverityHigh {
+				t.Errorf("Score, Severity = %v, %s, want the primary metric 8.8, HIGH", advisory.Score, advisory.Severity)
+			}
+
+			want := []Affected{
+				{Name: "firefox", Vendor: "mozilla", Ranges: []Range{{Introduced: "115.0", LastAffected: "115.5"}}},
+				{
+					Name:     "chrome",
+					Vendor:   "google",
+					Ranges:   []Range{{Fixed: "120.0.6099.129"}},
+					Versions: []string{"99.0"},
+				},
+			}
+			if !reflect.DeepEqual(advisory.Affected, want) {
+				t.Errorf("Affected = %+v, want %+v", advisory.Affected, want)
+			}
+		})
+	}
+
+	advisories, err := LoadNVD(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || advisories != nil {
+		t.Errorf("LoadNVD(missing) = %v, %v, want no advisories", advisories, err)
+	}
+}
+
+func TestLoadNVDLegacy(t *testing.T) {
+	advisories, err := LoadNVD(writeFeed(t, "nvdcve-1.1-2020.json", nvdLegacyFeedJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(advisories) != 1 {
+		t.Fatalf("loaded %d advisories, want 1", len(advisories))
+	}
+
+	advisory := advisories[0]
+	if advisory.ID != "CVE-2020-0001" || advisory.Summary != "Flaw in VLC" {
+		t.Errorf("ID, Summary = %q, %q", advisory.ID, advisory.Summary)
+	}
+	if advisory.Score != 6.8 || advisory.Severity != SeverityMedium {
+		t.Errorf("Score, Severity = %v, %s, want 6.8, MEDIUM", advisory.Score, advisory.Severity)
+	}
+
+	want := []Affected{{
+		Name:   "vlc_media_player",
+		Vendor: "videolan",
+		Ranges: []Range{{Introduced: "3.0.0", IntroducedExcluded: true, Fixed: "3.0.11"}},
+	}}
+	if !reflect.DeepEqual(advisory.Affected, want) {
+		t.Errorf("Affected = %+v, want %+v", advisory.Affected, want)
+	}
+}
+
+func TestNVDVersionBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		match   string
+		version string
+		want    bool
+	}{
+		{"start including, at start", `"versionStartIncluding": "2.0"`, "2.0", true},
+		{"start including, below start", `"versionStartIncluding": "2.0"`, "1.9", false},
+		{"start excluding, at start", `"versionStartExcluding": "2.0"`, "2.0", false},
+		{"start excluding, above start", `"versionStartExcluding": "2.0"`, "2.0.1", true},
+		{"end including, at end", `"versionEndIncluding": "3.0"`, "3.0", true},
+		{"end including, above end", `"versionEndIncluding": "3.0"`, "3.0.1", false},
+		{"end excluding, at end", `"versionEndExcluding": "3.0"`, "3.0", false},
+		{"end excluding, below end", `"versionEndExcluding": "3.0"`, "2.9.9", true},
+		{"both bounds, inside", `"versionStartIncluding": "2.0", "versionEndExcluding": "3.0"`, "2.5", true},
+		{"both bounds, outside", `"versionStartIncluding": "2.0", "versionEndExcluding": "3.0"`, "3.1", false},
+		{"no bounds", `"vulnerable": true`, "1.0", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := `{"vulnerabilities": [{"cve": {"id": "CVE-1", "configurations": [{"nodes": [{"cpeMatch": [
+				{"vulnerable": true, "criteria": "cpe:2.3:a:example:app:*:*:*:*:*:*:*:*", ` + tt.match + `}
+			]}]}]}}]}`
+			advisories, err := LoadNVD(writeFeed(t, "feed.json", feed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, got := advisories[0].Affected[0].affects(tt.version, version.Dotted); got != tt.want {
+				t.Errorf("affects(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetsMacOS(t *testing.T) {
+	tests := []struct {
+		targetSW string
+		want     bool
+	}{
+		{"*", true},
+		{"-", true},
+		{"macos", true},
+		{"mac_os_x", true},
+		{"windows", false},
+		{"android", false},
+	}
+
+	for _, tt := range tests {
+		if got := targetsMacOS(tt.targetSW); got != tt.want {
+			t.Errorf("targetsMacOS(%q) = %v, want %v", tt.targetSW, got, tt.want)
+		}
+	}
+}
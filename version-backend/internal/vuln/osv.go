@@ -0,0 +1,244 @@
+package vuln
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// osvRecord is a vulnerability in the OSV schema, see
+// https://ossf.github.io/osv-schema/
+type osvRecord struct {
+	ID        string        `json:"id"`
+	Aliases   []string      `json:"aliases"`
+	Summary   string        `json:"summary"`
+	Details   string        `json:"details"`
+	Withdrawn string        `json:"withdrawn"`
+	Severity  []osvSeverity `json:"severity"`
+	Affected  []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Severity []osvSeverity `json:"severity"`
+		Ranges   []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced   string `json:"introduced"`
+				Fixed        string `json:"fixed"`
+				LastAffected string `json:"last_affected"`
+			} `json:"events"`
+		} `json:"ranges"`
+		Versions          []string `json:"versions"`
+		EcosystemSpecific struct {
+			Severity string `json:"severity"`
+		} `json:"ecosystem_specific"`
+	} `json:"affected"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// osvSeverity is a severity score of an OSV record, such as a CVSS vector
+type osvSeverity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+// LoadOSV reads every OSV record in a directory and its subdirectories,
+// from .json files holding one record or a list of them and from .zip
+// archives such as the all.zip exports of osv.dev. A missing directory
+// holds no records.
+func LoadOSV(dir string) ([]*Advisory, error) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var advisories []*Advisory
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return errorf(path, err)
+			}
+			loaded, err := parseOSV(data)
+			if err != nil {
+				return errorf(path, err)
+			}
+			advisories = append(advisories, loaded...)
+		case ".zip":
+			loaded, err := loadOSVArchive(path)
+			if err != nil {
+				return errorf(path, err)
+			}
+			advisories = append(advisories, loaded...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return advisories, nil
+}
+
+// loadOSVArchive reads the OSV records of the .json files in a zip archive
+func loadOSVArchive(path string) ([]*Advisory, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	var advisories []*Advisory
+	for _, file := range archive.File {
+		if !strings.EqualFold(filepath.Ext(file.Name), ".json") {
+			continue
+		}
+
+		r, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		loaded, err := parseOSV(data)
+		if err != nil {
+			return nil, err
+		}
+		advisories = append(advisories, loaded...)
+	}
+	return advisories, nil
+}
+
+// parseOSV parses a single OSV record, a list of records or the
+// {"vulns": [...]} response of the OSV query API
+func parseOSV(data []byte) ([]*Advisory, error) {
+	var records []osvRecord
+	switch trimmed := bytes.TrimSpace(data); {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		if err := json.Unmarshal(trimmed, &records); err != nil {
+			return nil, err
+		}
+	default:
+		var wrapper struct {
+			Vulns []osvRecord `json:"vulns"`
+		}
+		if err := json.Unmarshal(trimmed, &wrapper); err != nil {
+			return nil, err
+		}
+		if wrapper.Vulns != nil {
+			records = wrapper.Vulns
+			break
+		}
+
+		var record osvRecord
+		if err := json.Unmarshal(trimmed, &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	advisories := make([]*Advisory, 0, len(records))
+	for _, record := range records {
+		if record.ID == "" || record.Withdrawn != "" {
+			continue
+		}
+		advisories = append(advisories, record.advisory())
+	}
+	return advisories, nil
+}
+
+// advisory converts an OSV record. The severity is the highest CVSS v3
+// score of the record or its packages, else the rating set by the
+// database that published it.
+func (r *osvRecord) advisory() *Advisory {
+	advisory := &Advisory{
+		ID:      r.ID,
+		Aliases: r.Aliases,
+		Summary: r.Summary,
+		Source:  "osv",
+	}
+	if advisory.Summary == "" {
+		advisory.Summary, _, _ = strings.Cut(strings.TrimSpace(r.Details), "\n")
+	}
+
+	severities := append([]osvSeverity(nil), r.Severity...)
+	rating := normalizeSeverity(r.DatabaseSpecific.Severity)
+
+	for _, a := range r.Affected {
+		severities = append(severities, a.Severity...)
+		if rating == "" {
+			rating = normalizeSeverity(a.EcosystemSpecific.Severity)
+		}
+
+		affected := Affected{
+			Ecosystem: a.Package.Ecosystem,
+			Name:      a.Package.Name,
+			Versions:  a.Versions,
+		}
+		for _, r := range a.Ranges {
+			// Commit ranges cannot be compared with installed versions
+			if r.Type == "GIT" {
+				continue
+			}
+
+			// Events open a range with introduced and close it with fixed
+			// or last_affected
+			var current *Range
+			for _, event := range r.Events {
+				switch {
+				case event.Introduced != "":
+					affected.Ranges = append(affected.Ranges, Range{Introduced: event.Introduced})
+					current = &affected.Ranges[len(affected.Ranges)-1]
+				case event.Fixed != "" || event.LastAffected != "":
+					if current == nil {
+						affected.Ranges = append(affected.Ranges, Range{})
+						current = &affected.Ranges[len(affected.Ranges)-1]
+					}
+					current.Fixed = event.Fixed
+					current.LastAffected = event.LastAffected
+					current = nil
+				}
+			}
+		}
+
+		// A package only affected in commit ranges cannot be matched
+		if len(affected.Ranges) == 0 && len(affected.Versions) == 0 && len(a.Ranges) > 0 {
+			continue
+		}
+		advisory.Affected = append(advisory.Affected, affected)
+	}
+
+	for _, severity := range severities {
+		if !strings.HasPrefix(severity.Type, "CVSS_V3") {
+			continue
+		}
+		if score, ok := CVSS3Score(severity.Score); ok && score > advisory.Score {
+			advisory.Score = score
+		}
+	}
+	advisory.Severity = rating
+	if advisory.Score > 0 {
+		advisory.Severity = SeverityForScore(advisory.Score)
+	}
+
+	return advisory
+}
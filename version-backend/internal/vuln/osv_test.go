@@ -0,0 +1,235 @@
+package vuln
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// osvRecordJSON is an OSV record of a PyPI package with two ranges, one
+// closed by fixed and one by last_affected
+const osvRecordJSON = `{
+	"id": "GHSA-test-0001",
+	"aliases": ["CVE-2024-0001"],
+	"details": "Request smuggling in example.\nMore details.",
+	"severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}],
+	"affected": [{
+		"package": {"ecosystem": "PyPI", "name": "example"},
+		"ranges": [{
+			"type": "ECOSYSTEM",
+			"events": [
+				{"introduced": "0"},
+				{"fixed": "1.2.0"},
+				{"introduced": "2.0.0"},
+				{"last_affected": "2.1.0"}
+			]
+		}],
+		"versions": ["1.0.0", "1.1.0"]
+	}]
+}`
+
+func TestParseOSV(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		ids  []string
+	}{
+		{name: "single record", data: osvRecordJSON, ids: []string{"GHSA-test-0001"}},
+		{name: "list of records", data: "[" + osvRecordJSON + "]", ids: []string{"GHSA-test-0001"}},
+		{name: "query API response", data: `{"vulns": [` + osvRecordJSON + `]}`, ids: []string{"GHSA-test-0001"}},
+		{
+			name: "withdrawn record",
+			data: `{"id": "GHSA-test-0002", "withdrawn": "2024-01-01T00:00:00Z"}`,
+			ids:  []string{},
+		},
+		{name: "record without ID", data: `{"summary": "nothing"}`, ids: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advisories, err := parseOSV([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, len(advisories))
+			for i, advisory := range advisories {
+				ids[i] = advisory.ID
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("parsed %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+
+	if _, err := parseOSV([]byte(`{"id": `)); err == nil {
+		t.Error("parseOSV accepted truncated JSON")
+	}
+}
+
+func TestLoadOSV(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "PyPI"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "PyPI", "GHSA-test-0001.json"), []byte(osvRecordJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.txt"), []byte("not a record"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// An all.zip export as downloaded from osv.dev
+	file, err := os.Create(filepath.Join(dir, "all.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := zip.NewWriter(file)
+	w, err := archive.Create("DSA-0001-1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(`{"id": "DSA-0001-1", "affected": [{"package": {"ecosystem": "Debian:12", "name": "openssl"}}]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	advisories, err := LoadOSV(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, advisory := range advisories {
+		ids = append(ids, advisory.ID)
+	}
+	sort.Strings(ids)
+	if want := []string{"DSA-0001-1", "GHSA-test-0001"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("loaded %v, want %v", ids, want)
+	}
+
+	advisories, err = LoadOSV(filepath.Join(dir, "missing"))
+	if err != nil || advisories != nil {
+		t.Errorf("LoadOSV(missing) = %v, %v, want no advisories", advisories, err)
+	}
+}
+
+func TestOSVAdvisory(t *testing.T) {
+	advisories, err := parseOSV([]byte(osvRecordJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	advisory := advisories[0]
+
+	if advisory.Summary != "Request smuggling in example." {
+		t.Errorf("Summary = %q, want the first line of the details", advisory.Summary)
+	}
+	if advisory.Score != 9.8 || advisory.Severity != SeverityCritical {
+		t.Errorf("Score, Severity = %v, %s, want 9.8, CRITICAL", advisory.Score, advisory.Severity)
+	}
+
+	want := []Affected{{
+		Ecosystem: "PyPI",
+		Name:      "example",
+		Ranges: []Range{
+			{Introduced: "0", Fixed: "1.2.0"},
+			{Introduced: "2.0.0", LastAffected: "2.1.0"},
+		},
+		Versions: []string{"1.0.0", "1.1.0"},
+	}}
+	if !reflect.DeepEqual(advisory.Affected, want) {
+		t.Errorf("Affected = %+v, want %+v", advisory.Affected, want)
+	}
+}
+
+func TestOSVAdvisoryRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		affected string
+		want     []Affected
+	}{
+		{
+			name: "fixed without introduced",
+			affected: `{"package": {"ecosystem": "npm", "name": "example"},
+				"ranges": [{"type": "SEMVER", "events": [{"fixed": "1.0.0"}]}]}`,
+			want: []Affected{{Ecosystem: "npm", Name: "example", Ranges: []Range{{Fixed: "1.0.0"}}}},
+		},
+		{
+			name: "open range",
+			affected: `{"package": {"ecosystem": "Debian:12", "name": "openssl"},
+				"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}]}]}`,
+			want: []Affected{{Ecosystem: "Debian:12", Name: "openssl", Ranges: []Range{{Introduced: "0"}}}},
+		},
+		{
+			name: "commit ranges only",
+			affected: `{"package": {"ecosystem": "Go", "name": "example.com/mod"},
+				"ranges": [{"type": "GIT", "events": [{"introduced": "abc"}, {"fixed": "def"}]}]}`,
+			want: nil,
+		},
+		{
+			name:     "every version",
+			affected: `{"package": {"ecosystem": "RubyGems", "name": "example"}}`,
+			want:     []Affected{{Ecosystem: "RubyGems", Name: "example"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advisories, err := parseOSV([]byte(`{"id": "OSV-1", "affected": [` + tt.affected + `]}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := advisories[0].Affected; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Affected = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOSVAdvisorySeverity(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		score    float64
+		severity string
+	}{
+		{
+			name: "package CVSS vector",
+			data: `{"id": "OSV-1", "affected": [{"package": {"ecosystem": "npm", "name": "a"},
+				"severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:N/A:N"}]}]}`,
+			score:    5.9,
+			severity: SeverityMedium,
+		},
+		{
+			name:     "database rating",
+			data:     `{"id": "OSV-1", "database_specific": {"severity": "MODERATE"}}`,
+			severity: SeverityMedium,
+		},
+		{
+			name: "distribution rating",
+			data: `{"id": "OSV-1", "affected": [{"package": {"ecosystem": "Red Hat:enterprise_linux:9::appstream", "name": "curl"},
+				"ecosystem_specific": {"severity": "Important"}}]}`,
+			severity: SeverityHigh,
+		},
+		{
+			name: "CVSS v2 vector ignored",
+			data: `{"id": "OSV-1", "severity": [{"type": "CVSS_V2", "score": "AV:N/AC:L/Au:N/C:P/I:P/A:P"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advisories, err := parseOSV([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := advisories[0]; got.Score != tt.score || got.Severity != tt.severity {
+				t.Errorf("Score, Severity = %v, %q, want %v, %q", got.Score, got.Severity, tt.score, tt.severity)
+			}
+		})
+	}
+}
@@ -0,0 +1,552 @@
+// Package vuln matches the installed software of hosts against
+// vulnerability data loaded from local files: a directory of OSV records
+// and an NVD JSON feed. Nothing is downloaded, so it works offline once the
+// files are copied to the server.
+package vuln
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"version-backend/internal/cpe"
+	"version-backend/internal/db/models"
+	"version-backend/pkg/version"
+)
+
+// Severity ratings, from the CVSS qualitative scale
+const (
+	SeverityCritical = "CRITICAL"
+	SeverityHigh     = "HIGH"
+	SeverityMedium   = "MEDIUM"
+	SeverityLow      = "LOW"
+	SeverityNone     = "NONE"
+)
+
+// Kinds of installed software a finding can be about
+const (
+	KindApp             = "app"
+	KindPackage         = "package"
+	KindLanguagePackage = "language_package"
+)
+
+// Advisory is a vulnerability and the software it affects
+type Advisory struct {
+	ID       string
+	Aliases  []string
+	Summary  string
+	Severity string
+	Score    float64
+	Source   string
+	Affected []Affected
+}
+
+// Affected is a package or app affected by an advisory in some of its
+// versions
+type Affected struct {
+	// Ecosystem is the OSV ecosystem of the package, such as PyPI or
+	// Debian:12. It is empty for the apps of an NVD feed.
+	Ecosystem string
+
+	// Name is the package name. Apps of an NVD feed are named by the
+	// product of their CPE and also matched by Vendor.
+	Name   string
+	Vendor string
+
+	// Ranges and Versions list the affected versions. A package with
+	// neither is affected in every version.
+	Ranges   []Range
+	Versions []string
+}
+
+// Range is a range of affected versions. An empty Introduced affects every
+// version up to Fixed or LastAffected.
+type Range struct {
+	Introduced         string
+	IntroducedExcluded bool
+	Fixed              string
+	LastAffected       string
+}
+
+// Finding is an advisory affecting a piece of software installed on a host
+type Finding struct {
+	Advisory     *Advisory
+	Host         *models.Host
+	Kind         string
+	Ecosystem    string
+	Name         string
+	Identifier   string
+	Path         string
+	Version      string
+	FixedVersion string
+}
+
+// Database holds the advisories loaded from the local feeds, indexed by
+// lowercase package name
+type Database struct {
+	advisories []*Advisory
+	index      map[string][]affectedRef
+	sources    []string
+	loadedAt   time.Time
+}
+
+// affectedRef points to an affected package of an advisory
+type affectedRef struct {
+	advisory *Advisory
+	affected *Affected
+}
+
+// Load reads the advisories of an OSV directory and an NVD feed file.
+// Either can be empty to skip it.
+func Load(osvDir, nvdFeed string) (*Database, error) {
+	var advisories []*Advisory
+	var sources []string
+
+	if osvDir != "" {
+		loaded, err := LoadOSV(osvDir)
+		if err != nil {
+			return nil, err
+		}
+		advisories = append(advisories, loaded...)
+		sources = append(sources, "osv:"+osvDir)
+	}
+
+	if nvdFeed != "" {
+		loaded, err := LoadNVD(nvdFeed)
+		if err != nil {
+			return nil, err
+		}
+		advisories = append(advisories, loaded...)
+		sources = append(sources, "nvd:"+nvdFeed)
+	}
+
+	return NewDatabase(advisories, sources...), nil
+}
+
+// NewDatabase indexes advisories for matching
+func NewDatabase(advisories []*Advisory, sources ...string) *Database {
+	d := &Database{
+		advisories: advisories,
+		index:      make(map[string][]affectedRef),
+		sources:    sources,
+		loadedAt:   time.Now(),
+	}
+	for _, advisory := range advisories {
+		for i := range advisory.Affected {
+			affected := &advisory.Affected[i]
+			key := normalizeName(affected.Ecosystem, affected.Name)
+			d.index[key] = append(d.index[key], affectedRef{advisory, affected})
+		}
+	}
+	return d
+}
+
+// Advisories returns the number of advisories loaded
+func (d *Database) Advisories() int {
+	return len(d.advisories)
+}
+
+// Sources returns the feeds the advisories were loaded from
+func (d *Database) Sources() []string {
+	return d.sources
+}
+
+// LoadedAt returns when the advisories were loaded
+func (d *Database) LoadedAt() time.Time {
+	return d.loadedAt
+}
+
+// Match returns the advisories affecting the current apps, OS packages and
+// language packages of a host, most severe first. An advisory listing the
+// same software more than once is reported once.
+func (d *Database) Match(info *models.SystemInfo) []Finding {
+	var findings []Finding
+	seen := make(map[string]bool)
+	add := func(finding Finding) {
+		key := strings.Join([]string{finding.Advisory.ID, finding.Kind, finding.Name, finding.Path, finding.Version}, "\x00")
+		if !seen[key] {
+			seen[key] = true
+			findings = append(findings, finding)
+		}
+	}
+
+	for _, app := range info.InstalledApps {
+		if app.EndTime != nil {
+			continue
+		}
+		for _, ref := range d.matchApp(app) {
+			fixed, ok := ref.affected.affects(app.BundleShortVersion, version.Dotted)
+			if !ok {
+				continue
+			}
+			add(Finding{
+				Advisory:     ref.advisory,
+				Host:         info.Host,
+				Kind:         KindApp,
+				Name:         app.Name,
+				Identifier:   app.BundleIdentifier,
+				Path:         app.Path,
+				Version:      app.BundleShortVersion,
+				FixedVersion: fixed,
+			})
+		}
+	}
+
+	for _, pkg := range info.Packages {
+		if pkg.EndTime != nil {
+			continue
+		}
+		for _, ref := range d.index[strings.ToLower(packageName(pkg))] {
+			ecosystem := ref.affected.Ecosystem
+			if !matchesDistribution(ecosystem, pkg.Source, info) {
+				continue
+			}
+			fixed, ok := ref.affected.affects(pkg.Version, ecosystemScheme(ecosystem))
+			if !ok {
+				continue
+			}
+			add(Finding{
+				Advisory:     ref.advisory,
+				Host:         info.Host,
+				Kind:         KindPackage,
+				Ecosystem:    pkg.Source,
+				Name:         pkg.Name,
+				Version:      pkg.Version,
+				FixedVersion: fixed,
+			})
+		}
+	}
+
+	for _, pkg := range info.LangPackages {
+		if pkg.EndTime != nil {
+			continue
+		}
+		ecosystem := languageEcosystems[pkg.Ecosystem]
+		if ecosystem == "" {
+			continue
+		}
+		for _, ref := range d.index[normalizeName(ecosystem, pkg.Name)] {
+			if !strings.EqualFold(ref.affected.Ecosystem, ecosystem) {
+				continue
+			}
+			fixed, ok := ref.affected.affects(pkg.Version, version.Dotted)
+			if !ok {
+				continue
+			}
+			add(Finding{
+				Advisory:     ref.advisory,
+				Host:         info.Host,
+				Kind:         KindLanguagePackage,
+				Ecosystem:    pkg.Ecosystem,
+				Name:         pkg.Name,
+				Path:         pkg.Path,
+				Version:      pkg.Version,
+				FixedVersion: fixed,
+			})
+		}
+	}
+
+	SortFindings(findings)
+	return findings
+}
+
+// SortFindings orders findings by severity and score, most severe first,
+// then by advisory and software name
+func SortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if x, y := SeverityRank(a.Advisory.Severity), SeverityRank(b.Advisory.Severity); x != y {
+			return x > y
+		}
+		if a.Advisory.Score != b.Advisory.Score {
+			return a.Advisory.Score > b.Advisory.Score
+		}
+		if a.Advisory.ID != b.Advisory.ID {
+			return a.Advisory.ID < b.Advisory.ID
+		}
+		return a.Name < b.Name
+	})
+}
+
+// matchApp returns the affected entries matching an app. OSV records name
+// apps by bundle identifier in the macOS ecosystem, while NVD products are
+// matched by the vendor and product of the CPE inferred for the app. Apps
+// saved without a CPE are matched by app name or the last part of the
+// bundle identifier, provided the vendor appears in the bundle identifier
+// or the name.
+func (d *Database) matchApp(app models.InstalledApp) []affectedRef {
+	var refs []affectedRef
+	if app.BundleIdentifier != "" {
+		for _, ref := range d.index[strings.ToLower(app.BundleIdentifier)] {
+			if ref.affected.Ecosystem == "macOS" {
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	if name, ok := cpe.Parse(app.CPE); ok {
+		for _, ref := range d.index[normalizeName("", name.Product)] {
+			if ref.affected.Ecosystem == "" && strings.EqualFold(ref.affected.Vendor, name.Vendor) {
+				refs = append(refs, ref)
+			}
+		}
+		return refs
+	}
+
+	candidates := make(map[string]bool)
+	for _, name := range []string{app.Name, app.BundleName, app.DisplayName} {
+		if product := cpe.Normalize(strings.TrimSuffix(name, ".app")); product != "" {
+			candidates[product] = true
+		}
+	}
+	parts := strings.Split(strings.ToLower(app.BundleIdentifier), ".")
+	if len(parts) > 1 {
+		candidates[cpe.Normalize(parts[len(parts)-1])] = true
+	}
+
+	for product := range candidates {
+		for _, ref := range d.index[product] {
+			if ref.affected.Ecosystem != "" || !matchesVendor(ref.affected.Vendor, product, app, parts) {
+				continue
+			}
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// matchesVendor reports whether the vendor of an NVD product is the one of
+// an app: the product itself, a part of the bundle identifier or a word of
+// the app name
+func matchesVendor(vendor, product string, app models.InstalledApp, bundleParts []string) bool {
+	if vendor == "" || vendor == product {
+		return true
+	}
+	for _, part := range bundleParts {
+		if cpe.Normalize(part) == vendor {
+			return true
+		}
+	}
+	for _, word := range strings.Fields(strings.ToLower(app.Name)) {
+		if cpe.Normalize(word) == vendor {
+			return true
+		}
+	}
+	return false
+}
+
+// affects reports whether a version is affected, and the version fixing it
+// when one is known
+func (a *Affected) affects(v string, scheme version.Scheme) (string, bool) {
+	if v == "" {
+		return "", false
+	}
+	if len(a.Ranges) == 0 && len(a.Versions) == 0 {
+		return "", true
+	}
+
+	for _, listed := range a.Versions {
+		if scheme.Compare(v, listed) == 0 {
+			return a.fixedAfter(v, scheme), true
+		}
+	}
+	for _, r := range a.Ranges {
+		if r.contains(v, scheme) {
+			return r.Fixed, true
+		}
+	}
+	return "", false
+}
+
+// fixedAfter returns the lowest fixed version above a version listed as
+// affected
+func (a *Affected) fixedAfter(v string, scheme version.Scheme) string {
+	var fixed string
+	for _, r := range a.Ranges {
+		if r.Fixed == "" || scheme.Compare(r.Fixed, v) <= 0 {
+			continue
+		}
+		if fixed == "" || scheme.Compare(r.Fixed, fixed) < 0 {
+			fixed = r.Fixed
+		}
+	}
+	return fixed
+}
+
+// contains reports whether a version falls in the range
+func (r Range) contains(v string, scheme version.Scheme) bool {
+	if r.Introduced != "" && r.Introduced != "0" {
+		c := scheme.Compare(v, r.Introduced)
+		if c < 0 || c == 0 && r.IntroducedExcluded {
+			return false
+		}
+	}
+	if r.Fixed != "" && scheme.Compare(v, r.Fixed) >= 0 {
+		return false
+	}
+	if r.LastAffected != "" && scheme.Compare(v, r.LastAffected) > 0 {
+		return false
+	}
+	return true
+}
+
+// languageEcosystems maps the language package ecosystems of the collectors
+// to their OSV names
+var languageEcosystems = map[string]string{
+	"pip": "PyPI",
+	"npm": "npm",
+	"gem": "RubyGems",
+	"go":  "Go",
+}
+
+// normalizeName returns the index key of a language package name. PyPI
+// names are compared as PEP 503 normalizes them.
+func normalizeName(ecosystem, name string) string {
+	name = strings.ToLower(name)
+	if ecosystem == "PyPI" {
+		name = strings.NewReplacer("_", "-", ".", "-").Replace(name)
+	}
+	return name
+}
+
+// distributions maps the OSV ecosystems of Linux distributions to the
+// package source of their packages and the osquery platforms running them
+var distributions = map[string]struct {
+	source    string
+	platforms []string
+}{
+	"Debian":      {"deb", []string{"debian"}},
+	"Ubuntu":      {"deb", []string{"ubuntu"}},
+	"Alpine":      {"apk", []string{"alpine"}},
+	"Red Hat":     {"rpm", []string{"rhel", "centos"}},
+	"AlmaLinux":   {"rpm", []string{"almalinux"}},
+	"Rocky Linux": {"rpm", []string{"rocky"}},
+	"SUSE":        {"rpm", []string{"sles"}},
+	"openSUSE":    {"rpm", []string{"opensuse", "opensuse-leap", "opensuse-tumbleweed"}},
+}
+
+// packageName returns the name OSV advisories list an OS package under.
+// Debian and Ubuntu advisories are keyed by source package, which dpkg
+// leaves out when it is the binary package itself.
+func packageName(pkg models.SoftwarePackage) string {
+	if pkg.Source == "deb" && pkg.SourcePackage != "" {
+		return pkg.SourcePackage
+	}
+	return pkg.Name
+}
+
+// matchesDistribution reports whether an OSV ecosystem such as Debian:12,
+// Alpine:v3.18 or Red Hat:enterprise_linux:9::appstream describes the
+// packages of a host: the same package source, the same distribution and,
+// when the ecosystem has one, the same release
+func matchesDistribution(ecosystem, source string, info *models.SystemInfo) bool {
+	name, qualifiers, _ := strings.Cut(ecosystem, ":")
+	distribution, ok := distributions[name]
+	if !ok || distribution.source != source {
+		return false
+	}
+
+	platform := strings.ToLower(info.OSPlatform)
+	matched := false
+	for _, p := range distribution.platforms {
+		if p == platform {
+			matched = true
+		}
+	}
+	if !matched {
+		return false
+	}
+
+	release := ecosystemRelease(qualifiers)
+	if release == "" {
+		return true
+	}
+	if !strings.HasPrefix(info.OSVersion, release) {
+		return false
+	}
+	rest := info.OSVersion[len(release):]
+	return rest == "" || rest[0] < '0' || rest[0] > '9'
+}
+
+// ecosystemRelease returns the release in the qualifiers of an OSV
+// ecosystem, the first word starting with a digit: 12 in Debian:12, 3.18
+// in Alpine:v3.18, 9 in Red Hat:enterprise_linux:9::appstream and 15.5 in
+// openSUSE:Leap 15.5. It is empty for ecosystems covering every release.
+func ecosystemRelease(qualifiers string) string {
+	words := strings.FieldsFunc(qualifiers, func(r rune) bool { return r == ':' || r == ' ' })
+	for _, word := range words {
+		word = strings.TrimPrefix(word, "v")
+		if word != "" && word[0] >= '0' && word[0] <= '9' {
+			return word
+		}
+	}
+	return ""
+}
+
+// ecosystemScheme returns the version scheme of an OSV ecosystem
+func ecosystemScheme(ecosystem string) version.Scheme {
+	name, _, _ := strings.Cut(ecosystem, ":")
+	if distribution, ok := distributions[name]; ok {
+		return version.ForSource(distribution.source)
+	}
+	return version.Dotted
+}
+
+// SeverityForScore returns the CVSS rating of a base score
+func SeverityForScore(score float64) string {
+	switch {
+	case score >= 9:
+		return SeverityCritical
+	case score >= 7:
+		return SeverityHigh
+	case score >= 4:
+		return SeverityMedium
+	case score > 0:
+		return SeverityLow
+	default:
+		return SeverityNone
+	}
+}
+
+// SeverityRank orders severities from NONE, 0, to CRITICAL, 4. Unknown
+// severities rank -1.
+func SeverityRank(severity string) int {
+	switch strings.ToUpper(severity) {
+	case SeverityCritical:
+		return 4
+	case SeverityHigh:
+		return 3
+	case SeverityMedium, "MODERATE":
+		return 2
+	case SeverityLow:
+		return 1
+	case SeverityNone:
+		return 0
+	default:
+		return -1
+	}
+}
+
+// normalizeSeverity maps the severities of the feeds to the CVSS ratings
+func normalizeSeverity(severity string) string {
+	switch strings.ToUpper(strings.TrimSpace(severity)) {
+	case SeverityCritical:
+		return SeverityCritical
+	case SeverityHigh, "IMPORTANT":
+		return SeverityHigh
+	case SeverityMedium, "MODERATE":
+		return SeverityMedium
+	case SeverityLow:
+		return SeverityLow
+	case SeverityNone:
+		return SeverityNone
+	default:
+		return ""
+	}
+}
+
+// errorf wraps an error reading a feed file
+func errorf(path string, err error) error {
+	return fmt.Errorf("error reading vulnerability feed %s: %w", path, err)
+}
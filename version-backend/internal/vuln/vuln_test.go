@@ -0,0 +1,222 @@
+package vuln
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"version-backend/internal/db/models"
+	"version-backend/pkg/version"
+)
+
+func TestAffects(t *testing.T) {
+	ranges := []Range{
+		{Introduced: "0", Fixed: "1.2.0"},
+		{Introduced: "2.0.0", LastAffected: "2.1.0"},
+	}
+
+	tests := []struct {
+		name     string
+		affected Affected
+		version  string
+		want     bool
+		fixed    string
+	}{
+		{name: "inside the first range", affected: Affected{Ranges: ranges}, version: "1.1.9", want: true, fixed: "1.2.0"},
+		{name: "exactly at fixed", affected: Affected{Ranges: ranges}, version: "1.2.0", want: false},
+		{name: "between the ranges", affected: Affected{Ranges: ranges}, version: "1.5", want: false},
+		{name: "at introduced", affected: Affected{Ranges: ranges}, version: "2.0.0", want: true},
+		{name: "exactly at last_affected", affected: Affected{Ranges: ranges}, version: "2.1.0", want: true},
+		{name: "after last_affected", affected: Affected{Ranges: ranges}, version: "2.1.1", want: false},
+		{
+			name:     "introduced excluded",
+			affected: Affected{Ranges: []Range{{Introduced: "3.0", IntroducedExcluded: true, Fixed: "3.5"}}},
+			version:  "3.0",
+			want:     false,
+		},
+		{
+			name:     "open range",
+			affected: Affected{Ranges: []Range{{Introduced: "3.0"}}},
+			version:  "99.0",
+			want:     true,
+		},
+		{
+			name:     "listed version, fixed in a later range",
+			affected: Affected{Versions: []string{"1.0"}, Ranges: []Range{{Introduced: "1.5", Fixed: "1.6"}, {Introduced: "0", Fixed: "1.1"}}},
+			version:  "1.0",
+			want:     true,
+			fixed:    "1.1",
+		},
+		{name: "unlisted version", affected: Affected{Versions: []string{"1.0"}}, version: "1.0.1", want: false},
+		{name: "every version", affected: Affected{}, version: "5.0", want: true},
+		{name: "no version", affected: Affected{}, version: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixed, ok := tt.affected.affects(tt.version, version.Dotted)
+			if ok != tt.want || fixed != tt.fixed {
+				t.Errorf("affects(%q) = %q, %v, want %q, %v", tt.version, fixed, ok, tt.fixed, tt.want)
+			}
+		})
+	}
+}
+
+func TestEcosystemRelease(t *testing.T) {
+	tests := []struct {
+		ecosystem string
+		want      string
+	}{
+		{"Debian:12", "12"},
+		{"Alpine:v3.18", "3.18"},
+		{"Ubuntu:22.04:LTS", "22.04"},
+		{"Ubuntu:Pro:18.04:LTS", "18.04"},
+		{"Red Hat:enterprise_linux:9::appstream", "9"},
+		{"Red Hat:rhel_eus:8.6::baseos", "8.6"},
+		{"openSUSE:Leap 15.5", "15.5"},
+		{"SUSE:Linux Enterprise Server 15 SP5", "15"},
+		{"openSUSE:Tumbleweed", ""},
+		{"Debian", ""},
+	}
+
+	for _, tt := range tests {
+		_, qualifiers, _ := strings.Cut(tt.ecosystem, ":")
+		if got := ecosystemRelease(qualifiers); got != tt.want {
+			t.Errorf("ecosystemRelease(%q) = %q, want %q", tt.ecosystem, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesDistribution(t *testing.T) {
+	debian := &models.SystemInfo{OSPlatform: "debian", OSVersion: "12"}
+	ubuntu := &models.SystemInfo{OSPlatform: "ubuntu", OSVersion: "22.04"}
+	rhel := &models.SystemInfo{OSPlatform: "rhel", OSVersion: "9.3"}
+	alpine := &models.SystemInfo{OSPlatform: "alpine", OSVersion: "3.18.4"}
+
+	tests := []struct {
+		name      string
+		ecosystem string
+		source    string
+		info      *models.SystemInfo
+		want      bool
+	}{
+		{"same release", "Debian:12", "deb", debian, true},
+		{"other release", "Debian:11", "deb", debian, false},
+		{"release prefix of another", "Debian:1", "deb", debian, false},
+		{"every release", "Debian", "deb", debian, true},
+		{"other distribution", "Ubuntu:22.04:LTS", "deb", debian, false},
+		{"Ubuntu LTS", "Ubuntu:22.04:LTS", "deb", ubuntu, true},
+		{"other package source", "Debian:12", "rpm", debian, false},
+		{"Red Hat", "Red Hat:enterprise_linux:9::appstream", "rpm", rhel, true},
+		{"Red Hat, other release", "Red Hat:enterprise_linux:8::appstream", "rpm", rhel, false},
+		{"Alpine", "Alpine:v3.18", "apk", alpine, true},
+		{"unknown ecosystem", "Gentoo:2.14", "deb", debian, false},
+		{"language ecosystem", "PyPI", "deb", debian, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesDistribution(tt.ecosystem, tt.source, tt.info); got != tt.want {
+				t.Errorf("matchesDistribution(%q, %q) = %v, want %v", tt.ecosystem, tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	osv, err := parseOSV([]byte(`[
+		{"id": "DSA-0001-1", "affected": [{"package": {"ecosystem": "Debian:12", "name": "openssl"},
+			"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "3.0.11-1~deb12u2"}]}]}]},
+		{"id": "RHSA-2024:0001", "affected": [{"package": {"ecosystem": "Red Hat:enterprise_linux:9::appstream", "name": "openssl"},
+			"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1:3.0.7-25.el9"}]}]}]},
+		{"id": "GENTOO-0001", "affected": [{"package": {"ecosystem": "Gentoo", "name": "openssl"}}]},
+		{"id": "PYSEC-0001", "affected": [{"package": {"ecosystem": "PyPI", "name": "Foo_Bar"},
+			"ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "2.0"}]}]}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nvd, err := LoadNVD(writeFeed(t, "feed.json", nvdFeedJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDatabase(append(osv, nvd...))
+
+	host := &models.Host{UUID: "host"}
+	removed := time.Now()
+	tests := []struct {
+		name string
+		info *models.SystemInfo
+		want []string
+	}{
+		{
+			name: "Debian binary package by its source package",
+			info: &models.SystemInfo{Host: host, OSPlatform: "debian", OSVersion: "12", Packages: []models.SoftwarePackage{
+				{Name: "libssl3", Version: "3.0.11-1~deb12u1", Source: "deb", SourcePackage: "openssl"},
+			}},
+			want: []string{"DSA-0001-1"},
+		},
+		{
+			name: "Debian package named after its source package",
+			info: &models.SystemInfo{Host: host, OSPlatform: "debian", OSVersion: "12", Packages: []models.SoftwarePackage{
+				{Name: "openssl", Version: "3.0.11-1~deb12u1", Source: "deb"},
+			}},
+			want: []string{"DSA-0001-1"},
+		},
+		{
+			name: "Debian package at the fixed version",
+			info: &models.SystemInfo{Host: host, OSPlatform: "debian", OSVersion: "12", Packages: []models.SoftwarePackage{
+				{Name: "libssl3", Version: "3.0.11-1~deb12u2", Source: "deb", SourcePackage: "openssl"},
+			}},
+		},
+		{
+			name: "Red Hat package",
+			info: &models.SystemInfo{Host: host, OSPlatform: "rhel", OSVersion: "9.3", Packages: []models.SoftwarePackage{
+				{Name: "openssl", Version: "1:3.0.7-24.el9", Source: "rpm"},
+			}},
+			want: []string{"RHSA-2024:0001"},
+		},
+		{
+			name: "removed package",
+			info: &models.SystemInfo{Host: host, OSPlatform: "rhel", OSVersion: "9.3", Packages: []models.SoftwarePackage{
+				{Name: "openssl", Version: "1:3.0.7-24.el9", Source: "rpm", EndTime: &removed},
+			}},
+		},
+		{
+			name: "language package by normalized name",
+			info: &models.SystemInfo{Host: host, LangPackages: []models.LanguagePackage{
+				{Name: "foo.bar", Version: "1.9", Ecosystem: "pip"},
+			}},
+			want: []string{"PYSEC-0001"},
+		},
+		{
+			name: "app by CPE",
+			info: &models.SystemInfo{Host: host, InstalledApps: []models.InstalledApp{
+				{Name: "Google Chrome.app", BundleIdentifier: "com.google.Chrome", BundleShortVersion: "119.0.6045.199",
+					CPE: "cpe:2.3:a:google:chrome:119.0.6045.199:*:*:*:*:macos:*:*"},
+			}},
+			want: []string{"CVE-2024-0001"},
+		},
+		{
+			name: "app of a CPE without versions",
+			info: &models.SystemInfo{Host: host, InstalledApps: []models.InstalledApp{
+				{Name: "Widget.app", BundleIdentifier: "com.example.widget", BundleShortVersion: "1.0",
+					CPE: "cpe:2.3:a:example:widget:1.0:*:*:*:*:macos:*:*"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := d.Match(tt.info)
+			if len(findings) != len(tt.want) {
+				t.Fatalf("%d findings, want %d: %+v", len(findings), len(tt.want), findings)
+			}
+			for i, finding := range findings {
+				if finding.Advisory.ID != tt.want[i] {
+					t.Errorf("finding %d is %s, want %s", i, finding.Advisory.ID, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -46,6 +46,15 @@ func Info(msg string, fields map[string]interface{}) {
 	}
 }
 
+// Warn logs warning level message
+func Warn(msg string, fields map[string]interface{}) {
+	if fields != nil {
+		log.WithFields(fields).Warn(msg)
+	} else {
+		log.Warn(msg)
+	}
+}
+
 // Error logs error level message
 func Error(msg string, err error, fields map[string]interface{}) {
 	if fields == nil {
@@ -0,0 +1,110 @@
+package version
+
+import "strings"
+
+// compareDotted orders app versions. Both being semantic versions, they
+// follow the semver rules. Otherwise they are split into runs of digits and
+// runs of letters, anything else separating them, and compared run by run:
+// digits numerically, letters case-insensitively, and a number after a
+// word, so 1.0b2 comes before 1.0.1. A version that continues where the
+// other ends is newer when a non-zero number follows, as 1.0.1 is to 1.0,
+// older when a pre-release follows, as 1.0 beta is to 1.0, and newer when
+// other letters follow, as 1.0.2a is to 1.0.2 in OpenSSL.
+func compareDotted(a, b string) int {
+	if av, ok := ParseSemver(a); ok {
+		if bv, ok := ParseSemver(b); ok {
+			return av.Compare(bv)
+		}
+	}
+
+	as, bs := segments(a), segments(b)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, y := as[i], bs[i]
+		xDigits, yDigits := isDigit(x[0]), isDigit(y[0])
+		switch {
+		case xDigits && yDigits:
+			if c := compareDigits(x, y); c != 0 {
+				return c
+			}
+		case xDigits:
+			return 1
+		case yDigits:
+			return -1
+		default:
+			if c := strings.Compare(strings.ToLower(x), strings.ToLower(y)); c != 0 {
+				return c
+			}
+		}
+	}
+
+	switch {
+	case len(as) > len(bs):
+		return trailing(as[len(bs):])
+	case len(bs) > len(as):
+		return -trailing(bs[len(as):])
+	default:
+		return 0
+	}
+}
+
+// trailing orders the segments a version has beyond the end of another
+// against it: zeros change nothing, a number makes it newer, a pre-release
+// older and any other word, such as a letter release or a patch, newer
+func trailing(rest []string) int {
+	for i, segment := range rest {
+		if !isDigit(segment[0]) {
+			numbered := i+1 < len(rest) && isDigit(rest[i+1][0])
+			if isPrerelease(segment, numbered) {
+				return -1
+			}
+			return 1
+		}
+		if strings.TrimLeft(segment, "0") != "" {
+			return 1
+		}
+	}
+	return 0
+}
+
+// prereleaseWords are the words marking a pre-release, as in 1.0 beta or
+// 2.0rc1
+var prereleaseWords = map[string]bool{
+	"alpha": true, "beta": true, "rc": true, "pre": true,
+	"preview": true, "dev": true, "snapshot": true,
+}
+
+// isPrerelease reports whether a word following the end of another version
+// marks a pre-release: a pre-release word, or a, b or c when numbered, as in
+// 3.0b2. A letter ending the version is a release after it instead, as in
+// OpenSSL 1.1.1w, and so are other words, such as the patch of OpenSSH 9.6p1.
+func isPrerelease(word string, numbered bool) bool {
+	word = strings.ToLower(word)
+	if prereleaseWords[word] {
+		return true
+	}
+	return numbered && (word == "a" || word == "b" || word == "c")
+}
+
+// segments splits a version into runs of digits and runs of letters
+func segments(v string) []string {
+	var segments []string
+	for i := 0; i < len(v); {
+		j := i + 1
+		switch {
+		case isDigit(v[i]):
+			for j < len(v) && isDigit(v[j]) {
+				j++
+			}
+		case isLetter(v[i]):
+			for j < len(v) && isLetter(v[j]) {
+				j++
+			}
+		default:
+			i = j
+			continue
+		}
+		segments = append(segments, v[i:j])
+		i = j
+	}
+	return segments
+}
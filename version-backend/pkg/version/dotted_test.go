@@ -0,0 +1,57 @@
+package version
+
+import (
+	"testing"
+)
+
+func TestCompareDotted(t *testing.T) {
+	checkCompare(t, Dotted, []compareTest{
+		// Numbers
+		{"1.0", "1.0", 0},
+		{"14.2.1", "14.2", 1},
+		{"1.10", "1.9", 1},
+		{"1.01", "1.1", 0},
+		{"20240101000000000000", "20231231000000000000", 1},
+
+		// Missing parts
+		{"1", "1.0.0", 0},
+		{"1.0", "1.0.0", 0},
+		{"1.0.1", "1.0", 1},
+		{"", "", 0},
+		{"", "1.0", -1},
+
+		// Build numbers
+		{"1.0 (345)", "1.0", 1},
+		{"1.0 (345)", "1.0 (346)", -1},
+
+		// Pre-releases
+		{"3.0b2", "3.0", -1},
+		{"3.0b2", "3.0b10", -1},
+		{"1.0b2", "1.0.1", -1},
+		{"1.0a1", "1.0", -1},
+		{"1.0 beta", "1.0", -1},
+		{"1.0 Beta", "1.0 beta", 0},
+		{"1.0 alpha", "1.0 beta", -1},
+		{"2.0rc1", "2.0", -1},
+		{"2.0-alpha", "2.0", -1},
+
+		// Letter releases and patches
+		{"1.0a", "1.0", 1},
+		{"1.1.1w", "1.1.1", 1},
+		{"1.1.1w", "1.1.1v", 1},
+		{"1.0.2a", "1.0.2b", -1},
+		{"9.6p1", "9.6", 1},
+		{"9.6p2", "9.6p1", 1},
+
+		// Semantic versions
+		{"1.0.0-beta", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-beta", -1},
+		{"v1.2.3", "1.2.3", 0},
+	})
+}
+
+func TestCompare(t *testing.T) {
+	if got := Compare("14.4", "14.3.1"); got != 1 {
+		t.Errorf("Compare(%q, %q) = %d, want 1", "14.4", "14.3.1", got)
+	}
+}
@@ -0,0 +1,201 @@
+package version
+
+import "strings"
+
+// compareDebian orders dpkg versions: the epoch numerically, then the
+// upstream version and the revision with the dpkg algorithm, in which a
+// tilde sorts before anything, even the end of the version, so 1.0~rc1
+// comes before 1.0
+func compareDebian(a, b string) int {
+	aEpoch, a := splitEpoch(a)
+	bEpoch, b := splitEpoch(b)
+	if aEpoch != bEpoch {
+		if aEpoch < bEpoch {
+			return -1
+		}
+		return 1
+	}
+
+	aUpstream, aRevision := splitRelease(a)
+	bUpstream, bRevision := splitRelease(b)
+	if c := debianCompare(aUpstream, bUpstream); c != 0 {
+		return c
+	}
+	return debianCompare(aRevision, bRevision)
+}
+
+// debianOrder returns the weight of a character in the non-digit parts of
+// a Debian version. The end of the string, at i == len(s), weighs 0.
+func debianOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	switch c := s[i]; {
+	case isDigit(c):
+		return 0
+	case isLetter(c):
+		return int(c)
+	case c == '~':
+		return -1
+	default:
+		return int(c) + 256
+	}
+}
+
+// debianCompare compares an upstream version or revision as dpkg does,
+// alternating between non-digit parts compared character by character and
+// digit parts compared numerically
+func debianCompare(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for i < len(a) && !isDigit(a[i]) || j < len(b) && !isDigit(b[j]) {
+			if x, y := debianOrder(a, i), debianOrder(b, j); x != y {
+				return sign(x - y)
+			}
+			i++
+			j++
+		}
+
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+
+		firstDiff := 0
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return sign(firstDiff)
+		}
+	}
+	return 0
+}
+
+// compareRPM orders RPM versions: the epoch numerically, then the version
+// and the release with rpmvercmp. The release is only compared when both
+// versions have one, as rpm does.
+func compareRPM(a, b string) int {
+	aEpoch, a := splitEpoch(a)
+	bEpoch, b := splitEpoch(b)
+	if aEpoch != bEpoch {
+		if aEpoch < bEpoch {
+			return -1
+		}
+		return 1
+	}
+
+	aVersion, aRelease := splitRelease(a)
+	bVersion, bRelease := splitRelease(b)
+	if c := rpmCompare(aVersion, bVersion); c != 0 || aRelease == "" || bRelease == "" {
+		return c
+	}
+	return rpmCompare(aRelease, bRelease)
+}
+
+// rpmCompare compares a version or release as rpmvercmp does. Runs of
+// digits and runs of letters are compared in turn, numbers being newer
+// than words. A tilde sorts before anything, as in 1.0~rc1, and a caret
+// after the end of the version but before anything else, as in 1.0^git1.
+func rpmCompare(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for i < len(a) && !isDigit(a[i]) && !isLetter(a[i]) && a[i] != '~' && a[i] != '^' {
+			i++
+		}
+		for j < len(b) && !isDigit(b[j]) && !isLetter(b[j]) && b[j] != '~' && b[j] != '^' {
+			j++
+		}
+
+		aTilde, bTilde := i < len(a) && a[i] == '~', j < len(b) && b[j] == '~'
+		if aTilde || bTilde {
+			if !aTilde {
+				return 1
+			}
+			if !bTilde {
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+
+		aCaret, bCaret := i < len(a) && a[i] == '^', j < len(b) && b[j] == '^'
+		if aCaret || bCaret {
+			switch {
+			case i >= len(a):
+				return -1
+			case j >= len(b):
+				return 1
+			case !aCaret:
+				return 1
+			case !bCaret:
+				return -1
+			}
+			i++
+			j++
+			continue
+		}
+
+		if i >= len(a) || j >= len(b) {
+			break
+		}
+
+		// Take a run of the same kind from both versions
+		numeric := isDigit(a[i])
+		same := isLetter
+		if numeric {
+			same = isDigit
+		}
+		x, y := i, j
+		for i < len(a) && same(a[i]) {
+			i++
+		}
+		for j < len(b) && same(b[j]) {
+			j++
+		}
+
+		// The runs differ in kind, so the number is newer
+		if y == j {
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+
+		var c int
+		if numeric {
+			c = compareDigits(a[x:i], b[y:j])
+		} else {
+			c = strings.Compare(a[x:i], b[y:j])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case i >= len(a) && j >= len(b):
+		return 0
+	case i < len(a):
+		return 1
+	default:
+		return -1
+	}
+}
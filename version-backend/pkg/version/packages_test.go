@@ -0,0 +1,68 @@
+package version
+
+import (
+	"testing"
+)
+
+func TestCompareDebian(t *testing.T) {
+	checkCompare(t, Debian, []compareTest{
+		{"1.0", "1.0", 0},
+		{"1.2.10", "1.2.9", 1},
+		{"1.01", "1.1", 0},
+
+		// Epochs
+		{"1:1.0", "2.0", 1},
+		{"0:1.0", "1.0", 0},
+		{"2:1.0", "1:9.9", 1},
+
+		// Tildes
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0~~", "1.0~", -1},
+		{"1.0~", "1.0", -1},
+		{"1.0~rc1-1", "1.0-1", -1},
+
+		// Revisions and missing parts
+		{"1.0-1", "1.0-2", -1},
+		{"1.0", "1.0-1", -1},
+		{"2.30-0ubuntu1", "2.30-0ubuntu2", -1},
+		{"1.0-1ubuntu0.1", "1.0-1", 1},
+		{"1.0", "1.0.0", -1},
+
+		// Suffixes
+		{"1.0a", "1.0", 1},
+		{"1.0+dfsg", "1.0", 1},
+		{"1.0+b1", "1.0", 1},
+		{"1.0+dfsg-1", "1.0-1", 1},
+	})
+}
+
+func TestCompareRPM(t *testing.T) {
+	checkCompare(t, RPM, []compareTest{
+		{"1.0", "1.0", 0},
+		{"1.2.10", "1.2.9", 1},
+		{"1.01", "1.1", 0},
+
+		// Epochs
+		{"1:1.0-1", "2.0-1", 1},
+		{"0:1.0-1", "1.0-1", 0},
+
+		// Tildes and carets
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0^git1", "1.0", 1},
+		{"1.0^git1", "1.0.1", -1},
+		{"1.0~rc1^git1", "1.0~rc1", 1},
+
+		// Releases and missing parts
+		{"1.0-1.el9", "1.0-2.el9", -1},
+		{"1.0", "1.0-5.el9", 0},
+		{"2.34-60.el9", "2.34-60.el9_2", -1},
+		{"1.0", "1.0.0", -1},
+
+		// Letters
+		{"1.0a", "1.0", 1},
+		{"1.0a", "1.0.1", -1},
+		{"1.0a", "1.0b", -1},
+	})
+}
@@ -0,0 +1,127 @@
+package version
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Semver is a semantic version, MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD]
+type Semver struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string
+	Build      string
+}
+
+// ParseSemver parses a semantic version, allowing a leading v as in Go
+// module versions. It reports false when the version is not valid semver.
+func ParseSemver(s string) (Semver, bool) {
+	var v Semver
+	s = strings.TrimPrefix(s, "v")
+
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		v.Build = s[i+1:]
+		if !validIdentifiers(v.Build) {
+			return Semver{}, false
+		}
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		prerelease := s[i+1:]
+		if !validIdentifiers(prerelease) {
+			return Semver{}, false
+		}
+		v.Prerelease = strings.Split(prerelease, ".")
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return Semver{}, false
+	}
+	numbers := []*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		if part == "" || len(part) > 1 && part[0] == '0' {
+			return Semver{}, false
+		}
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return Semver{}, false
+		}
+		*numbers[i] = n
+	}
+
+	return v, true
+}
+
+// validIdentifiers reports whether a prerelease or build is made of
+// non-empty dot-separated alphanumeric identifiers
+func validIdentifiers(s string) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		for i := 0; i < len(id); i++ {
+			if !isDigit(id[i]) && !isLetter(id[i]) && id[i] != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Compare orders two semantic versions by precedence. Build metadata is
+// ignored, and a prerelease comes before the release it precedes.
+func (v Semver) Compare(o Semver) int {
+	for _, pair := range [][2]uint64{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case len(v.Prerelease) == 0 && len(o.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(o.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.Prerelease) && i < len(o.Prerelease); i++ {
+		if c := compareIdentifiers(v.Prerelease[i], o.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return sign(len(v.Prerelease) - len(o.Prerelease))
+}
+
+// compareIdentifiers orders prerelease identifiers: numeric ones
+// numerically and before alphanumeric ones, which compare as strings
+func compareIdentifiers(a, b string) int {
+	aNumeric, bNumeric := isNumeric(a), isNumeric(b)
+	switch {
+	case aNumeric && bNumeric:
+		return compareDigits(a, b)
+	case aNumeric:
+		return -1
+	case bNumeric:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// isNumeric reports whether a string is made of digits only
+func isNumeric(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,77 @@
+package version
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		version string
+		want    Semver
+		ok      bool
+	}{
+		{"1.2.3", Semver{Major: 1, Minor: 2, Patch: 3}, true},
+		{"v1.2.3", Semver{Major: 1, Minor: 2, Patch: 3}, true},
+		{"0.0.0", Semver{}, true},
+		{"1.2.3-rc.1", Semver{Major: 1, Minor: 2, Patch: 3, Prerelease: []string{"rc", "1"}}, true},
+		{"1.2.3+build.5", Semver{Major: 1, Minor: 2, Patch: 3, Build: "build.5"}, true},
+		{"1.2.3-beta-2+exp.sha.5114f85", Semver{Major: 1, Minor: 2, Patch: 3, Prerelease: []string{"beta-2"}, Build: "exp.sha.5114f85"}, true},
+
+		// Missing parts
+		{"", Semver{}, false},
+		{"1", Semver{}, false},
+		{"1.2", Semver{}, false},
+		{"1.2.", Semver{}, false},
+		{"1.2.3.4", Semver{}, false},
+		{"1.2.3-", Semver{}, false},
+		{"1.2.3-rc..1", Semver{}, false},
+		{"1.2.3+", Semver{}, false},
+
+		// Invalid parts
+		{"01.2.3", Semver{}, false},
+		{"1.2.x", Semver{}, false},
+		{"1.2.3-rc_1", Semver{}, false},
+		{"1:1.2.3", Semver{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseSemver(tt.version)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSemver(%q) = %+v, %v, want %+v, %v", tt.version, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	// The precedence examples of the semver specification, in order
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+	}
+	for i := 1; i < len(ordered); i++ {
+		a, _ := ParseSemver(ordered[i-1])
+		b, _ := ParseSemver(ordered[i])
+		if got := a.Compare(b); got != -1 {
+			t.Errorf("Compare(%q, %q) = %d, want -1", ordered[i-1], ordered[i], got)
+		}
+		if got := b.Compare(a); got != 1 {
+			t.Errorf("Compare(%q, %q) = %d, want 1", ordered[i], ordered[i-1], got)
+		}
+	}
+
+	a, _ := ParseSemver("1.0.0+build.1")
+	b, _ := ParseSemver("1.0.0+build.2")
+	if got := a.Compare(b); got != 0 {
+		t.Errorf("Compare(%q, %q) = %d, want 0", "1.0.0+build.1", "1.0.0+build.2", got)
+	}
+}
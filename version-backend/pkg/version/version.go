@@ -0,0 +1,140 @@
+// Package version parses and compares software versions in the formats the
+// collectors report: semantic versions, macOS-style dotted app versions,
+// Debian versions with epochs and revisions, and RPM epoch:version-release
+// strings. Versions are kept as the strings osquery returned and are only
+// parsed to be ordered.
+package version
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Scheme is a version format with its own ordering rules
+type Scheme int
+
+const (
+	// Dotted orders app versions such as 14.2.1, 1.0 (345) or 3.0b2.
+	// Versions that are both valid semantic versions follow the semver
+	// rules instead.
+	Dotted Scheme = iota
+
+	// Debian orders dpkg versions, [epoch:]upstream[-revision]
+	Debian
+
+	// RPM orders RPM versions, [epoch:]version[-release]
+	RPM
+)
+
+// ForSource returns the scheme of the versions reported by a package
+// source, such as deb or rpm. Other sources use Dotted.
+func ForSource(source string) Scheme {
+	switch source {
+	case "deb":
+		return Debian
+	case "rpm":
+		return RPM
+	default:
+		return Dotted
+	}
+}
+
+// String returns the name of the scheme
+func (s Scheme) String() string {
+	switch s {
+	case Debian:
+		return "debian"
+	case RPM:
+		return "rpm"
+	default:
+		return "dotted"
+	}
+}
+
+// Compare compares two versions in the scheme. It returns -1 when a is
+// older than b, 1 when it is newer and 0 when they are equivalent, such as
+// 1.0 and 1.0.0.
+func (s Scheme) Compare(a, b string) int {
+	switch s {
+	case Debian:
+		return compareDebian(a, b)
+	case RPM:
+		return compareRPM(a, b)
+	default:
+		return compareDotted(a, b)
+	}
+}
+
+// Newest returns the newest of the versions in the scheme, or an empty
+// string when there are none
+func (s Scheme) Newest(versions ...string) string {
+	var newest string
+	for i, v := range versions {
+		if i == 0 || s.Compare(v, newest) > 0 {
+			newest = v
+		}
+	}
+	return newest
+}
+
+// Compare compares two app versions with the Dotted scheme
+func Compare(a, b string) int {
+	return Dotted.Compare(a, b)
+}
+
+// sign reduces a difference to -1, 0 or 1
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// isDigit reports whether a byte is an ASCII digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isLetter reports whether a byte is an ASCII letter
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// compareDigits compares two runs of digits numerically without
+// overflowing on long runs
+func compareDigits(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return sign(len(a) - len(b))
+	}
+	return strings.Compare(a, b)
+}
+
+// splitEpoch splits the numeric epoch off a Debian or RPM version. A
+// missing or invalid epoch is 0.
+func splitEpoch(v string) (int64, string) {
+	i := strings.IndexByte(v, ':')
+	if i < 0 {
+		return 0, v
+	}
+	epoch, err := strconv.ParseInt(v[:i], 10, 64)
+	if err != nil {
+		return 0, v
+	}
+	return epoch, v[i+1:]
+}
+
+// splitRelease splits a version at its last hyphen into the version and
+// the Debian revision or RPM release
+func splitRelease(v string) (string, string) {
+	i := strings.LastIndexByte(v, '-')
+	if i < 0 {
+		return v, ""
+	}
+	return v[:i], v[i+1:]
+}
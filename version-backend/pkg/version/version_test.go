@@ -0,0 +1,65 @@
+package version
+
+import (
+	"testing"
+)
+
+// compareTest is a pair of versions and how the first compares to the
+// second
+type compareTest struct {
+	a, b string
+	want int
+}
+
+// checkCompare checks that a scheme orders every pair as expected, both
+// ways round
+func checkCompare(t *testing.T, scheme Scheme, tests []compareTest) {
+	t.Helper()
+	for _, tt := range tests {
+		if got := scheme.Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s.Compare(%q, %q) = %d, want %d", scheme, tt.a, tt.b, got, tt.want)
+		}
+		if got := scheme.Compare(tt.b, tt.a); got != -tt.want {
+			t.Errorf("%s.Compare(%q, %q) = %d, want %d", scheme, tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestForSource(t *testing.T) {
+	tests := []struct {
+		source string
+		want   Scheme
+	}{
+		{"deb", Debian},
+		{"rpm", RPM},
+		{"snap", Dotted},
+		{"", Dotted},
+	}
+
+	for _, tt := range tests {
+		if got := ForSource(tt.source); got != tt.want {
+			t.Errorf("ForSource(%q) = %s, want %s", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestNewest(t *testing.T) {
+	tests := []struct {
+		scheme   Scheme
+		versions []string
+		want     string
+	}{
+		{Dotted, nil, ""},
+		{Dotted, []string{"1.0"}, "1.0"},
+		{Dotted, []string{"1.9", "1.10", "1.2"}, "1.10"},
+		{Dotted, []string{"3.0", "3.0b2"}, "3.0"},
+		{Debian, []string{"1:1.0", "2.0", "1.5"}, "1:1.0"},
+		{RPM, []string{"1.0~rc1", "1.0", "0.9"}, "1.0"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.scheme.Newest(tt.versions...); got != tt.want {
+			t.Errorf("%s.Newest(%q) = %q, want %q", tt.scheme, tt.versions, got, tt.want)
+		}
+	}
+}